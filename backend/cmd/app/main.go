@@ -3,10 +3,17 @@ package main
 import (
 	"L0-wbtech/internal/app"
 	"L0-wbtech/internal/cache"
+	"L0-wbtech/internal/codec"
 	"L0-wbtech/internal/config"
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/export"
+	"L0-wbtech/internal/ingest"
 	"L0-wbtech/internal/kafka"
+	"L0-wbtech/internal/outbox"
 	"L0-wbtech/internal/service"
-	"L0-wbtech/internal/storage/postgres"
+	"L0-wbtech/internal/storage/backend"
+	"L0-wbtech/internal/stream"
+	"L0-wbtech/internal/webhook"
 	"L0-wbtech/pkg/logger/sl"
 	"L0-wbtech/pkg/logger/slogsetup"
 	"context"
@@ -19,28 +26,202 @@ func main() {
 	log := slogsetup.SetupLogger(cfg.Env)
 	log.Info("Starting server", "env", cfg.Env)
 
-	storage, err := postgres.NewPostgresDB(cfg.Database)
+	storage, err := backend.Open(cfg.Storage, cfg.Database)
 	if err != nil {
 		log.Error("Failed to initialize storage", sl.Err(err))
 		os.Exit(1)
 	}
 
-	orderCache := cache.NewCache()
+	var orderCache cache.Cache
+	var remote *cache.RemoteCache
+	switch cfg.Cache.Backend {
+	case "memory", "":
+		orderCache = cache.NewCache()
+	case "resp":
+		remote = cache.NewRemoteCache(cache.RemoteConfig{
+			Addr:     cfg.Cache.RESP.Addr,
+			Password: cfg.Cache.RESP.Password,
+			DB:       cfg.Cache.RESP.DB,
+			Prefix:   cfg.Cache.RESP.Prefix,
+			TTL:      cfg.Cache.RESP.TTL,
+			Timeout:  cfg.Cache.RESP.Timeout,
+			PoolSize: cfg.Cache.RESP.PoolSize,
+			// The mirror is bounded like the L1 in front of it.
+			LocalSize: cfg.Cache.L1Size,
+		}, log)
+		orderCache = remote
+	default:
+		log.Error("Unknown cache backend", "backend", cfg.Cache.Backend)
+		os.Exit(1)
+	}
+
+	events := event.NewBus(log)
+
+	orders := cache.NewReadThrough(orderCache, storage, cache.ReadThroughConfig{
+		L1Size:      cfg.Cache.L1Size,
+		L1TTL:       cfg.Cache.L1TTL,
+		NegativeTTL: cfg.Cache.NegativeTTL,
+	}, log)
+
+	orderService := service.NewOrderService(storage, orders, events, log)
+
+	// A shared cache outlives the process and fills on misses, so only the
+	// in-memory one is restored up front, from a snapshot when there is a
+	// usable one.
+	var snapshots *cache.Snapshotter
+	if cfg.Cache.Backend != "resp" {
+		restored := false
+		if cfg.Cache.Snapshot.Enabled {
+			snapshots = cache.NewSnapshotter(orderCache, storage, cache.SnapshotConfig{
+				Path:     cfg.Cache.Snapshot.Path,
+				Interval: cfg.Cache.Snapshot.Interval,
+				Overlap:  cfg.Cache.Snapshot.Overlap,
+			}, log)
+			if err := snapshots.Restore(context.Background()); err != nil {
+				log.Warn("Cache snapshot not used, restoring from storage", sl.Err(err))
+			} else {
+				restored = true
+			}
+		}
+		if !restored {
+			if err := orderService.RestoreCache(context.Background()); err != nil {
+				log.Error("Failed to restore cache", sl.Err(err))
+			}
+		}
+	}
+
+	schemas, err := codec.NewSchemaSource(cfg.Kafka.Schemas)
+	if err != nil {
+		log.Error("Failed to initialize schema source", sl.Err(err))
+		os.Exit(1)
+	}
+
+	subscriptions, err := kafka.BuildSubscriptions(cfg.Kafka, schemas, orderService, log)
+	if err != nil {
+		log.Error("Failed to configure kafka subscriptions", sl.Err(err))
+		os.Exit(1)
+	}
 
-	orderService := service.NewOrderService(storage, orderCache, log)
+	var source ingest.Source
+	switch cfg.Ingest.Source {
+	case "kafka", "":
+		consumer, err := kafka.NewConsumer(cfg.Kafka, subscriptions, log)
+		if err != nil {
+			log.Error("Failed to initialize kafka consumer", sl.Err(err))
+			os.Exit(1)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Kafka.InitTimeout)
+		err = kafka.PrepareGroup(ctx, cfg.Kafka, consumer.Topics(), log)
+		cancel()
+		if err != nil {
+			log.Error("Failed to prepare kafka consumer group", sl.Err(err))
+			os.Exit(1)
+		}
+		source = consumer
+	case "files":
+		source, err = ingest.NewFileSource(subscriptions, ingest.FileConfig{
+			Dir:          cfg.Ingest.Files.Dir,
+			PollInterval: cfg.Ingest.Files.PollInterval,
+		}, log)
+		if err != nil {
+			log.Error("Failed to initialize file source", sl.Err(err))
+			os.Exit(1)
+		}
+	case "inbox":
+		inbox, ok := storage.(ingest.InboxStore)
+		if !ok {
+			log.Error("Inbox source requires postgres storage", "driver", cfg.Storage.Driver)
+			os.Exit(1)
+		}
+		source = ingest.NewInboxSource(inbox, subscriptions, ingest.InboxConfig{
+			BatchSize:    cfg.Ingest.Inbox.BatchSize,
+			PollInterval: cfg.Ingest.Inbox.PollInterval,
+			Lease:        cfg.Ingest.Inbox.Lease,
+		}, log)
+	default:
+		log.Error("Unknown ingest source", "source", cfg.Ingest.Source)
+		os.Exit(1)
+	}
+
+	var relay *outbox.Relay
+	if cfg.Outbox.Enabled {
+		producer, err := kafka.NewProducer(cfg.Kafka)
+		if err != nil {
+			log.Error("Failed to initialize kafka producer", sl.Err(err))
+			os.Exit(1)
+		}
+		relay = outbox.NewRelay(
+			storage,
+			producer,
+			outbox.Config{
+				Topic:        cfg.Outbox.Topic,
+				BatchSize:    cfg.Outbox.BatchSize,
+				PollInterval: cfg.Outbox.PollInterval,
+			},
+			log,
+		)
+	}
 
-	if err := orderService.RestoreCache(context.Background()); err != nil {
-		log.Error("Failed to restore cache", sl.Err(err))
+	components := app.Components{
+		OrderService: orderService,
+		Source:       source,
+		Relay:        relay,
+		Retention: outbox.NewRetention(storage, outbox.RetentionConfig{
+			Retention: cfg.Outbox.Retention,
+			Relay:     cfg.Outbox.Enabled,
+			Webhooks:  cfg.Webhooks.Enabled,
+		}, log),
+		Snapshots:  snapshots,
+		CacheAdmin: cache.NewAdmin(orders, orderCache, storage, log),
+		Exporter:   export.NewExporter(storage, log),
 	}
 
-	consumer := kafka.NewConsumer(
-		cfg.Kafka.Brokers,
-		cfg.Kafka.Topic,
-		cfg.Kafka.GroupID,
-		orderService,
-		log,
-	)
+	if cfg.Cache.Coherence {
+		if feed, ok := storage.(cache.ChangeFeed); ok {
+			components.Invalidator = cache.NewInvalidator(orders, feed, storage, log)
+		} else {
+			log.Warn("Cache coherence requires postgres storage, ignoring", "driver", cfg.Storage.Driver)
+		}
+	}
+
+	if cfg.Webhooks.Enabled {
+		components.Webhooks = webhook.NewService(storage)
+		components.Dispatcher = webhook.NewDispatcher(
+			storage,
+			events,
+			nil,
+			webhook.Config{
+				Workers:           cfg.Webhooks.Workers,
+				Timeout:           cfg.Webhooks.Timeout,
+				MaxAttempts:       cfg.Webhooks.MaxAttempts,
+				InitialBackoff:    cfg.Webhooks.InitialBackoff,
+				MaxBackoff:        cfg.Webhooks.MaxBackoff,
+				DisableAfter:      cfg.Webhooks.DisableAfter,
+				PollInterval:      cfg.Webhooks.PollInterval,
+				BatchSize:         cfg.Webhooks.BatchSize,
+				DeliveryRetention: cfg.Webhooks.DeliveryRetention,
+			},
+			log,
+		)
+	}
 
-	application := app.New(cfg, orderService, consumer, log)
-	application.Run()
+	if cfg.Stream.Enabled {
+		components.Stream = stream.NewHub(stream.Config{
+			ClientBuffer: cfg.Stream.ClientBuffer,
+			History:      cfg.Stream.History,
+		}, log)
+		components.Events = events
+	}
+
+	application := app.New(cfg, components, log)
+	err = application.Run()
+
+	if remote != nil {
+		remote.Close()
+	}
+
+	if err != nil {
+		log.Error("Application failed", sl.Err(err))
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"L0-wbtech/internal/config"
+	"L0-wbtech/internal/export"
+	"L0-wbtech/internal/storage"
+	"L0-wbtech/internal/storage/backend"
+	"L0-wbtech/pkg/logger/sl"
+	"L0-wbtech/pkg/logger/slogsetup"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+
+	format := flag.String("format", "ndjson", "Output format: ndjson, csv or parquet")
+	from := flag.String("from", "", "Only orders created at or after this time (RFC 3339 or YYYY-MM-DD)")
+	to := flag.String("to", "", "Only orders created before this time (RFC 3339 or YYYY-MM-DD)")
+	deliveryService := flag.String("delivery-service", "", "Only orders of this delivery service")
+	customerID := flag.String("customer", "", "Only orders of this customer")
+	out := flag.String("out", "-", "Output file, - for stdout")
+	flag.Parse()
+
+	cfg := config.MustLoad()
+
+	log := slogsetup.SetupLoggerTo(cfg.Env, os.Stderr)
+
+	outputFormat, err := export.ParseFormat(*format)
+	if err != nil {
+		log.Error("Invalid format", sl.Err(err))
+		os.Exit(1)
+	}
+
+	filter := storage.OrderFilter{
+		DeliveryService: *deliveryService,
+		CustomerID:      *customerID,
+	}
+	if filter.From, err = export.ParseTime(*from); err != nil {
+		log.Error("Invalid -from", sl.Err(err))
+		os.Exit(1)
+	}
+	if filter.To, err = export.ParseTime(*to); err != nil {
+		log.Error("Invalid -to", sl.Err(err))
+		os.Exit(1)
+	}
+
+	db, err := backend.Open(cfg.Storage, cfg.Database)
+	if err != nil {
+		log.Error("Failed to initialize storage", sl.Err(err))
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Error("Failed to create output file", sl.Err(err))
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	count, err := export.NewExporter(db, log).Export(ctx, filter, outputFormat, w)
+	if err != nil {
+		log.Error("Export failed", sl.Err(err), "written", count)
+		os.Exit(1)
+	}
+
+	log.Info("Export finished", "orders", count, "out", *out)
+}
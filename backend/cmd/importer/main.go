@@ -0,0 +1,69 @@
+package main
+
+import (
+	"L0-wbtech/internal/config"
+	"L0-wbtech/internal/importer"
+	"L0-wbtech/internal/storage/backend"
+	"L0-wbtech/pkg/logger/sl"
+	"L0-wbtech/pkg/logger/slogsetup"
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+
+	input := flag.String("input", "", "File or directory with JSON/NDJSON orders, optionally .gz")
+	batchSize := flag.Int("batch-size", 500, "Orders per transaction")
+	checkpoint := flag.String("checkpoint", "import.checkpoint.json", "Checkpoint file for resuming, empty to disable")
+	rejects := flag.String("rejects", "import.rejected.ndjson", "File that collects rejected records")
+	progress := flag.Duration("progress", 0, "Progress report interval (default 5s)")
+	flag.Parse()
+
+	cfg := config.MustLoad()
+
+	log := slogsetup.SetupLogger(cfg.Env)
+
+	if *input == "" {
+		log.Error("Input path is required")
+		os.Exit(1)
+	}
+
+	files, err := importer.ListFiles(*input)
+	if err != nil {
+		log.Error("Failed to list input files", sl.Err(err))
+		os.Exit(1)
+	}
+	log.Info("Found input files", "count", len(files))
+
+	db, err := backend.Open(cfg.Storage, cfg.Database)
+	if err != nil {
+		log.Error("Failed to initialize storage", sl.Err(err))
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	imp := importer.New(db, importer.Config{
+		BatchSize:        *batchSize,
+		CheckpointPath:   *checkpoint,
+		RejectsPath:      *rejects,
+		ProgressInterval: *progress,
+	}, log)
+
+	stats, err := imp.Run(ctx, files)
+	if err != nil {
+		log.Error("Import stopped, rerun to resume", sl.Err(err),
+			"inserted", stats.Inserted,
+			"rejected", stats.Rejected)
+		os.Exit(1)
+	}
+
+	if stats.Rejected > 0 {
+		log.Warn("Some records were rejected", "count", stats.Rejected, "rejects", *rejects)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"L0-wbtech/internal/config"
+	"L0-wbtech/internal/kafka"
+	"L0-wbtech/internal/loadgen"
+	"L0-wbtech/pkg/logger/sl"
+	"L0-wbtech/pkg/logger/slogsetup"
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+
+	mode := flag.String("mode", "kafka", "Where to send orders: kafka or file")
+	topic := flag.String("topic", "orders", "Kafka topic for -mode=kafka")
+	out := flag.String("out", "orders.ndjson", "Output file for -mode=file, .gz to compress")
+	rate := flag.Float64("rate", 100, "Orders per second, 0 for unlimited")
+	count := flag.Int("count", 0, "Number of orders to generate, 0 for no limit")
+	duration := flag.Duration("duration", 0, "How long to run, 0 for no limit")
+	invalid := flag.Float64("invalid", 0, "Fraction of invalid orders")
+	duplicate := flag.Float64("duplicate", 0, "Fraction of duplicate orders")
+	seed := flag.Uint64("seed", 0, "Random seed, 0 for a random one")
+	apiURL := flag.String("api", "", "Base URL of the HTTP API for latency probing, e.g. http://localhost:8081")
+	probeWorkers := flag.Int("probe-workers", 32, "Concurrent latency probes")
+	probeTimeout := flag.Duration("probe-timeout", 30*time.Second, "Give up on an order after this long")
+	flag.Parse()
+
+	cfg := config.MustLoad()
+
+	log := slogsetup.SetupLoggerTo(cfg.Env, os.Stderr)
+
+	if *count == 0 && *duration == 0 {
+		log.Error("Either -count or -duration is required")
+		os.Exit(1)
+	}
+
+	var sink loadgen.Sink
+	var kafkaSink *loadgen.KafkaSink
+	switch *mode {
+	case "kafka":
+		transport, err := kafka.NewTransport(cfg.Kafka)
+		if err != nil {
+			log.Error("Failed to configure kafka connection", sl.Err(err))
+			os.Exit(1)
+		}
+		kafkaSink = loadgen.NewKafkaSink(cfg.Kafka.Brokers, *topic, transport)
+		sink = kafkaSink
+	case "file":
+		fileSink, err := loadgen.NewFileSink(*out)
+		if err != nil {
+			log.Error("Failed to create output file", sl.Err(err))
+			os.Exit(1)
+		}
+		sink = fileSink
+	default:
+		log.Error("Unknown mode", "mode", *mode)
+		os.Exit(1)
+	}
+
+	var prober *loadgen.Prober
+	if *apiURL != "" {
+		prober = loadgen.NewProber(*apiURL, *probeWorkers, 50*time.Millisecond, *probeTimeout)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	gen := loadgen.NewGenerator(loadgen.GeneratorConfig{
+		Seed:          *seed,
+		InvalidRate:   *invalid,
+		DuplicateRate: *duplicate,
+	})
+
+	runner := loadgen.NewRunner(gen, sink, prober, loadgen.RunConfig{
+		Rate:     *rate,
+		Count:    *count,
+		Duration: *duration,
+	}, log)
+
+	stats, runErr := runner.Run(ctx)
+
+	if err := sink.Close(); err != nil {
+		log.Error("Failed to close sink", sl.Err(err))
+	}
+	if kafkaSink != nil && kafkaSink.Failed() > 0 {
+		log.Warn("Messages not acknowledged by Kafka", "count", kafkaSink.Failed())
+	}
+
+	if prober != nil {
+		log.Info("Waiting for outstanding latency probes")
+		lat := prober.Close()
+		log.Info("End-to-end latency",
+			"read_back", lat.Count,
+			"timeouts", lat.Timeouts,
+			"dropped", lat.Dropped,
+			"p50", lat.P50,
+			"p90", lat.P90,
+			"p99", lat.P99,
+			"max", lat.Max)
+	}
+
+	log.Info("Load generation finished",
+		"sent", stats.Total(),
+		"errors", stats.Errors,
+		"elapsed", stats.Elapsed.Round(time.Millisecond))
+
+	if runErr != nil {
+		log.Error("Load generation interrupted", sl.Err(runErr))
+		os.Exit(1)
+	}
+}
@@ -1,14 +1,22 @@
 package app
 
 import (
+	"L0-wbtech/internal/cache"
 	"L0-wbtech/internal/config"
+	"L0-wbtech/internal/export"
+	"L0-wbtech/internal/grpcapi"
 	"L0-wbtech/internal/handler"
-	"L0-wbtech/internal/kafka"
+	"L0-wbtech/internal/ingest"
+	"L0-wbtech/internal/outbox"
 	"L0-wbtech/internal/service"
+	"L0-wbtech/internal/stream"
+	"L0-wbtech/internal/webhook"
 	"L0-wbtech/pkg/logger/sl"
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
@@ -21,63 +29,149 @@ type App struct {
 	cfg          *config.Config
 	log          *slog.Logger
 	orderService service.Service
-	consumer     *kafka.Consumer
+	source       ingest.Source
+	relay        *outbox.Relay
+	retention    *outbox.Retention
+	invalidator  *cache.Invalidator
+	snapshots    *cache.Snapshotter
+	cacheAdmin   *cache.Admin
+	webhooks     *webhook.Service
+	dispatcher   *webhook.Dispatcher
+	stream       *stream.Hub
+	exporter     *export.Exporter
+	events       stream.Subscriber
 	httpServer   *http.Server
+	grpcServer   *grpcapi.Server
+	supervisor   *supervisor
+	// cancelIngest abandons the messages in flight when they do not finish
+	// within the ingest shutdown stage.
+	cancelIngest context.CancelFunc
+}
+
+// Components are the parts App runs. Optional components are nil when
+// disabled in the config.
+type Components struct {
+	OrderService service.Service
+	Source       ingest.Source
+	Relay        *outbox.Relay
+	Retention    *outbox.Retention
+	Invalidator  *cache.Invalidator
+	Snapshots    *cache.Snapshotter
+	CacheAdmin   *cache.Admin
+	Webhooks     *webhook.Service
+	Dispatcher   *webhook.Dispatcher
+	Stream       *stream.Hub
+	Exporter     *export.Exporter
+	Events       stream.Subscriber
 }
 
 func New(
 	cfg *config.Config,
-	orderService service.Service,
-	consumer *kafka.Consumer,
+	components Components,
 	log *slog.Logger,
 ) *App {
 	return &App{
 		cfg:          cfg,
-		orderService: orderService,
-		consumer:     consumer,
+		orderService: components.OrderService,
+		source:       components.Source,
+		relay:        components.Relay,
+		retention:    components.Retention,
+		invalidator:  components.Invalidator,
+		snapshots:    components.Snapshots,
+		cacheAdmin:   components.CacheAdmin,
+		webhooks:     components.Webhooks,
+		dispatcher:   components.Dispatcher,
+		stream:       components.Stream,
+		exporter:     components.Exporter,
+		events:       components.Events,
+		supervisor:   newSupervisor(cfg.Supervisor, log),
 		log:          log,
 	}
 }
 
-func (a *App) Run() {
+// Run starts every component and blocks until a signal arrives or a
+// component fails, then shuts down. It returns the failure, if any.
+func (a *App) Run() error {
+	const op = "app.Run"
+	log := a.log.With(slog.String("op", op))
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	go a.consumer.Start(ctx)
+	a.startIngest(ctx)
 
-	go a.runHTTPServer()
+	if a.relay != nil {
+		a.supervisor.Go(ctx, background("outbox relay", a.relay.Run))
+	}
 
-	a.log.Info("Application started",
-		"port", a.cfg.Server.Port,
-		"kafka_topic", a.cfg.Kafka.Topic)
+	if a.retention != nil {
+		a.supervisor.Go(ctx, background("outbox retention", a.retention.Run))
+	}
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
-	<-quit
+	if a.invalidator != nil {
+		a.supervisor.Go(ctx, component{name: "cache invalidator", run: a.invalidator.Run, restart: true})
+	}
 
-	const op = "app.Run"
-	a.log.With(slog.String("op", op)).Info("Shutting down server...")
+	if a.snapshots != nil {
+		a.supervisor.Go(ctx, background("cache snapshots", a.snapshots.Run))
+	}
+
+	if a.cacheAdmin != nil {
+		a.supervisor.Go(ctx, background("cache warmer", a.cacheAdmin.Run))
+	}
 
-	ctxShutdown, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancelShutdown()
+	if a.dispatcher != nil {
+		a.supervisor.Go(ctx, background("webhook dispatcher", a.dispatcher.Run))
+	}
 
-	if err := a.httpServer.Shutdown(ctxShutdown); err != nil {
-		a.log.Error("HTTP server shutdown error", sl.Err(err))
+	if a.stream != nil {
+		a.supervisor.Go(ctx, background("stream hub", func(ctx context.Context) {
+			a.stream.Run(ctx, a.events)
+		}))
 	}
 
-	if err := a.consumer.Close(); err != nil {
-		a.log.Error("Kafka consumer close error", sl.Err(err))
+	a.httpServer = a.newHTTPServer()
+	a.supervisor.Go(ctx, component{name: "http", run: a.runHTTPServer})
+
+	if a.cfg.Server.GRPCPort != "" {
+		a.grpcServer = grpcapi.NewServer(a.orderService, a.stream, a.log)
+		a.supervisor.Go(ctx, component{name: "grpc", run: a.runGRPCServer})
 	}
 
-	if err := a.orderService.Close(); err != nil {
-		a.log.Error("Order service close error", sl.Err(err))
+	a.log.Info("Application started",
+		"port", a.cfg.Server.Port,
+		"grpc_port", a.cfg.Server.GRPCPort,
+		"ingest", a.cfg.Ingest.Source)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(quit)
+
+	var err error
+	select {
+	case sig := <-quit:
+		log.Info("Shutting down server...", "signal", sig.String())
+	case <-a.supervisor.Failed():
+		err = a.supervisor.Err()
+		log.Error("Component failed, shutting down", sl.Err(err))
 	}
 
+	a.supervisor.Stop()
+	a.shutdown(a.shutdownStages(cancel))
+
 	a.log.Info("Application stopped")
+	return err
 }
 
-func (a *App) runHTTPServer() {
-	const op = "app.runHTTPServer"
+// startIngest runs the source under a context of its own, so the shutdown
+// can cancel it without stopping anything else.
+func (a *App) startIngest(ctx context.Context) {
+	ctx, a.cancelIngest = context.WithCancel(ctx)
+	a.supervisor.Go(ctx, component{name: "ingest", run: a.source.Start})
+}
+
+func (a *App) newHTTPServer() *http.Server {
+	const op = "app.newHTTPServer"
 	log := a.log.With(slog.String("op", op))
 
 	gin.SetMode(gin.ReleaseMode)
@@ -91,17 +185,79 @@ func (a *App) runHTTPServer() {
 
 	apiHandler := handler.New(a.orderService, a.log)
 	apiHandler.RegisterRoutes(router)
+	if a.cfg.Server.AdminToken != "" {
+		admin := apiHandler.RegisterAdminRoutes(router, a.cfg.Server.AdminToken)
+		if a.webhooks != nil {
+			handler.NewWebhookHandler(a.webhooks, a.log).RegisterRoutes(admin)
+		}
+		if a.exporter != nil {
+			handler.NewExportHandler(a.exporter, a.log).RegisterRoutes(admin)
+		}
+		if a.cacheAdmin != nil {
+			handler.NewCacheHandler(a.cacheAdmin, a.log).RegisterRoutes(admin)
+		}
+	} else {
+		log.Warn("Admin API disabled: ADMIN_TOKEN is not set")
+	}
 
-	a.httpServer = &http.Server{
+	if a.stream != nil {
+		if token := a.streamToken(); token != "" {
+			handler.NewStreamHandler(
+				a.stream,
+				a.cfg.Stream.Heartbeat,
+				a.cfg.Stream.AllowedOrigins,
+				a.log,
+			).RegisterRoutes(router, token)
+		} else {
+			log.Warn("Order stream disabled: neither STREAM_TOKEN nor ADMIN_TOKEN is set")
+		}
+	}
+
+	return &http.Server{
 		Addr:    ":" + a.cfg.Server.Port,
 		Handler: router,
 	}
+}
+
+// streamToken is the token clients of the order stream present. It falls
+// back to the admin token.
+func (a *App) streamToken() string {
+	if a.cfg.Stream.Token != "" {
+		return a.cfg.Stream.Token
+	}
+	return a.cfg.Server.AdminToken
+}
+
+func (a *App) runHTTPServer(context.Context) error {
+	const op = "app.runHTTPServer"
+	log := a.log.With(slog.String("op", op))
 
 	log.Info("Starting HTTP server", "port", a.cfg.Server.Port)
 	if err := a.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Error("HTTP server error", sl.Err(err))
-		os.Exit(1)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (a *App) runGRPCServer(context.Context) error {
+	const op = "app.runGRPCServer"
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("Starting gRPC server", "port", a.cfg.Server.GRPCPort)
+	if err := a.grpcServer.ListenAndServe(a.cfg.Server.GRPCPort); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// redactQuery hides the stream token browsers pass in the query string.
+func redactQuery(raw string) string {
+	values, err := url.ParseQuery(raw)
+	if err != nil || !values.Has("token") {
+		return raw
 	}
+	values.Set("token", "REDACTED")
+	return values.Encode()
 }
 
 func requestLogger(log *slog.Logger) gin.HandlerFunc {
@@ -117,7 +273,7 @@ func requestLogger(log *slog.Logger) gin.HandlerFunc {
 			"status", c.Writer.Status(),
 			"method", c.Request.Method,
 			"path", path,
-			"query", query,
+			"query", redactQuery(query),
 			"ip", c.ClientIP(),
 			"user-agent", c.Request.UserAgent(),
 			"latency", latency,
@@ -0,0 +1,186 @@
+package app
+
+import (
+	"L0-wbtech/pkg/logger/sl"
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// stage is one step of the shutdown, run under its own timeout.
+type stage struct {
+	name    string
+	timeout time.Duration
+	run     func(ctx context.Context) error
+}
+
+// shutdown runs the stages in order. A stage that fails or runs out of time
+// is logged and the next one still runs, so storage is closed whatever
+// happened before.
+func (a *App) shutdown(stages []stage) {
+	const op = "app.App.shutdown"
+	log := a.log.With(slog.String("op", op))
+
+	for _, s := range stages {
+		log := log.With(slog.String("stage", s.name))
+		log.Info("Shutdown stage started", "timeout", s.timeout)
+
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		err := s.run(ctx)
+		cancel()
+
+		if err != nil {
+			log.Error("Shutdown stage failed", sl.Err(err), "elapsed", time.Since(start))
+			continue
+		}
+		log.Info("Shutdown stage finished", "elapsed", time.Since(start))
+	}
+}
+
+// shutdownStages drains the application front to back: stop taking
+// messages and let those in flight commit, drain the APIs, stop the
+// background jobs, publish what is left in the outbox, write out the
+// metrics, then save the cache and close storage once nothing writes to
+// them.
+func (a *App) shutdownStages(cancel context.CancelFunc) []stage {
+	cfg := a.cfg.Shutdown
+
+	return []stage{
+		{name: "ingest", timeout: cfg.Ingest, run: a.stopIngest},
+		{name: "http", timeout: cfg.HTTP, run: a.stopServers},
+		{name: "background", timeout: cfg.Background, run: func(ctx context.Context) error {
+			cancel()
+			return a.supervisor.Wait(ctx)
+		}},
+		{name: "outbox", timeout: cfg.Outbox, run: a.flushOutbox},
+		{name: "metrics", timeout: cfg.Metrics, run: a.flushMetrics},
+		{name: "cache snapshot", timeout: cfg.Snapshot, run: a.saveSnapshot},
+		{name: "storage", timeout: cfg.Storage, run: func(ctx context.Context) error {
+			return within(ctx, a.orderService.Close)
+		}},
+	}
+}
+
+// ingestAbortTimeout bounds the wait for the source to return once the
+// messages in flight are abandoned.
+const ingestAbortTimeout = 5 * time.Second
+
+func (a *App) stopIngest(ctx context.Context) error {
+	const op = "app.App.stopIngest"
+
+	var errs []error
+	if err := a.source.Stop(ctx); err != nil {
+		errs = append(errs, err)
+
+		// Out of time: cancel the handlers, whose messages are redelivered
+		// after the restart, and let them return before the source closes.
+		if a.cancelIngest != nil {
+			a.cancelIngest()
+		}
+		abort, cancel := context.WithTimeout(context.Background(), ingestAbortTimeout)
+		if err := a.source.Stop(abort); err != nil {
+			errs = append(errs, fmt.Errorf("abandon messages in flight: %w", err))
+		}
+		cancel()
+	}
+	if err := a.source.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := stdErrors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (a *App) stopServers(ctx context.Context) error {
+	const op = "app.App.stopServers"
+
+	// Open feeds never finish on their own, so end them before draining HTTP.
+	if a.stream != nil {
+		a.stream.Close()
+	}
+
+	var errs []error
+	if a.httpServer != nil {
+		if err := a.httpServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("http: %w", err))
+		}
+	}
+	if a.grpcServer != nil {
+		if err := a.grpcServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("grpc: %w", err))
+		}
+	}
+	if err := stdErrors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// flushOutbox publishes the events written since the relay last ran, so
+// they do not wait for the next start.
+func (a *App) flushOutbox(ctx context.Context) error {
+	const op = "app.App.flushOutbox"
+
+	if a.relay == nil {
+		return nil
+	}
+
+	n, err := a.relay.Flush(ctx)
+	if closeErr := a.relay.Close(); closeErr != nil {
+		err = stdErrors.Join(err, closeErr)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.log.Info("Outbox flushed", slog.String("op", op), "published", n)
+	return nil
+}
+
+// flushMetrics logs the final values of the counters kept in memory, which
+// are lost with the process otherwise.
+func (a *App) flushMetrics(context.Context) error {
+	const op = "app.App.flushMetrics"
+
+	if a.cacheAdmin == nil {
+		return nil
+	}
+
+	stats := a.cacheAdmin.Stats()
+	a.log.Info("Final cache metrics",
+		slog.String("op", op),
+		"size", stats.Size,
+		"l1_size", stats.L1Size,
+		"l1_hits", stats.Counters.L1Hits,
+		"l2_hits", stats.Counters.L2Hits,
+		"negative_hits", stats.Counters.NegativeHits,
+		"misses", stats.Counters.Misses,
+		"hit_ratio", stats.HitRatio)
+	return nil
+}
+
+func (a *App) saveSnapshot(ctx context.Context) error {
+	if a.snapshots == nil {
+		return nil
+	}
+	return a.snapshots.Save(ctx)
+}
+
+// within runs fn but stops waiting for it once ctx is done.
+func within(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
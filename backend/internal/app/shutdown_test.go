@@ -0,0 +1,186 @@
+package app
+
+import (
+	"L0-wbtech/internal/config"
+	"context"
+	stdErrors "errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// journal records what happened, in order.
+type journal struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (j *journal) add(entry string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = append(j.entries, entry)
+}
+
+func (j *journal) get() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return slices.Clone(j.entries)
+}
+
+func newTestApp(cfg config.Config) *App {
+	return New(&cfg, Components{}, slog.New(slog.DiscardHandler))
+}
+
+func TestShutdownStageOrder(t *testing.T) {
+	a := newTestApp(config.Config{})
+
+	var names []string
+	for _, s := range a.shutdownStages(func() {}) {
+		names = append(names, s.name)
+	}
+	want := []string{"ingest", "http", "background", "outbox", "metrics", "cache snapshot", "storage"}
+	if !slices.Equal(names, want) {
+		t.Errorf("stages %v, want %v", names, want)
+	}
+}
+
+func TestShutdownStages(t *testing.T) {
+	const timeout = 30 * time.Millisecond
+
+	a := newTestApp(config.Config{})
+	var j journal
+	var overrun time.Duration
+
+	a.shutdown([]stage{
+		{name: "first", timeout: time.Second, run: func(context.Context) error {
+			j.add("first")
+			return nil
+		}},
+		{name: "failing", timeout: time.Second, run: func(context.Context) error {
+			j.add("failing")
+			return stdErrors.New("broken")
+		}},
+		{name: "slow", timeout: timeout, run: func(ctx context.Context) error {
+			start := time.Now()
+			<-ctx.Done()
+			overrun = time.Since(start)
+			j.add("slow: " + ctx.Err().Error())
+			return ctx.Err()
+		}},
+		{name: "last", timeout: time.Second, run: func(ctx context.Context) error {
+			// Every stage gets its own budget, whatever the others used.
+			if ctx.Err() != nil {
+				j.add("last: no time left")
+				return ctx.Err()
+			}
+			j.add("last")
+			return nil
+		}},
+	})
+
+	want := []string{"first", "failing", "slow: " + context.DeadlineExceeded.Error(), "last"}
+	if got := j.get(); !slices.Equal(got, want) {
+		t.Errorf("stages ran as %v, want %v", got, want)
+	}
+	if overrun < timeout || overrun > 10*timeout {
+		t.Errorf("slow stage was cut after %v, want about %v", overrun, timeout)
+	}
+}
+
+// source handles one message that finishes once release is closed, unless
+// its context is cancelled first.
+type source struct {
+	j        *journal
+	started  chan struct{}
+	release  chan struct{}
+	stopOnce sync.Once
+	stopping chan struct{}
+	done     chan struct{}
+}
+
+func newSource(j *journal) *source {
+	return &source{
+		j:        j,
+		started:  make(chan struct{}),
+		release:  make(chan struct{}),
+		stopping: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *source) Start(ctx context.Context) error {
+	defer close(s.done)
+
+	close(s.started)
+	select {
+	case <-s.release:
+		s.j.add("handled")
+	case <-ctx.Done():
+		s.j.add("cancelled")
+		return nil
+	}
+
+	select {
+	case <-s.stopping:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func (s *source) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stopping) })
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *source) Close() error {
+	s.j.add("closed")
+	return nil
+}
+
+func TestStopIngest(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+
+	for _, tc := range []struct {
+		name    string
+		finish  bool
+		want    []string
+		wantErr error
+	}{
+		{"message finishes in time", true, []string{"handled", "closed"}, nil},
+		{"message abandoned on timeout", false, []string{"cancelled", "closed"}, context.DeadlineExceeded},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var j journal
+			src := newSource(&j)
+			a := newTestApp(config.Config{})
+			a.source = src
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			a.startIngest(ctx)
+			<-src.started
+
+			if tc.finish {
+				time.AfterFunc(timeout/5, func() { close(src.release) })
+			}
+			stageCtx, stageCancel := context.WithTimeout(context.Background(), timeout)
+			defer stageCancel()
+
+			err := a.stopIngest(stageCtx)
+			if !stdErrors.Is(err, tc.wantErr) || (tc.wantErr == nil && err != nil) {
+				t.Errorf("stopIngest returned %v, want %v", err, tc.wantErr)
+			}
+			// Close must come after the handler returned.
+			if got := j.get(); !slices.Equal(got, tc.want) {
+				t.Errorf("ingest went %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
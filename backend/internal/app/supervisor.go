@@ -0,0 +1,172 @@
+package app
+
+import (
+	"L0-wbtech/internal/config"
+	"L0-wbtech/pkg/logger/sl"
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	maxRestartBackoff = time.Minute
+	// A component that ran this long before failing starts over with a
+	// fresh restart budget.
+	restartReset = 5 * time.Minute
+)
+
+// component is a part of the application the supervisor runs. run blocks
+// until ctx is cancelled or the component is shut down; returning nil
+// means it is done, an error that it failed.
+type component struct {
+	name string
+	run  func(ctx context.Context) error
+	// restart marks a component that can be started again after it fails.
+	restart bool
+}
+
+// background adapts a job that runs until ctx is cancelled. Jobs hold no
+// state a panic can leave broken, so they are restarted.
+func background(name string, run func(ctx context.Context)) component {
+	return component{
+		name: name,
+		run: func(ctx context.Context) error {
+			run(ctx)
+			return nil
+		},
+		restart: true,
+	}
+}
+
+// supervisor runs components like an errgroup: the first failure it cannot
+// recover from is kept and reported through Failed, so the app can shut the
+// others down. Panics count as failures. Restartable components are started
+// again with exponential backoff until they fail MaxRestarts times in a row.
+// Once Stop is called, failures are only logged.
+type supervisor struct {
+	cfg config.SupervisorConfig
+	log *slog.Logger
+
+	wg       sync.WaitGroup
+	once     sync.Once
+	err      error
+	failed   chan struct{}
+	stopping atomic.Bool
+}
+
+func newSupervisor(cfg config.SupervisorConfig, log *slog.Logger) *supervisor {
+	if cfg.RestartBackoff <= 0 {
+		cfg.RestartBackoff = time.Second
+	}
+	return &supervisor{
+		cfg:    cfg,
+		log:    log,
+		failed: make(chan struct{}),
+	}
+}
+
+func (s *supervisor) Go(ctx context.Context, c component) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.supervise(ctx, c)
+	}()
+}
+
+func (s *supervisor) supervise(ctx context.Context, c component) {
+	const op = "app.supervisor.supervise"
+	log := s.log.With(slog.String("op", op), slog.String("component", c.name))
+
+	backoff := s.cfg.RestartBackoff
+	restarts := 0
+	for {
+		start := time.Now()
+		err := runComponent(ctx, c, log)
+		if err == nil {
+			return
+		}
+		if s.stopping.Load() || ctx.Err() != nil {
+			log.Warn("Component failed during shutdown", sl.Err(err))
+			return
+		}
+
+		if time.Since(start) >= restartReset {
+			backoff, restarts = s.cfg.RestartBackoff, 0
+		}
+		if !c.restart || restarts >= s.cfg.MaxRestarts {
+			log.Error("Component failed", sl.Err(err), "restarts", restarts)
+			s.fail(fmt.Errorf("%s: %w", c.name, err))
+			return
+		}
+
+		restarts++
+		log.Warn("Component failed, restarting",
+			sl.Err(err),
+			"attempt", restarts,
+			"backoff", backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRestartBackoff)
+	}
+}
+
+func runComponent(ctx context.Context, c component, log *slog.Logger) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("Component panicked", "panic", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return c.run(ctx)
+}
+
+func (s *supervisor) fail(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.failed)
+	})
+}
+
+// Failed is closed when a component fails for good; Err then returns the
+// failure.
+func (s *supervisor) Failed() <-chan struct{} {
+	return s.failed
+}
+
+func (s *supervisor) Err() error {
+	select {
+	case <-s.failed:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Stop marks the shutdown: components exiting from now on are expected.
+func (s *supervisor) Stop() {
+	s.stopping.Store(true)
+}
+
+// Wait blocks until every component has returned or ctx is done.
+func (s *supervisor) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"L0-wbtech/internal/config"
+	"context"
+	stdErrors "errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testBackoff = 20 * time.Millisecond
+
+var errBroken = stdErrors.New("broken")
+
+// runs records when a component was started.
+type runs struct {
+	mu     sync.Mutex
+	starts []time.Time
+}
+
+func (r *runs) start() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.starts = append(r.starts, time.Now())
+	return len(r.starts)
+}
+
+func (r *runs) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.starts)
+}
+
+func newTestSupervisor(maxRestarts int) *supervisor {
+	return newSupervisor(config.SupervisorConfig{
+		MaxRestarts:    maxRestarts,
+		RestartBackoff: testBackoff,
+	}, slog.New(slog.DiscardHandler))
+}
+
+func waitFailed(t *testing.T, s *supervisor) error {
+	t.Helper()
+
+	select {
+	case <-s.Failed():
+		return s.Err()
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor did not report a failure")
+		return nil
+	}
+}
+
+func TestSupervisorRestartsWithBackoff(t *testing.T) {
+	const failures = 3
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newTestSupervisor(failures)
+
+	var r runs
+	healthy := make(chan struct{})
+	s.Go(ctx, component{
+		name: "flaky",
+		run: func(ctx context.Context) error {
+			if r.start() <= failures {
+				return errBroken
+			}
+			close(healthy)
+			<-ctx.Done()
+			return nil
+		},
+		restart: true,
+	})
+
+	select {
+	case <-healthy:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("component started %d times, want %d", r.count(), failures+1)
+	}
+	cancel()
+	if err := s.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Err(); err != nil {
+		t.Errorf("supervisor failed with %v after a successful restart", err)
+	}
+	backoff := testBackoff
+	for i := 1; i < len(r.starts); i++ {
+		if gap := r.starts[i].Sub(r.starts[i-1]); gap < backoff {
+			t.Errorf("restart %d after %v, want at least %v", i, gap, backoff)
+		}
+		backoff *= 2
+	}
+}
+
+func TestSupervisorFailsForGood(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		restart     bool
+		maxRestarts int
+		run         func(ctx context.Context) error
+		wantRuns    int
+	}{
+		{
+			name:        "not restartable",
+			maxRestarts: 3,
+			run:         func(context.Context) error { return errBroken },
+			wantRuns:    1,
+		},
+		{
+			name:        "restarts exhausted",
+			restart:     true,
+			maxRestarts: 2,
+			run:         func(context.Context) error { return errBroken },
+			wantRuns:    3,
+		},
+		{
+			name:        "panics",
+			restart:     true,
+			maxRestarts: 1,
+			run:         func(context.Context) error { panic(errBroken) },
+			wantRuns:    2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			s := newTestSupervisor(tc.maxRestarts)
+
+			var r runs
+			s.Go(ctx, component{
+				name: "worker",
+				run: func(ctx context.Context) error {
+					r.start()
+					return tc.run(ctx)
+				},
+				restart: tc.restart,
+			})
+
+			err := waitFailed(t, s)
+			if err == nil || !strings.HasPrefix(err.Error(), "worker: ") {
+				t.Errorf("Err() = %v, want the failure of worker", err)
+			}
+			if err := s.Wait(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if n := r.count(); n != tc.wantRuns {
+				t.Errorf("component ran %d times, want %d", n, tc.wantRuns)
+			}
+		})
+	}
+}
+
+func TestSupervisorIgnoresFailuresAfterStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := newTestSupervisor(0)
+
+	release := make(chan struct{})
+	s.Go(ctx, component{
+		name: "closing",
+		run: func(context.Context) error {
+			<-release
+			return errBroken
+		},
+	})
+
+	s.Stop()
+	close(release)
+	if err := s.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Err(); err != nil {
+		t.Errorf("failure during shutdown reported as %v", err)
+	}
+}
+
+func TestSupervisorDoesNotRestartFinishedComponent(t *testing.T) {
+	s := newTestSupervisor(3)
+
+	var r runs
+	s.Go(context.Background(), background("oneshot", func(context.Context) { r.start() }))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := r.count(); n != 1 {
+		t.Errorf("component ran %d times, want 1", n)
+	}
+}
@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"L0-wbtech/internal/model"
+	"L0-wbtech/pkg/logger/sl"
+	"context"
+	stdErrors "errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// warmPage is how many orders a re-warm reads from storage at a time.
+const warmPage = 500
+
+var ErrWarmRunning = stdErrors.New("cache: warm already running")
+
+// Lister pages through every order in storage.
+type Lister interface {
+	ListOrders(ctx context.Context, afterUID string, limit int) ([]*model.Order, error)
+}
+
+type Stats struct {
+	Size   int `json:"size"`
+	L1Size int `json:"l1_size"`
+	// MemoryBytes estimates the memory of the cached orders, -1 when the
+	// backend cannot tell.
+	MemoryBytes int64    `json:"memory_bytes"`
+	HitRatio    float64  `json:"hit_ratio"`
+	Counters    Counters `json:"counters"`
+}
+
+type WarmState string
+
+const (
+	WarmIdle    WarmState = "idle"
+	WarmRunning WarmState = "running"
+	WarmDone    WarmState = "done"
+	WarmFailed  WarmState = "failed"
+)
+
+// WarmProgress describes the latest re-warm. Loaded counts the orders
+// written to the cache so far.
+type WarmProgress struct {
+	State      WarmState  `json:"state"`
+	Loaded     int        `json:"loaded"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Admin gives operators visibility into the cache and ways to repair it.
+// Re-warms run in Run, so they share the application lifecycle: shutdown
+// cancels a warm in progress and waits for it to stop.
+type Admin struct {
+	loader *ReadThrough
+	cache  Cache
+	lister Lister
+	log    *slog.Logger
+	start  chan struct{}
+
+	mu   sync.Mutex
+	warm WarmProgress
+}
+
+func NewAdmin(loader *ReadThrough, cache Cache, lister Lister, log *slog.Logger) *Admin {
+	return &Admin{
+		loader: loader,
+		cache:  cache,
+		lister: lister,
+		log:    log,
+		start:  make(chan struct{}, 1),
+		warm:   WarmProgress{State: WarmIdle},
+	}
+}
+
+func (a *Admin) Stats() Stats {
+	counters := a.loader.Counters()
+
+	memory := int64(-1)
+	if m, ok := a.cache.(interface{ MemoryBytes() int64 }); ok {
+		memory = m.MemoryBytes()
+	}
+
+	return Stats{
+		Size:        a.cache.Len(),
+		L1Size:      a.loader.L1Len(),
+		MemoryBytes: memory,
+		HitRatio:    counters.HitRatio(),
+		Counters:    counters,
+	}
+}
+
+// Contains reports which tiers hold the order.
+func (a *Admin) Contains(uid string) (l1, l2 bool) {
+	return a.loader.Contains(uid)
+}
+
+// Evict drops one order from every tier and reports whether it was cached.
+func (a *Admin) Evict(uid string) bool {
+	l1, l2 := a.loader.Contains(uid)
+	a.loader.Delete(uid)
+	return l1 || l2
+}
+
+// EvictAll empties the cache and returns how many orders it held.
+func (a *Admin) EvictAll() int {
+	n := a.cache.Len()
+	a.loader.Clear()
+	return n
+}
+
+// Run performs the re-warms requested through Warm until ctx is done.
+func (a *Admin) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.start:
+			a.runWarm(ctx)
+		}
+	}
+}
+
+// Warm asks Run to reload every order from storage and returns the initial
+// progress, or ErrWarmRunning.
+func (a *Admin) Warm() (WarmProgress, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.warm.State == WarmRunning {
+		return a.warm, ErrWarmRunning
+	}
+
+	now := time.Now().UTC()
+	a.warm = WarmProgress{State: WarmRunning, StartedAt: &now}
+	// Only one warm runs at a time, so the slot is free.
+	a.start <- struct{}{}
+	return a.warm, nil
+}
+
+func (a *Admin) WarmProgress() WarmProgress {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.warm
+}
+
+func (a *Admin) runWarm(ctx context.Context) {
+	const op = "cache.Admin.runWarm"
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("Cache warm started")
+
+	var err error
+	after := ""
+	for {
+		var orders []*model.Order
+		orders, err = a.lister.ListOrders(ctx, after, warmPage)
+		if err != nil {
+			break
+		}
+		for _, order := range orders {
+			a.loader.Prime(order)
+		}
+
+		a.mu.Lock()
+		a.warm.Loaded += len(orders)
+		a.mu.Unlock()
+
+		if len(orders) < warmPage {
+			break
+		}
+		after = orders[len(orders)-1].OrderUID
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now().UTC()
+	a.warm.FinishedAt = &now
+	if err != nil {
+		a.warm.State = WarmFailed
+		a.warm.Error = err.Error()
+		if ctx.Err() != nil {
+			log.Warn("Cache warm interrupted by shutdown", "loaded", a.warm.Loaded)
+			return
+		}
+		log.Error("Cache warm failed", sl.Err(err), "loaded", a.warm.Loaded)
+		return
+	}
+	a.warm.State = WarmDone
+	log.Info("Cache warm finished", "loaded", a.warm.Loaded, "duration", now.Sub(*a.warm.StartedAt))
+}
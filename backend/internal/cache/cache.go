@@ -2,34 +2,164 @@ package cache
 
 import (
 	"L0-wbtech/internal/model"
+	"encoding/json"
 	"sync"
 )
 
+// Cache holds frozen orders: Set stores a private copy and every read hands
+// out a fresh one, so callers may modify what they get without affecting
+// the cache or each other.
 type Cache interface {
 	Set(order *model.Order)
 	Get(uid string) (*model.Order, bool)
+	// GetJSON returns the order marshaled to JSON. The bytes are shared and
+	// must not be modified.
+	GetJSON(uid string) ([]byte, bool)
+	// GetMany returns the cached orders among uids, keyed by UID.
+	GetMany(uids []string) map[string]*model.Order
+	Delete(uid string)
+	// Range calls fn for every cached order until fn returns false.
+	Range(fn func(order *model.Order) bool)
+	Len() int
+	Clear()
+}
+
+// frozen is an order no one else holds a pointer to, with its JSON encoding
+// prepared once for serving.
+type frozen struct {
+	order *model.Order
+	json  []byte
+}
+
+func freeze(order *model.Order) *frozen {
+	return adopt(order.Clone())
+}
+
+// adopt freezes an order the caller owns exclusively, such as one just
+// loaded from storage, without copying it.
+func adopt(order *model.Order) *frozen {
+	f := &frozen{order: order}
+	// Orders always marshal; should that change, GetJSON marshals on read.
+	f.json, _ = json.Marshal(order)
+	return f
+}
+
+func (f *frozen) thaw() *model.Order {
+	return f.order.Clone()
+}
+
+// size estimates the memory of the entry: the JSON plus the decoded order,
+// which takes about as much again.
+func (f *frozen) size() int64 {
+	return int64(2 * len(f.json))
+}
+
+func (f *frozen) marshal() ([]byte, error) {
+	if f.json != nil {
+		return f.json, nil
+	}
+	return json.Marshal(f.order)
 }
 
 type inMemoryCache struct {
-	data map[string]*model.Order
-	mu   sync.RWMutex
+	data  map[string]*frozen
+	bytes int64
+	mu    sync.RWMutex
 }
 
 func NewCache() Cache {
 	return &inMemoryCache{
-		data: make(map[string]*model.Order),
+		data: make(map[string]*frozen),
 	}
 }
 
 func (c *inMemoryCache) Set(order *model.Order) {
+	f := freeze(order)
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.data[order.OrderUID] = order
+	if old, ok := c.data[order.OrderUID]; ok {
+		c.bytes -= old.size()
+	}
+	c.data[order.OrderUID] = f
+	c.bytes += f.size()
 }
 
 func (c *inMemoryCache) Get(uid string) (*model.Order, bool) {
+	f, ok := c.get(uid)
+	if !ok {
+		return nil, false
+	}
+	return f.thaw(), true
+}
+
+func (c *inMemoryCache) GetJSON(uid string) ([]byte, bool) {
+	f, ok := c.get(uid)
+	if !ok {
+		return nil, false
+	}
+	data, err := f.marshal()
+	return data, err == nil
+}
+
+func (c *inMemoryCache) get(uid string) (*frozen, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	f, ok := c.data[uid]
+	return f, ok
+}
+
+func (c *inMemoryCache) GetMany(uids []string) map[string]*model.Order {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	order, ok := c.data[uid]
-	return order, ok
+	found := make(map[string]*model.Order, len(uids))
+	for _, uid := range uids {
+		if f, ok := c.data[uid]; ok {
+			found[uid] = f.thaw()
+		}
+	}
+	return found
+}
+
+func (c *inMemoryCache) Delete(uid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f, ok := c.data[uid]; ok {
+		c.bytes -= f.size()
+		delete(c.data, uid)
+	}
+}
+
+func (c *inMemoryCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.data)
+}
+
+func (c *inMemoryCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data = make(map[string]*frozen)
+	c.bytes = 0
+}
+
+// MemoryBytes estimates the memory held by the cached orders.
+func (c *inMemoryCache) MemoryBytes() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.bytes
+}
+
+func (c *inMemoryCache) Range(fn func(order *model.Order) bool) {
+	c.mu.RLock()
+	entries := make([]*frozen, 0, len(c.data))
+	for _, f := range c.data {
+		entries = append(entries, f)
+	}
+	c.mu.RUnlock()
+
+	for _, f := range entries {
+		if !fn(f.thaw()) {
+			return
+		}
+	}
 }
@@ -0,0 +1,220 @@
+package cache_test
+
+import (
+	"L0-wbtech/internal/cache"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage/memory"
+	"L0-wbtech/internal/storage/storagetest"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// These tests are meant for -race: a cache that hands out or keeps a
+// pointer someone else writes through shows up as a data race as well as
+// a changed order.
+
+// mutate changes every part of an order a caller could reach, including
+// the shared backing arrays of its items.
+func mutate(order *model.Order) {
+	order.TrackNumber = "MUTATED"
+	order.Delivery.Name = "Mutated"
+	order.Payment.Amount++
+	order.DateCreated = order.DateCreated.Add(time.Hour)
+	for i := range order.Items {
+		order.Items[i].Name = "mutated"
+		now := time.Now()
+		order.Items[i].ReturnedAt = &now
+	}
+	order.Items = append(order.Items[:cap(order.Items)], model.Item{Name: "extra"})
+}
+
+func marshal(t *testing.T, order *model.Order) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func newReadThrough(store cache.Source) *cache.ReadThrough {
+	return cache.NewReadThrough(cache.NewCache(), store, cache.ReadThroughConfig{}, slog.New(slog.DiscardHandler))
+}
+
+// store writes order to a fresh storage and returns it as stored, with the
+// fields storage fills in.
+func store(t *testing.T, order *model.Order) (*memory.Storage, *model.Order) {
+	t.Helper()
+
+	ctx := context.Background()
+	s := memory.New()
+	if err := s.CreateOrder(ctx, order.Clone(), nil); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := s.GetOrder(ctx, order.OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, stored
+}
+
+// hammer runs read concurrently with writers goroutines that each call
+// write, and waits for all of them.
+func hammer(read, write func()) {
+	const readers, writers, rounds = 4, 4, 200
+
+	var wg sync.WaitGroup
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				read()
+			}
+		}()
+	}
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				write()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCacheReadsAreCopies(t *testing.T) {
+	ctx := context.Background()
+	s, order := store(t, storagetest.NewOrders().Next())
+	want := marshal(t, order)
+	uid := order.OrderUID
+
+	c := cache.NewCache()
+	c.Set(order)
+	r := newReadThrough(s)
+
+	check := func(what string, got *model.Order, ok bool) {
+		if !ok {
+			t.Errorf("%s missed", what)
+			return
+		}
+		if data := marshal(t, got); !bytes.Equal(data, want) {
+			t.Errorf("%s returned a changed order:\n%s\nwant\n%s", what, data, want)
+		}
+	}
+
+	hammer(func() {
+		got, ok := c.Get(uid)
+		check("Cache.Get", got, ok)
+		got, err := r.Load(ctx, uid)
+		check("ReadThrough.Load", got, err == nil)
+		many, err := r.LoadMany(ctx, []string{uid})
+		check("ReadThrough.LoadMany", many[uid], err == nil)
+		many = c.GetMany([]string{uid})
+		check("Cache.GetMany", many[uid], many[uid] != nil)
+	}, func() {
+		if got, ok := c.Get(uid); ok {
+			mutate(got)
+		}
+		if got, err := r.Load(ctx, uid); err == nil {
+			mutate(got)
+		}
+		if many, err := r.LoadMany(ctx, []string{uid}); err == nil && many[uid] != nil {
+			mutate(many[uid])
+		}
+		c.Range(func(got *model.Order) bool {
+			mutate(got)
+			return true
+		})
+	})
+}
+
+func TestCacheSetKeepsACopy(t *testing.T) {
+	ctx := context.Background()
+	base := storagetest.NewOrders().Next()
+	want := marshal(t, base)
+	uid := base.OrderUID
+
+	c := cache.NewCache()
+	r := newReadThrough(memory.New())
+	c.Set(base.Clone())
+	r.Set(base.Clone())
+
+	check := func(what string, data []byte) {
+		if !bytes.Equal(data, want) {
+			t.Errorf("%s returned a changed order:\n%s\nwant\n%s", what, data, want)
+		}
+	}
+
+	// Writers keep storing the same order and changing it afterwards.
+	hammer(func() {
+		data, _ := c.GetJSON(uid)
+		check("Cache.GetJSON", data)
+		data, _ = r.LoadJSON(ctx, uid)
+		check("ReadThrough.LoadJSON", data)
+		if got, ok := c.Get(uid); ok {
+			check("Cache.Get", marshal(t, got))
+		}
+		if got, err := r.Load(ctx, uid); err == nil {
+			check("ReadThrough.Load", marshal(t, got))
+		}
+	}, func() {
+		order := base.Clone()
+		c.Set(order)
+		r.Set(order)
+		mutate(order)
+	})
+}
+
+func TestCacheJSONMatchesOrder(t *testing.T) {
+	ctx := context.Background()
+	gen := storagetest.NewOrders()
+	s, stored := store(t, gen.Next())
+	cached, written := gen.Next(), gen.Next()
+
+	c := cache.NewCache()
+	c.Set(cached)
+	r := newReadThrough(s)
+	r.Set(written)
+
+	for _, tc := range []struct {
+		name  string
+		order *model.Order
+		get   func() ([]byte, error)
+	}{
+		{"Cache.GetJSON", cached, func() ([]byte, error) {
+			data, _ := c.GetJSON(cached.OrderUID)
+			return data, nil
+		}},
+		{"LoadJSON from storage", stored, func() ([]byte, error) { return r.LoadJSON(ctx, stored.OrderUID) }},
+		// The second read is served by the local tier.
+		{"LoadJSON from L1", stored, func() ([]byte, error) { return r.LoadJSON(ctx, stored.OrderUID) }},
+		{"LoadJSON after Set", written, func() ([]byte, error) { return r.LoadJSON(ctx, written.OrderUID) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.get()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := marshal(t, tc.order); !bytes.Equal(data, want) {
+				t.Errorf("body\n%s\nwant\n%s", data, want)
+			}
+
+			var decoded model.Order
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if err := storagetest.Compare(tc.order, &decoded); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"L0-wbtech/internal/model"
+	"L0-wbtech/pkg/logger/sl"
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"log/slog"
+)
+
+// ChangeFeed reports orders written by any replica.
+type ChangeFeed interface {
+	// OrderChanges delivers the UIDs of changed orders until ctx is
+	// cancelled. An empty UID means changes may have been lost, for example
+	// across a reconnect, and the whole cache has to be resynchronised.
+	OrderChanges(ctx context.Context) (<-chan string, error)
+}
+
+// Target is the cache the invalidator keeps up to date, either a Cache or
+// the Loader in front of one.
+type Target interface {
+	Set(order *model.Order)
+	Delete(uid string)
+}
+
+// Invalidator keeps the cache coherent with writes made by other replicas:
+// every changed order is reloaded, and evicted if it cannot be, so the next
+// read goes to storage.
+type Invalidator struct {
+	target Target
+	feed   ChangeFeed
+	source Source
+	log    *slog.Logger
+
+	// lost is set when Run failed, so the next Run resynchronises the
+	// changes made while nothing was listening.
+	lost bool
+}
+
+var errFeedClosed = stdErrors.New("cache: order changes feed closed")
+
+// maxRefreshBatch bounds how many queued changes are reloaded at once.
+const maxRefreshBatch = 500
+
+func NewInvalidator(target Target, feed ChangeFeed, source Source, log *slog.Logger) *Invalidator {
+	return &Invalidator{
+		target: target,
+		feed:   feed,
+		source: source,
+		log:    log,
+	}
+}
+
+// Run applies order changes until ctx is cancelled. It fails when the feed
+// cannot be subscribed to or ends early, so its supervisor restarts it.
+func (i *Invalidator) Run(ctx context.Context) error {
+	const op = "cache.Invalidator.Run"
+	log := i.log.With(slog.String("op", op))
+
+	changes, err := i.feed.OrderChanges(ctx)
+	if err != nil {
+		i.lost = true
+		return fmt.Errorf("%s: subscribe to order changes: %w", op, err)
+	}
+
+	log.Info("Starting cache invalidator")
+
+	if i.lost {
+		i.resync(ctx)
+		i.lost = false
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Cache invalidator stopped")
+			return nil
+		case uid, ok := <-changes:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				i.lost = true
+				return fmt.Errorf("%s: %w", op, errFeedClosed)
+			}
+			uids, resync := i.collect(changes, uid)
+			if resync {
+				i.resync(ctx)
+				continue
+			}
+			i.refresh(ctx, uids)
+		}
+	}
+}
+
+// collect drains the changes already queued behind first, so a burst of
+// writes costs one reload.
+func (i *Invalidator) collect(changes <-chan string, first string) ([]string, bool) {
+	seen := map[string]bool{first: true}
+	uids := []string{first}
+	resync := first == ""
+
+	for len(uids) < maxRefreshBatch {
+		select {
+		case uid, ok := <-changes:
+			if !ok {
+				return uids, resync
+			}
+			if uid == "" {
+				resync = true
+			}
+			if !seen[uid] {
+				seen[uid] = true
+				uids = append(uids, uid)
+			}
+		default:
+			return uids, resync
+		}
+	}
+	return uids, resync
+}
+
+func (i *Invalidator) refresh(ctx context.Context, uids []string) {
+	orders, err := i.source.GetOrders(ctx, uids)
+	if err != nil {
+		if ctx.Err() == nil {
+			i.log.Error("Failed to reload changed orders, evicting", sl.Err(err), "count", len(uids))
+		}
+		orders = nil
+	}
+
+	for _, uid := range uids {
+		if order, ok := orders[uid]; ok {
+			i.target.Set(order)
+		} else {
+			i.target.Delete(uid)
+		}
+	}
+	i.log.Debug("Cache refreshed from order changes", "count", len(uids))
+}
+
+func (i *Invalidator) resync(ctx context.Context) {
+	orders, err := i.source.GetAllOrders(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			i.log.Error("Failed to resynchronise cache", sl.Err(err))
+		}
+		return
+	}
+
+	for _, order := range orders {
+		i.target.Set(order)
+	}
+	i.log.Info("Cache resynchronised", "orders_count", len(orders))
+}
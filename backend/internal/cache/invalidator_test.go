@@ -0,0 +1,172 @@
+package cache_test
+
+import (
+	"L0-wbtech/internal/cache"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage/memory"
+	"L0-wbtech/internal/storage/storagetest"
+	"context"
+	stdErrors "errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// feed hands out changes, or fails to subscribe while err is set.
+type feed struct {
+	mu      sync.Mutex
+	err     error
+	changes chan string
+}
+
+func newFeed() *feed {
+	return &feed{changes: make(chan string, 16)}
+}
+
+func (f *feed) OrderChanges(context.Context) (<-chan string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.changes, nil
+}
+
+// recorder is a target that keeps what happened to it.
+type recorder struct {
+	mu      sync.Mutex
+	set     []string
+	deleted []string
+}
+
+func (r *recorder) Set(order *model.Order) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.set = append(r.set, order.OrderUID)
+}
+
+func (r *recorder) Delete(uid string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleted = append(r.deleted, uid)
+}
+
+func (r *recorder) get() (set, deleted []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	set, deleted = slices.Clone(r.set), slices.Clone(r.deleted)
+	slices.Sort(set)
+	slices.Sort(deleted)
+	return set, deleted
+}
+
+func (r *recorder) waitFor(t *testing.T, set, deleted []string) {
+	t.Helper()
+
+	slices.Sort(set)
+	slices.Sort(deleted)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		gotSet, gotDeleted := r.get()
+		if slices.Equal(gotSet, set) && slices.Equal(gotDeleted, deleted) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("target got set %v, deleted %v, want set %v, deleted %v", gotSet, gotDeleted, set, deleted)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// runInvalidator runs inv until the test ends and returns what Run
+// returned.
+func runInvalidator(t *testing.T, inv *cache.Invalidator) <-chan error {
+	ctx, cancel := context.WithCancel(context.Background())
+	done, finished := make(chan error, 1), make(chan struct{})
+	go func() {
+		defer close(finished)
+		done <- inv.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-finished
+	})
+	return done
+}
+
+func seed(t *testing.T, n int) (*memory.Storage, []string) {
+	t.Helper()
+
+	s := memory.New()
+	gen := storagetest.NewOrders()
+	uids := make([]string, n)
+	for i := range uids {
+		order := gen.Next()
+		if err := s.CreateOrder(context.Background(), order, nil); err != nil {
+			t.Fatal(err)
+		}
+		uids[i] = order.OrderUID
+	}
+	return s, uids
+}
+
+func TestInvalidatorReloadsChangedOrders(t *testing.T) {
+	s, uids := seed(t, 3)
+	f, target := newFeed(), &recorder{}
+	runInvalidator(t, cache.NewInvalidator(target, f, s, slog.New(slog.DiscardHandler)))
+
+	// A burst with a repeat and an order that no longer exists.
+	for _, uid := range []string{uids[0], uids[1], uids[0], "deleted"} {
+		f.changes <- uid
+	}
+	target.waitFor(t, []string{uids[0], uids[1]}, []string{"deleted"})
+}
+
+func TestInvalidatorResyncsOnEmptyUID(t *testing.T) {
+	s, uids := seed(t, 3)
+	f, target := newFeed(), &recorder{}
+	runInvalidator(t, cache.NewInvalidator(target, f, s, slog.New(slog.DiscardHandler)))
+
+	// Changes may have been lost: every order is reloaded, and nothing the
+	// feed did name is evicted.
+	f.changes <- ""
+	target.waitFor(t, uids, nil)
+}
+
+func TestInvalidatorFailsForRestart(t *testing.T) {
+	errListen := stdErrors.New("listen failed")
+
+	for _, tc := range []struct {
+		name string
+		fail func(f *feed)
+		want error
+	}{
+		{"subscribe fails", func(f *feed) { f.err = errListen }, errListen},
+		{"feed closes", func(f *feed) { close(f.changes) }, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, uids := seed(t, 2)
+			f, target := newFeed(), &recorder{}
+			inv := cache.NewInvalidator(target, f, s, slog.New(slog.DiscardHandler))
+
+			tc.fail(f)
+			select {
+			case err := <-runInvalidator(t, inv):
+				if err == nil || (tc.want != nil && !stdErrors.Is(err, tc.want)) {
+					t.Fatalf("Run returned %v, want a failure", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Run kept going without a feed")
+			}
+
+			// The restarted invalidator catches up on what it missed.
+			f.mu.Lock()
+			f.err, f.changes = nil, make(chan string, 16)
+			f.mu.Unlock()
+			runInvalidator(t, inv)
+			target.waitFor(t, uids, nil)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lru is a size bounded map whose entries expire after a TTL. A nil entry
+// records a negative lookup.
+type lru struct {
+	mu    sync.Mutex
+	size  int
+	items map[string]*list.Element
+	order *list.List
+}
+
+type lruEntry struct {
+	uid       string
+	order     *frozen
+	expiresAt time.Time
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:  size,
+		items: make(map[string]*list.Element, size),
+		order: list.New(),
+	}
+}
+
+// get returns the entry for uid. found with a nil order is a cached miss.
+func (l *lru) get(uid string) (order *frozen, found bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.items[uid]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if time.Now().After(e.expiresAt) {
+		l.removeElement(el)
+		return nil, false
+	}
+	l.order.MoveToFront(el)
+	return e.order, true
+}
+
+func (l *lru) add(uid string, order *frozen, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if el, ok := l.items[uid]; ok {
+		e := el.Value.(*lruEntry)
+		e.order = order
+		e.expiresAt = expiresAt
+		l.order.MoveToFront(el)
+		return
+	}
+
+	l.items[uid] = l.order.PushFront(&lruEntry{uid: uid, order: order, expiresAt: expiresAt})
+	for l.order.Len() > l.size {
+		l.removeElement(l.order.Back())
+	}
+}
+
+func (l *lru) remove(uid string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if el, ok := l.items[uid]; ok {
+		l.removeElement(el)
+	}
+}
+
+func (l *lru) removeElement(el *list.Element) {
+	l.order.Remove(el)
+	delete(l.items, el.Value.(*lruEntry).uid)
+}
+
+// contains reports whether uid holds a live order, without refreshing it.
+func (l *lru) contains(uid string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.items[uid]
+	if !ok {
+		return false
+	}
+	e := el.Value.(*lruEntry)
+	return e.order != nil && time.Now().Before(e.expiresAt)
+}
+
+func (l *lru) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+// each calls fn for every live order until fn returns false. fn runs
+// outside the lock.
+func (l *lru) each(fn func(order *frozen) bool) {
+	l.mu.Lock()
+	now := time.Now()
+	orders := make([]*frozen, 0, l.order.Len())
+	for el := l.order.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*lruEntry)
+		if e.order != nil && now.Before(e.expiresAt) {
+			orders = append(orders, e.order)
+		}
+	}
+	l.mu.Unlock()
+
+	for _, f := range orders {
+		if !fn(f) {
+			return
+		}
+	}
+}
+
+// bytes estimates the memory held by the cached orders.
+func (l *lru) bytes() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var n int64
+	for el := l.order.Front(); el != nil; el = el.Next() {
+		if f := el.Value.(*lruEntry).order; f != nil {
+			n += f.size()
+		}
+	}
+	return n
+}
+
+func (l *lru) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.items = make(map[string]*list.Element, l.size)
+	l.order.Init()
+}
@@ -0,0 +1,273 @@
+package cache
+
+import (
+	"L0-wbtech/internal/model"
+	"L0-wbtech/pkg/errors"
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Source is the storage behind the caches.
+type Source interface {
+	GetOrder(ctx context.Context, orderUID string) (*model.Order, error)
+	GetOrders(ctx context.Context, orderUIDs []string) (map[string]*model.Order, error)
+	GetAllOrders(ctx context.Context) (map[string]*model.Order, error)
+}
+
+// Loader serves orders read-through, so callers never fill a cache by hand.
+type Loader interface {
+	// Load returns errors.ErrNotFound for an order that does not exist.
+	Load(ctx context.Context, uid string) (*model.Order, error)
+	// LoadJSON is Load with the order marshaled to JSON. The bytes are
+	// shared and must not be modified.
+	LoadJSON(ctx context.Context, uid string) ([]byte, error)
+	// LoadMany returns the existing orders among uids, keyed by UID.
+	LoadMany(ctx context.Context, uids []string) (map[string]*model.Order, error)
+	// Set stores an order this process has just written.
+	Set(order *model.Order)
+	// Prime stores an order in the shared tier only, for bulk warm-up.
+	Prime(order *model.Order)
+	Delete(uid string)
+}
+
+type ReadThroughConfig struct {
+	// L1Size bounds the local tier.
+	L1Size int
+	// L1TTL bounds how long the local tier may serve an order another
+	// replica has since changed in the shared tier.
+	L1TTL time.Duration
+	// NegativeTTL is how long a missing order is remembered.
+	NegativeTTL time.Duration
+}
+
+// ReadThrough layers a small local LRU (L1) over a shared cache (L2) and
+// storage. Concurrent misses for one order share a single storage read,
+// and orders that do not exist are remembered for NegativeTTL so repeated
+// lookups of bogus UIDs stay off the database.
+type ReadThrough struct {
+	l1     *lru
+	l2     Cache
+	source Source
+	group  singleflight.Group
+	cfg    ReadThroughConfig
+	log    *slog.Logger
+
+	l1Hits       atomic.Uint64
+	l2Hits       atomic.Uint64
+	negativeHits atomic.Uint64
+	misses       atomic.Uint64
+}
+
+// Counters are the lookups served by each layer since startup. Lookups
+// answered by a remembered miss count as NegativeHits; Misses reached
+// storage.
+type Counters struct {
+	L1Hits       uint64 `json:"l1_hits"`
+	L2Hits       uint64 `json:"l2_hits"`
+	NegativeHits uint64 `json:"negative_hits"`
+	Misses       uint64 `json:"misses"`
+}
+
+// HitRatio is the share of lookups that did not reach storage.
+func (c Counters) HitRatio() float64 {
+	hits := c.L1Hits + c.L2Hits + c.NegativeHits
+	if hits+c.Misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+c.Misses)
+}
+
+func NewReadThrough(l2 Cache, source Source, cfg ReadThroughConfig, log *slog.Logger) *ReadThrough {
+	if cfg.L1Size <= 0 {
+		cfg.L1Size = 1024
+	}
+	if cfg.L1TTL <= 0 {
+		cfg.L1TTL = 30 * time.Second
+	}
+	if cfg.NegativeTTL <= 0 {
+		cfg.NegativeTTL = 5 * time.Second
+	}
+	return &ReadThrough{
+		l1:     newLRU(cfg.L1Size),
+		l2:     l2,
+		source: source,
+		cfg:    cfg,
+		log:    log,
+	}
+}
+
+func (r *ReadThrough) Load(ctx context.Context, uid string) (*model.Order, error) {
+	const op = "cache.ReadThrough.Load"
+
+	f, err := r.load(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return f.thaw(), nil
+}
+
+func (r *ReadThrough) LoadJSON(ctx context.Context, uid string) ([]byte, error) {
+	const op = "cache.ReadThrough.LoadJSON"
+
+	f, err := r.load(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	data, err := f.marshal()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return data, nil
+}
+
+func (r *ReadThrough) load(ctx context.Context, uid string) (*frozen, error) {
+	if f, ok := r.l1.get(uid); ok {
+		if f == nil {
+			r.negativeHits.Add(1)
+			return nil, errors.ErrNotFound
+		}
+		r.l1Hits.Add(1)
+		return f, nil
+	}
+
+	// The flight outlives a caller that gives up, so the callers sharing it
+	// are not cancelled along with the first one.
+	ch := r.group.DoChan(uid, func() (any, error) {
+		return r.fetch(context.WithoutCancel(ctx), uid)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*frozen), nil
+	}
+}
+
+func (r *ReadThrough) fetch(ctx context.Context, uid string) (*frozen, error) {
+	if order, ok := r.l2.Get(uid); ok {
+		r.l2Hits.Add(1)
+		f := adopt(order)
+		r.l1.add(uid, f, r.cfg.L1TTL)
+		return f, nil
+	}
+
+	r.misses.Add(1)
+	order, err := r.source.GetOrder(ctx, uid)
+	if err != nil {
+		if stdErrors.Is(err, errors.ErrNotFound) {
+			r.l1.add(uid, nil, r.cfg.NegativeTTL)
+			r.log.Debug("Remembering missing order", "order_uid", uid, "ttl", r.cfg.NegativeTTL)
+			return nil, errors.ErrNotFound
+		}
+		return nil, err
+	}
+
+	r.l2.Set(order)
+	f := adopt(order)
+	r.l1.add(uid, f, r.cfg.L1TTL)
+	return f, nil
+}
+
+func (r *ReadThrough) LoadMany(ctx context.Context, uids []string) (map[string]*model.Order, error) {
+	const op = "cache.ReadThrough.LoadMany"
+
+	found := make(map[string]*model.Order, len(uids))
+	var misses []string
+	for _, uid := range uids {
+		f, ok := r.l1.get(uid)
+		switch {
+		case !ok:
+			misses = append(misses, uid)
+		case f != nil:
+			r.l1Hits.Add(1)
+			found[uid] = f.thaw()
+		default:
+			r.negativeHits.Add(1)
+		}
+	}
+	if len(misses) == 0 {
+		return found, nil
+	}
+
+	shared := r.l2.GetMany(misses)
+	var rest []string
+	for _, uid := range misses {
+		if order, ok := shared[uid]; ok {
+			r.l1.add(uid, freeze(order), r.cfg.L1TTL)
+			found[uid] = order
+		} else {
+			rest = append(rest, uid)
+		}
+	}
+	r.l2Hits.Add(uint64(len(misses) - len(rest)))
+	if len(rest) == 0 {
+		return found, nil
+	}
+
+	r.misses.Add(uint64(len(rest)))
+
+	loaded, err := r.source.GetOrders(ctx, rest)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, uid := range rest {
+		order, ok := loaded[uid]
+		if !ok {
+			r.l1.add(uid, nil, r.cfg.NegativeTTL)
+			continue
+		}
+		r.l2.Set(order)
+		r.l1.add(uid, freeze(order), r.cfg.L1TTL)
+		found[uid] = order
+	}
+	return found, nil
+}
+
+func (r *ReadThrough) Set(order *model.Order) {
+	r.l2.Set(order)
+	r.l1.add(order.OrderUID, freeze(order), r.cfg.L1TTL)
+}
+
+func (r *ReadThrough) Prime(order *model.Order) {
+	r.l2.Set(order)
+}
+
+func (r *ReadThrough) Delete(uid string) {
+	r.l2.Delete(uid)
+	r.l1.remove(uid)
+}
+
+// Contains reports which tiers hold the order, without loading it.
+func (r *ReadThrough) Contains(uid string) (l1, l2 bool) {
+	_, l2 = r.l2.Get(uid)
+	return r.l1.contains(uid), l2
+}
+
+// Clear empties both tiers, including remembered misses.
+func (r *ReadThrough) Clear() {
+	r.l2.Clear()
+	r.l1.clear()
+}
+
+func (r *ReadThrough) L1Len() int {
+	return r.l1.len()
+}
+
+func (r *ReadThrough) Counters() Counters {
+	return Counters{
+		L1Hits:       r.l1Hits.Load(),
+		L2Hits:       r.l2Hits.Load(),
+		NegativeHits: r.negativeHits.Load(),
+		Misses:       r.misses.Load(),
+	}
+}
@@ -0,0 +1,249 @@
+package cache_test
+
+import (
+	"L0-wbtech/internal/cache"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage/memory"
+	"L0-wbtech/internal/storage/storagetest"
+	"L0-wbtech/pkg/errors"
+	"context"
+	stdErrors "errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingSource counts the storage reads per order. With a gate it holds
+// every read until the gate is closed, announcing it on started.
+type countingSource struct {
+	*memory.Storage
+
+	gate    chan struct{}
+	started chan string
+
+	mu     sync.Mutex
+	calls  map[string]int
+	ctxErr error
+}
+
+func newCountingSource(gated bool, orders ...*model.Order) *countingSource {
+	s := &countingSource{
+		Storage: memory.New(),
+		calls:   make(map[string]int),
+		started: make(chan string, 16),
+	}
+	if gated {
+		s.gate = make(chan struct{})
+	}
+	for _, order := range orders {
+		if err := s.CreateOrder(context.Background(), order, nil); err != nil {
+			panic(err)
+		}
+	}
+	return s
+}
+
+func (s *countingSource) GetOrder(ctx context.Context, uid string) (*model.Order, error) {
+	s.mu.Lock()
+	s.calls[uid]++
+	s.mu.Unlock()
+
+	if s.gate != nil {
+		s.started <- uid
+		<-s.gate
+	}
+
+	s.mu.Lock()
+	s.ctxErr = ctx.Err()
+	s.mu.Unlock()
+	return s.Storage.GetOrder(ctx, uid)
+}
+
+func (s *countingSource) count(uid string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[uid]
+}
+
+func (s *countingSource) waitStarted(t *testing.T) {
+	t.Helper()
+
+	select {
+	case <-s.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("storage read did not start")
+	}
+}
+
+func newTestReadThrough(source cache.Source, cfg cache.ReadThroughConfig) *cache.ReadThrough {
+	return cache.NewReadThrough(cache.NewCache(), source, cfg, slog.New(slog.DiscardHandler))
+}
+
+func TestReadThroughCoalescesMisses(t *testing.T) {
+	const callers = 10
+
+	order := storagetest.NewOrders().Next()
+	source := newCountingSource(true, order)
+	rt := newTestReadThrough(source, cache.ReadThroughConfig{})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	load := func() {
+		defer wg.Done()
+		got, err := rt.Load(context.Background(), order.OrderUID)
+		if err == nil && got.OrderUID != order.OrderUID {
+			err = stdErrors.New("loaded " + got.OrderUID)
+		}
+		errs <- err
+	}
+
+	wg.Add(1)
+	go load()
+	source.waitStarted(t)
+	for range callers - 1 {
+		wg.Add(1)
+		go load()
+	}
+	// Give the late callers time to join the flight before it lands.
+	time.Sleep(50 * time.Millisecond)
+	close(source.gate)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if n := source.count(order.OrderUID); n != 1 {
+		t.Errorf("%d concurrent misses made %d storage reads, want 1", callers, n)
+	}
+}
+
+func TestReadThroughRemembersMissingOrders(t *testing.T) {
+	const ttl = 100 * time.Millisecond
+
+	order := storagetest.NewOrders().Next()
+	source := newCountingSource(false)
+	rt := newTestReadThrough(source, cache.ReadThroughConfig{NegativeTTL: ttl})
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := rt.Load(ctx, order.OrderUID); !stdErrors.Is(err, errors.ErrNotFound) {
+			t.Fatalf("Load of a missing order returned %v, want %v", err, errors.ErrNotFound)
+		}
+	}
+	if n := source.count(order.OrderUID); n != 1 {
+		t.Errorf("repeated misses made %d storage reads, want 1", n)
+	}
+	if c := rt.Counters(); c.NegativeHits != 2 || c.Misses != 1 {
+		t.Errorf("counters %+v, want 2 negative hits and 1 miss", c)
+	}
+
+	// The order appears, but the remembered miss stands until it expires.
+	if err := source.CreateOrder(ctx, order, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rt.Load(ctx, order.OrderUID); !stdErrors.Is(err, errors.ErrNotFound) {
+		t.Errorf("Load within the negative TTL returned %v, want %v", err, errors.ErrNotFound)
+	}
+
+	time.Sleep(ttl + 20*time.Millisecond)
+	if _, err := rt.Load(ctx, order.OrderUID); err != nil {
+		t.Errorf("Load after the negative TTL: %v", err)
+	}
+	if n := source.count(order.OrderUID); n != 2 {
+		t.Errorf("made %d storage reads, want 2", n)
+	}
+}
+
+func TestReadThroughEvictsFromL1(t *testing.T) {
+	gen := storagetest.NewOrders()
+	a, b, c := gen.Next(), gen.Next(), gen.Next()
+	source := newCountingSource(false, a, b, c)
+	rt := newTestReadThrough(source, cache.ReadThroughConfig{L1Size: 2})
+	ctx := context.Background()
+
+	// a is used again after b, so b is the least recently used when c
+	// comes in.
+	for _, order := range []*model.Order{a, b, a, c} {
+		if _, err := rt.Load(ctx, order.OrderUID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := rt.L1Len(); n != 2 {
+		t.Errorf("L1 holds %d orders, want 2", n)
+	}
+	for _, tc := range []struct {
+		order  *model.Order
+		wantL1 bool
+	}{
+		{a, true},
+		{b, false},
+		{c, true},
+	} {
+		l1, l2 := rt.Contains(tc.order.OrderUID)
+		if l1 != tc.wantL1 || !l2 {
+			t.Errorf("order %s in L1 %t, L2 %t, want %t and true", tc.order.OrderUID, l1, l2, tc.wantL1)
+		}
+	}
+
+	// The evicted order comes back from L2, not storage.
+	if _, err := rt.Load(ctx, b.OrderUID); err != nil {
+		t.Fatal(err)
+	}
+	if n := source.count(b.OrderUID); n != 1 {
+		t.Errorf("evicted order read from storage %d times, want 1", n)
+	}
+	if got := rt.Counters(); got.L1Hits != 1 || got.L2Hits != 1 || got.Misses != 3 {
+		t.Errorf("counters %+v, want 1 L1 hit, 1 L2 hit and 3 misses", got)
+	}
+}
+
+func TestReadThroughLoadOutlivesCancelledCaller(t *testing.T) {
+	order := storagetest.NewOrders().Next()
+	source := newCountingSource(true, order)
+	rt := newTestReadThrough(source, cache.ReadThroughConfig{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := rt.Load(ctx, order.OrderUID)
+		done <- err
+	}()
+	source.waitStarted(t)
+
+	cancel()
+	select {
+	case err := <-done:
+		if !stdErrors.Is(err, context.Canceled) {
+			t.Errorf("cancelled Load returned %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled Load kept waiting for storage")
+	}
+
+	// The read finishes on its own and fills the cache for the next caller.
+	close(source.gate)
+	deadline := time.Now().Add(5 * time.Second)
+	for l1, _ := rt.Contains(order.OrderUID); !l1; l1, _ = rt.Contains(order.OrderUID) {
+		if time.Now().After(deadline) {
+			t.Fatal("abandoned load did not fill the cache")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if _, err := rt.Load(context.Background(), order.OrderUID); err != nil {
+		t.Fatal(err)
+	}
+	if n := source.count(order.OrderUID); n != 1 {
+		t.Errorf("made %d storage reads, want 1", n)
+	}
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	if source.ctxErr != nil {
+		t.Errorf("storage read saw a %v context", source.ctxErr)
+	}
+}
@@ -0,0 +1,393 @@
+package cache
+
+import (
+	"L0-wbtech/internal/cache/resp"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/pkg/logger/sl"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ugorji/go/codec"
+)
+
+const (
+	defaultRemotePrefix    = "orders:"
+	defaultRemoteTTL       = 24 * time.Hour
+	defaultRemoteLocalSize = 1024
+	// defaultRemoteRetryAfter is how long a replica serves from memory
+	// after the remote cache fails before trying it again.
+	defaultRemoteRetryAfter = 5 * time.Second
+	// mgetChunk bounds the keys of one MGET in a pipelined batch read.
+	mgetChunk = 100
+	// clearTimeout bounds the whole key scan of Clear.
+	clearTimeout = time.Minute
+	// resyncTimeout bounds the clean-up after an outage, whose backlog can
+	// be far larger than what one call handles.
+	resyncTimeout = 10 * time.Second
+)
+
+type RemoteConfig struct {
+	Addr     string
+	Password string
+	DB       int
+	Prefix   string
+	TTL      time.Duration
+	Timeout  time.Duration
+	PoolSize int
+	// LocalSize bounds the in-memory mirror, like the L1 size bounds the
+	// read-through LRU.
+	LocalSize  int
+	RetryAfter time.Duration
+}
+
+// RemoteCache shares orders between replicas through a server speaking the
+// Redis protocol, stored as msgpack under a key prefix with a TTL. The
+// most recent writes are mirrored into a bounded LRU, which serves reads
+// while the remote cache is unreachable. Orders changed during an outage
+// are deleted from it once it answers again, so they reload from storage.
+type RemoteCache struct {
+	client     *resp.Client
+	local      *lru
+	localTTL   time.Duration
+	prefix     string
+	ttl        string
+	timeout    time.Duration
+	retryAfter time.Duration
+	log        *slog.Logger
+
+	mu        sync.Mutex
+	downUntil time.Time
+	pending   map[string]struct{}
+}
+
+func NewRemoteCache(cfg RemoteConfig, log *slog.Logger) *RemoteCache {
+	if cfg.Prefix == "" {
+		cfg.Prefix = defaultRemotePrefix
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultRemoteTTL
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 200 * time.Millisecond
+	}
+	if cfg.LocalSize <= 0 {
+		cfg.LocalSize = defaultRemoteLocalSize
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = defaultRemoteRetryAfter
+	}
+
+	return &RemoteCache{
+		client: resp.NewClient(resp.Config{
+			Addr:        cfg.Addr,
+			Password:    cfg.Password,
+			DB:          cfg.DB,
+			PoolSize:    cfg.PoolSize,
+			DialTimeout: cfg.Timeout,
+			Timeout:     cfg.Timeout,
+		}),
+		local:      newLRU(cfg.LocalSize),
+		localTTL:   cfg.TTL,
+		prefix:     cfg.Prefix,
+		ttl:        strconv.FormatInt(cfg.TTL.Milliseconds(), 10),
+		timeout:    cfg.Timeout,
+		retryAfter: cfg.RetryAfter,
+		log:        log,
+		pending:    make(map[string]struct{}),
+	}
+}
+
+func (c *RemoteCache) Set(order *model.Order) {
+	const op = "cache.RemoteCache.Set"
+
+	c.local.add(order.OrderUID, freeze(order), c.localTTL)
+
+	data, err := c.encode(order)
+	if err != nil {
+		c.log.Error("Failed to encode order", slog.String("op", op), sl.Err(err), "order_uid", order.OrderUID)
+		return
+	}
+
+	c.remote(op, []string{order.OrderUID}, func(ctx context.Context) error {
+		_, err := c.client.Do(ctx, "SET", c.key(order.OrderUID), data, "PX", c.ttl)
+		return err
+	})
+}
+
+func (c *RemoteCache) Get(uid string) (*model.Order, bool) {
+	order, ok := c.GetMany([]string{uid})[uid]
+	return order, ok
+}
+
+// GetJSON marshals the order read from the remote cache, which keeps it in
+// msgpack.
+func (c *RemoteCache) GetJSON(uid string) ([]byte, bool) {
+	order, ok := c.Get(uid)
+	if !ok {
+		return nil, false
+	}
+	data, err := json.Marshal(order)
+	return data, err == nil
+}
+
+// GetMany reads the keys in chunks of MGET sent as one pipeline.
+func (c *RemoteCache) GetMany(uids []string) map[string]*model.Order {
+	const op = "cache.RemoteCache.GetMany"
+
+	if len(uids) == 0 {
+		return map[string]*model.Order{}
+	}
+
+	var found map[string]*model.Order
+	ok := c.remote(op, nil, func(ctx context.Context) error {
+		var cmds [][]string
+		for start := 0; start < len(uids); start += mgetChunk {
+			chunk := uids[start:min(start+mgetChunk, len(uids))]
+			cmd := make([]string, 0, len(chunk)+1)
+			cmd = append(cmd, "MGET")
+			for _, uid := range chunk {
+				cmd = append(cmd, c.key(uid))
+			}
+			cmds = append(cmds, cmd)
+		}
+
+		replies, err := c.client.Pipeline(ctx, cmds)
+		if err != nil {
+			return err
+		}
+
+		found = make(map[string]*model.Order, len(uids))
+		for i, reply := range replies {
+			if reply.Err != nil {
+				return reply.Err
+			}
+			for j, value := range reply.Array {
+				if value.Nil {
+					continue
+				}
+				uid := uids[i*mgetChunk+j]
+				order, err := c.decode(value.Str)
+				if err != nil {
+					c.log.Warn("Dropping undecodable cache entry", slog.String("op", op), sl.Err(err), "order_uid", uid)
+					continue
+				}
+				found[uid] = order
+			}
+		}
+		return nil
+	})
+	if !ok {
+		return c.localMany(uids)
+	}
+	return found
+}
+
+func (c *RemoteCache) localMany(uids []string) map[string]*model.Order {
+	found := make(map[string]*model.Order, len(uids))
+	for _, uid := range uids {
+		if f, ok := c.local.get(uid); ok && f != nil {
+			found[uid] = f.thaw()
+		}
+	}
+	return found
+}
+
+func (c *RemoteCache) Delete(uid string) {
+	const op = "cache.RemoteCache.Delete"
+
+	c.local.remove(uid)
+	c.remote(op, []string{uid}, func(ctx context.Context) error {
+		_, err := c.client.Do(ctx, "DEL", c.key(uid))
+		return err
+	})
+}
+
+// Range walks the in-memory mirror; the remote cache is not enumerated.
+func (c *RemoteCache) Range(fn func(order *model.Order) bool) {
+	c.local.each(func(f *frozen) bool {
+		return fn(f.thaw())
+	})
+}
+
+// Len counts the in-memory mirror, which holds what this replica has seen
+// rather than everything in the shared cache.
+func (c *RemoteCache) Len() int {
+	return c.local.len()
+}
+
+func (c *RemoteCache) MemoryBytes() int64 {
+	return c.local.bytes()
+}
+
+// Clear deletes every key under the prefix, leaving other data in the same
+// database alone.
+func (c *RemoteCache) Clear() {
+	const op = "cache.RemoteCache.Clear"
+
+	c.local.clear()
+
+	ctx, cancel := context.WithTimeout(context.Background(), clearTimeout)
+	defer cancel()
+
+	cursor := "0"
+	for {
+		reply, err := c.client.Do(ctx, "SCAN", cursor, "MATCH", c.prefix+"*", "COUNT", strconv.Itoa(mgetChunk))
+		if err == nil && len(reply.Array) != 2 {
+			err = fmt.Errorf("unexpected SCAN reply")
+		}
+		if err != nil {
+			c.log.Error("Failed to clear remote cache", slog.String("op", op), sl.Err(err))
+			return
+		}
+
+		cursor = string(reply.Array[0].Str)
+		if keys := reply.Array[1].Array; len(keys) > 0 {
+			cmd := make([]string, 0, len(keys)+1)
+			cmd = append(cmd, "DEL")
+			for _, key := range keys {
+				cmd = append(cmd, string(key.Str))
+			}
+			if _, err := c.client.Do(ctx, cmd...); err != nil {
+				c.log.Error("Failed to clear remote cache", slog.String("op", op), sl.Err(err))
+				return
+			}
+		}
+		if cursor == "0" {
+			return
+		}
+	}
+}
+
+func (c *RemoteCache) Close() error {
+	return c.client.Close()
+}
+
+// remote runs fn against the remote cache unless it is known to be down,
+// and reports whether it succeeded. The UIDs a failed write touched are
+// remembered and written back from memory on recovery.
+func (c *RemoteCache) remote(op string, uids []string, fn func(ctx context.Context) error) bool {
+	c.mu.Lock()
+	if time.Now().Before(c.downUntil) {
+		for _, uid := range uids {
+			c.pending[uid] = struct{}{}
+		}
+		c.mu.Unlock()
+		return false
+	}
+	c.mu.Unlock()
+
+	if err := c.resync(); err != nil {
+		c.markDown(op, uids, err)
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	if err := fn(ctx); err != nil {
+		c.markDown(op, uids, err)
+		return false
+	}
+	return true
+}
+
+func (c *RemoteCache) markDown(op string, uids []string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, uid := range uids {
+		c.pending[uid] = struct{}{}
+	}
+	if time.Now().Before(c.downUntil) {
+		return
+	}
+	c.downUntil = time.Now().Add(c.retryAfter)
+	c.log.Warn("Remote cache unavailable, serving from memory",
+		slog.String("op", op),
+		sl.Err(err),
+		slog.Duration("retry_after", c.retryAfter))
+}
+
+// resync deletes the orders changed while the remote cache was down. The
+// memory copies are not written back: other replicas may have stored newer
+// versions meanwhile, and the next read reloads the order from storage.
+func (c *RemoteCache) resync() error {
+	c.mu.Lock()
+	if len(c.pending) == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	pending := c.pending
+	c.pending = make(map[string]struct{})
+	c.mu.Unlock()
+
+	var cmds [][]string
+	for uid := range pending {
+		if len(cmds) == 0 || len(cmds[len(cmds)-1]) > mgetChunk {
+			cmds = append(cmds, []string{"DEL"})
+		}
+		cmds[len(cmds)-1] = append(cmds[len(cmds)-1], c.key(uid))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resyncTimeout)
+	defer cancel()
+
+	replies, err := c.client.Pipeline(ctx, cmds)
+	if err == nil {
+		for _, reply := range replies {
+			if reply.Err != nil {
+				err = reply.Err
+				break
+			}
+		}
+	}
+	if err != nil {
+		c.mu.Lock()
+		for uid := range pending {
+			c.pending[uid] = struct{}{}
+		}
+		c.mu.Unlock()
+		return fmt.Errorf("cache.RemoteCache.resync: %w", err)
+	}
+
+	c.log.Info("Remote cache recovered", "invalidated", len(pending))
+	return nil
+}
+
+func (c *RemoteCache) key(uid string) string {
+	return c.prefix + uid
+}
+
+func (c *RemoteCache) encode(order *model.Order) (string, error) {
+	data, err := encodeOrder(order)
+	return string(data), err
+}
+
+func (c *RemoteCache) decode(data []byte) (*model.Order, error) {
+	return decodeOrder(data)
+}
+
+// msgpack encodes times with the timestamp extension, so they keep their
+// full precision.
+var msgpack = &codec.MsgpackHandle{WriteExt: true}
+
+func encodeOrder(order *model.Order) ([]byte, error) {
+	var data []byte
+	if err := codec.NewEncoderBytes(&data, msgpack).Encode(order); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func decodeOrder(data []byte) (*model.Order, error) {
+	var order model.Order
+	if err := codec.NewDecoderBytes(data, msgpack).Decode(&order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
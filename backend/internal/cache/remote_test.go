@@ -0,0 +1,243 @@
+package cache_test
+
+import (
+	"L0-wbtech/internal/cache"
+	"L0-wbtech/internal/cache/resptest"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage/storagetest"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	testPrefix = "test:"
+	testTTL    = time.Hour
+)
+
+func startServer(t *testing.T) *resptest.Server {
+	t.Helper()
+
+	srv, err := resptest.Start("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+func newRemote(t *testing.T, srv *resptest.Server, cfg cache.RemoteConfig) *cache.RemoteCache {
+	t.Helper()
+
+	cfg.Addr = srv.Addr()
+	cfg.Password = "secret"
+	cfg.Prefix = testPrefix
+	cfg.TTL = testTTL
+	if cfg.Timeout == 0 {
+		cfg.Timeout = time.Second
+	}
+	c := cache.NewRemoteCache(cfg, slog.New(slog.DiscardHandler))
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// mustEqual fails unless got is exactly want, down to the nanoseconds of
+// every timestamp.
+func mustEqual(t *testing.T, want, got *model.Order) {
+	t.Helper()
+
+	if got == nil {
+		t.Fatalf("order %s: got nil", want.OrderUID)
+	}
+	if !got.DateCreated.Equal(want.DateCreated) {
+		t.Errorf("order %s: date_created %v, want %v", want.OrderUID, got.DateCreated, want.DateCreated)
+	}
+	w, g := *want, *got
+	w.DateCreated, g.DateCreated = time.Time{}, time.Time{}
+	w.StatusUpdatedAt, g.StatusUpdatedAt = time.Time{}, time.Time{}
+	if !got.StatusUpdatedAt.Equal(want.StatusUpdatedAt) {
+		t.Errorf("order %s: status_updated_at %v, want %v", want.OrderUID, got.StatusUpdatedAt, want.StatusUpdatedAt)
+	}
+	if !reflect.DeepEqual(w, g) {
+		t.Errorf("order %s: got\n\t%+v\nwant\n\t%+v", want.OrderUID, g, w)
+	}
+}
+
+func TestRemoteCacheRoundTrip(t *testing.T) {
+	srv := startServer(t)
+	writer := newRemote(t, srv, cache.RemoteConfig{})
+	// A second replica has nothing in its mirror, so it reads the server.
+	reader := newRemote(t, srv, cache.RemoteConfig{})
+
+	want := storagetest.NewOrders().Next()
+	want.DateCreated = time.Date(2024, 3, 1, 12, 30, 45, 123456789, time.UTC)
+	writer.Set(want)
+
+	got, ok := reader.Get(want.OrderUID)
+	if !ok {
+		t.Fatal("order not found in the remote cache")
+	}
+	mustEqual(t, want, got)
+
+	data, ok := reader.GetJSON(want.OrderUID)
+	if !ok {
+		t.Fatal("GetJSON missed")
+	}
+	var decoded model.Order
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	mustEqual(t, want, &decoded)
+}
+
+func TestRemoteCacheKeyPrefixAndTTL(t *testing.T) {
+	srv := startServer(t)
+	c := newRemote(t, srv, cache.RemoteConfig{})
+
+	order := storagetest.NewOrders().Next()
+	c.Set(order)
+
+	key := testPrefix + order.OrderUID
+	if keys := srv.Keys(); !slices.Equal(keys, []string{key}) {
+		t.Fatalf("server keys = %v, want [%s]", keys, key)
+	}
+	if ttl := srv.TTL(key); ttl <= testTTL-time.Minute || ttl > testTTL {
+		t.Errorf("TTL of %s = %v, want about %v", key, ttl, testTTL)
+	}
+
+	srv.FastForward(testTTL)
+	if keys := srv.Keys(); len(keys) != 0 {
+		t.Errorf("server keys after the TTL = %v, want none", keys)
+	}
+	reader := newRemote(t, srv, cache.RemoteConfig{})
+	if _, ok := reader.Get(order.OrderUID); ok {
+		t.Error("expired order still served")
+	}
+}
+
+func TestRemoteCacheGetManyPipelines(t *testing.T) {
+	const n = 250
+
+	srv := startServer(t)
+	writer := newRemote(t, srv, cache.RemoteConfig{})
+	reader := newRemote(t, srv, cache.RemoteConfig{})
+
+	gen := storagetest.NewOrders()
+	uids := make([]string, 0, n+1)
+	for range n {
+		order := gen.Next()
+		writer.Set(order)
+		uids = append(uids, order.OrderUID)
+	}
+	uids = append(uids, "missing")
+
+	before := srv.Lookups()
+	found := reader.GetMany(uids)
+	if len(found) != n {
+		t.Fatalf("GetMany found %d orders, want %d", len(found), n)
+	}
+	for _, uid := range uids[:n] {
+		if found[uid] == nil || found[uid].OrderUID != uid {
+			t.Fatalf("GetMany returned %v under %s", found[uid], uid)
+		}
+	}
+	// 251 keys in chunks of 100.
+	if lookups := srv.Lookups() - before; lookups != 3 {
+		t.Errorf("GetMany sent %d MGETs, want 3", lookups)
+	}
+}
+
+func TestRemoteCacheFallsBackAndResyncs(t *testing.T) {
+	const retryAfter = 50 * time.Millisecond
+
+	srv := startServer(t)
+	c := newRemote(t, srv, cache.RemoteConfig{RetryAfter: retryAfter})
+
+	gen := storagetest.NewOrders()
+	before, during, untouched := gen.Next(), gen.Next(), gen.Next()
+	c.Set(before)
+	c.Set(untouched)
+
+	if err := srv.Close(); err != nil {
+		t.Fatal(err)
+	}
+	changed := before.Clone()
+	changed.TrackNumber = "CHANGED"
+	c.Set(changed)
+	c.Set(during)
+
+	for _, want := range []*model.Order{changed, during} {
+		got, ok := c.Get(want.OrderUID)
+		if !ok {
+			t.Fatalf("order %s not served from memory while the server is down", want.OrderUID)
+		}
+		mustEqual(t, want, got)
+	}
+
+	if err := srv.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	// Another replica that never lost the server stores a newer version
+	// before this one retries.
+	other := newRemote(t, srv, cache.RemoteConfig{})
+	newer := changed.Clone()
+	newer.TrackNumber = "NEWER"
+	other.Set(newer)
+	time.Sleep(retryAfter)
+
+	// The next call finds the server again and first drops the orders
+	// changed during the outage, rather than writing back its own copies.
+	if _, ok := c.Get(untouched.OrderUID); !ok {
+		t.Fatal("order written before the outage missing after recovery")
+	}
+	reader := newRemote(t, srv, cache.RemoteConfig{})
+	for _, uid := range []string{changed.OrderUID, during.OrderUID} {
+		if got, ok := reader.Get(uid); ok {
+			t.Errorf("order %s still cached after recovery with track number %s", uid, got.TrackNumber)
+		}
+	}
+	if keys := srv.Keys(); !slices.Equal(keys, []string{testPrefix + untouched.OrderUID}) {
+		t.Errorf("server keys after recovery = %v, want only the untouched order", keys)
+	}
+}
+
+func TestRemoteCacheBoundsMirror(t *testing.T) {
+	const size = 3
+
+	srv := startServer(t)
+	c := newRemote(t, srv, cache.RemoteConfig{LocalSize: size})
+
+	gen := storagetest.NewOrders()
+	var uids []string
+	for range 2 * size {
+		order := gen.Next()
+		c.Set(order)
+		uids = append(uids, order.OrderUID)
+	}
+
+	if n := c.Len(); n != size {
+		t.Fatalf("mirror holds %d orders, want %d", n, size)
+	}
+	var mirrored []string
+	c.Range(func(order *model.Order) bool {
+		mirrored = append(mirrored, order.OrderUID)
+		return true
+	})
+	slices.Sort(mirrored)
+	want := slices.Clone(uids[size:])
+	slices.Sort(want)
+	if !slices.Equal(mirrored, want) {
+		t.Errorf("mirror holds %v, want the latest %v", mirrored, want)
+	}
+
+	// The server keeps everything.
+	if keys := srv.Keys(); len(keys) != 2*size {
+		t.Errorf("server holds %d keys, want %d", len(keys), 2*size)
+	}
+	if _, ok := c.Get(uids[0]); !ok {
+		t.Errorf("evicted order %s not read from the server", uids[0])
+	}
+}
@@ -0,0 +1,208 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+type Config struct {
+	Addr        string
+	Password    string
+	DB          int
+	PoolSize    int
+	DialTimeout time.Duration
+	// Timeout bounds each round trip when the context has no deadline.
+	Timeout time.Duration
+}
+
+// Client keeps a bounded pool of connections. A connection that fails in
+// any way is discarded rather than reused.
+type Client struct {
+	cfg    Config
+	idle   chan *conn
+	tokens chan struct{}
+	closed atomic.Bool
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+var ErrClosed = stdErrors.New("resp: client is closed")
+
+func NewClient(cfg Config) *Client {
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 10
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Second
+	}
+	return &Client{
+		cfg:    cfg,
+		idle:   make(chan *conn, cfg.PoolSize),
+		tokens: make(chan struct{}, cfg.PoolSize),
+	}
+}
+
+// Do sends one command. An error reply is returned as an Error.
+func (c *Client) Do(ctx context.Context, args ...string) (Reply, error) {
+	replies, err := c.Pipeline(ctx, [][]string{args})
+	if err != nil {
+		return Reply{}, err
+	}
+	return replies[0], replies[0].Err
+}
+
+// Pipeline sends all commands before reading any reply, so the batch costs
+// one round trip. Error replies are left in the matching Reply.Err.
+func (c *Client) Pipeline(ctx context.Context, cmds [][]string) ([]Reply, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := cn.roundTrip(ctx, c.cfg.Timeout, cmds)
+	if err != nil {
+		c.discard(cn)
+		return nil, err
+	}
+	c.put(cn)
+	return replies, nil
+}
+
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	if c.closed.Load() {
+		return nil, ErrClosed
+	}
+
+	select {
+	case c.tokens <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+
+	cn, err := c.dial(ctx)
+	if err != nil {
+		<-c.tokens
+		return nil, err
+	}
+	return cn, nil
+}
+
+func (c *Client) put(cn *conn) {
+	if c.closed.Load() {
+		c.discard(cn)
+		return
+	}
+
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+	<-c.tokens
+}
+
+func (c *Client) discard(cn *conn) {
+	cn.Close()
+	<-c.tokens
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	dialer := net.Dialer{Timeout: c.cfg.DialTimeout}
+	nc, err := dialer.DialContext(ctx, "tcp", c.cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+
+	var setup [][]string
+	if c.cfg.Password != "" {
+		setup = append(setup, []string{"AUTH", c.cfg.Password})
+	}
+	if c.cfg.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.cfg.DB)})
+	}
+	if len(setup) == 0 {
+		return cn, nil
+	}
+
+	replies, err := cn.roundTrip(ctx, c.cfg.Timeout, setup)
+	if err == nil {
+		for _, reply := range replies {
+			if reply.Err != nil {
+				err = fmt.Errorf("resp: connection setup: %w", reply.Err)
+				break
+			}
+		}
+	}
+	if err != nil {
+		cn.Close()
+		return nil, err
+	}
+	return cn, nil
+}
+
+func (cn *conn) roundTrip(ctx context.Context, timeout time.Duration, cmds [][]string) ([]Reply, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(timeout)
+	}
+	if err := cn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	for _, args := range cmds {
+		if err := WriteCommand(cn.w, args...); err != nil {
+			return nil, err
+		}
+	}
+	if err := cn.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	replies := make([]Reply, len(cmds))
+	for i := range replies {
+		reply, err := ReadReply(cn.r)
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+// Close closes the idle connections. Connections in use are closed when
+// they are returned.
+func (c *Client) Close() error {
+	c.closed.Store(true)
+
+	var errs []error
+	for {
+		select {
+		case cn := <-c.idle:
+			if err := cn.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			return stdErrors.Join(errs...)
+		}
+	}
+}
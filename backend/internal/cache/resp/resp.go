@@ -0,0 +1,112 @@
+// Package resp is a minimal client for servers speaking the Redis
+// serialization protocol, such as Redis, Valkey, KeyDB or Dragonfly.
+package resp
+
+import (
+	"bufio"
+	stdErrors "errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Error is an error reply sent by the server.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// Reply is one decoded server reply. Nil is set for null bulk strings and
+// arrays; Err holds an error reply.
+type Reply struct {
+	Str   []byte
+	Int   int64
+	Array []Reply
+	Nil   bool
+	Err   error
+}
+
+var errProtocol = stdErrors.New("resp: protocol error")
+
+// WriteCommand encodes args as an array of bulk strings.
+func WriteCommand(w *bufio.Writer, args ...string) error {
+	if _, err := fmt.Fprintf(w, "*%d\r\n", len(args)); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if _, err := fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadReply decodes the next reply. Commands sent to a server are arrays of
+// bulk strings, so servers use it to read requests as well.
+func ReadReply(r *bufio.Reader) (Reply, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return Reply{}, err
+	}
+	if len(line) == 0 {
+		return Reply{}, errProtocol
+	}
+
+	switch line[0] {
+	case '+':
+		return Reply{Str: line[1:]}, nil
+	case '-':
+		return Reply{Err: Error(line[1:])}, nil
+	case ':':
+		n, err := strconv.ParseInt(string(line[1:]), 10, 64)
+		if err != nil {
+			return Reply{}, fmt.Errorf("%w: %v", errProtocol, err)
+		}
+		return Reply{Int: n}, nil
+	case '$':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return Reply{}, fmt.Errorf("%w: %v", errProtocol, err)
+		}
+		if n < 0 {
+			return Reply{Nil: true}, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return Reply{}, err
+		}
+		return Reply{Str: buf[:n]}, nil
+	case '*':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return Reply{}, fmt.Errorf("%w: %v", errProtocol, err)
+		}
+		if n < 0 {
+			return Reply{Nil: true}, nil
+		}
+		array := make([]Reply, n)
+		for i := range array {
+			if array[i], err = ReadReply(r); err != nil {
+				return Reply{}, err
+			}
+		}
+		return Reply{Array: array}, nil
+	default:
+		return Reply{}, fmt.Errorf("%w: unexpected type %q", errProtocol, line[0])
+	}
+}
+
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		if stdErrors.Is(err, bufio.ErrBufferFull) {
+			return nil, errProtocol
+		}
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, errProtocol
+	}
+	return append([]byte(nil), line[:len(line)-2]...), nil
+}
@@ -0,0 +1,387 @@
+// Package resptest provides an in-process server speaking the Redis
+// protocol that stands in for a remote cache in tests. It implements the
+// string commands the RESP cache uses, with key expiry driven by a clock
+// that tests can move forward.
+package resptest
+
+import (
+	"L0-wbtech/internal/cache/resp"
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Server struct {
+	listener net.Listener
+	password string
+
+	mu      sync.Mutex
+	data    map[string]entry
+	offset  time.Duration
+	conns   map[net.Conn]struct{}
+	closed  bool
+	wg      sync.WaitGroup
+	lookups int
+}
+
+type entry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// Start listens on a random loopback port. A non-empty password makes
+// clients AUTH before any other command.
+func Start(password string) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("resptest.Start: %w", err)
+	}
+
+	s := &Server{
+		listener: listener,
+		password: password,
+		data:     make(map[string]entry),
+		conns:    make(map[net.Conn]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// FastForward moves the expiry clock.
+func (s *Server) FastForward(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset += d
+}
+
+// Keys returns the live keys.
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.data))
+	for key := range s.data {
+		if _, ok := s.lookup(key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// TTL returns the remaining lifetime of key, zero when it has none and -1
+// when it does not exist.
+func (s *Server) TTL(key string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.lookup(key)
+	switch {
+	case !ok:
+		return -1
+	case e.expiresAt.IsZero():
+		return 0
+	default:
+		return e.expiresAt.Sub(s.now())
+	}
+}
+
+// Lookups counts the GET and MGET commands served, which shows whether
+// reads were batched.
+func (s *Server) Lookups() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lookups
+}
+
+// Close stops the server and drops every client connection, which is how
+// tests simulate the cache going down.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+// Restart listens again on the address of a closed server, keeping its
+// data, which is how tests bring the cache back.
+func (s *Server) Restart() error {
+	listener, err := net.Listen("tcp", s.Addr())
+	if err != nil {
+		return fmt.Errorf("resptest.Restart: %w", err)
+	}
+
+	s.mu.Lock()
+	s.listener = listener
+	s.closed = false
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.accept()
+	return nil
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return
+		}
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serve(c)
+	}
+}
+
+func (s *Server) serve(c net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.Close()
+	}()
+
+	r := bufio.NewReader(c)
+	w := bufio.NewWriter(c)
+	authed := s.password == ""
+
+	for {
+		req, err := resp.ReadReply(r)
+		if err != nil {
+			return
+		}
+		args := make([]string, len(req.Array))
+		for i, arg := range req.Array {
+			args[i] = string(arg.Str)
+		}
+		if len(args) == 0 {
+			writeError(w, "ERR empty command")
+		} else {
+			authed = s.exec(w, args, authed)
+		}
+
+		// Pipelined commands are answered together.
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) exec(w *bufio.Writer, args []string, authed bool) bool {
+	cmd := strings.ToUpper(args[0])
+
+	if cmd == "AUTH" {
+		if len(args) == 2 && args[1] == s.password {
+			writeSimple(w, "OK")
+			return true
+		}
+		writeError(w, "WRONGPASS invalid password")
+		return authed
+	}
+	if !authed {
+		writeError(w, "NOAUTH Authentication required.")
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch cmd {
+	case "PING":
+		writeSimple(w, "PONG")
+	case "SELECT":
+		writeSimple(w, "OK")
+	case "GET":
+		if len(args) != 2 {
+			writeArity(w, cmd)
+			break
+		}
+		s.lookups++
+		if e, ok := s.lookup(args[1]); ok {
+			writeBulk(w, e.value)
+		} else {
+			writeNil(w)
+		}
+	case "MGET":
+		if len(args) < 2 {
+			writeArity(w, cmd)
+			break
+		}
+		s.lookups++
+		fmt.Fprintf(w, "*%d\r\n", len(args)-1)
+		for _, key := range args[1:] {
+			if e, ok := s.lookup(key); ok {
+				writeBulk(w, e.value)
+			} else {
+				writeNil(w)
+			}
+		}
+	case "SET":
+		s.set(w, args)
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := s.lookup(key); ok {
+				deleted++
+			}
+			delete(s.data, key)
+		}
+		writeInt(w, deleted)
+	case "EXISTS":
+		n := 0
+		for _, key := range args[1:] {
+			if _, ok := s.lookup(key); ok {
+				n++
+			}
+		}
+		writeInt(w, n)
+	case "DBSIZE":
+		n := 0
+		for key := range s.data {
+			if _, ok := s.lookup(key); ok {
+				n++
+			}
+		}
+		writeInt(w, n)
+	case "SCAN":
+		s.scan(w, args)
+	case "FLUSHDB":
+		s.data = make(map[string]entry)
+		writeSimple(w, "OK")
+	default:
+		writeError(w, fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+	return true
+}
+
+// set handles SET key value [EX seconds | PX milliseconds].
+func (s *Server) set(w *bufio.Writer, args []string) {
+	if len(args) != 3 && len(args) != 5 {
+		writeArity(w, "SET")
+		return
+	}
+
+	e := entry{value: args[2]}
+	if len(args) == 5 {
+		n, err := strconv.ParseInt(args[4], 10, 64)
+		if err != nil || n <= 0 {
+			writeError(w, "ERR invalid expire time in 'set' command")
+			return
+		}
+		switch strings.ToUpper(args[3]) {
+		case "EX":
+			e.expiresAt = s.now().Add(time.Duration(n) * time.Second)
+		case "PX":
+			e.expiresAt = s.now().Add(time.Duration(n) * time.Millisecond)
+		default:
+			writeError(w, "ERR syntax error")
+			return
+		}
+	}
+
+	s.data[args[1]] = e
+	writeSimple(w, "OK")
+}
+
+// scan handles SCAN cursor [MATCH pattern] [COUNT n], returning every
+// match in one page. Patterns support a trailing * only.
+func (s *Server) scan(w *bufio.Writer, args []string) {
+	if len(args) < 2 || len(args)%2 != 0 {
+		writeArity(w, "SCAN")
+		return
+	}
+
+	match := "*"
+	for i := 2; i < len(args); i += 2 {
+		if strings.EqualFold(args[i], "MATCH") {
+			match = args[i+1]
+		}
+	}
+	prefix, wildcard := strings.CutSuffix(match, "*")
+
+	var keys []string
+	for key := range s.data {
+		if _, ok := s.lookup(key); !ok {
+			continue
+		}
+		if key == match || wildcard && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	w.WriteString("*2\r\n")
+	writeBulk(w, "0")
+	fmt.Fprintf(w, "*%d\r\n", len(keys))
+	for _, key := range keys {
+		writeBulk(w, key)
+	}
+}
+
+func (s *Server) now() time.Time {
+	return time.Now().Add(s.offset)
+}
+
+func (s *Server) lookup(key string) (entry, bool) {
+	e, ok := s.data[key]
+	if !ok {
+		return entry{}, false
+	}
+	if !e.expiresAt.IsZero() && !s.now().Before(e.expiresAt) {
+		delete(s.data, key)
+		return entry{}, false
+	}
+	return e, true
+}
+
+func writeSimple(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "+%s\r\n", s)
+}
+
+func writeError(w *bufio.Writer, msg string) {
+	fmt.Fprintf(w, "-%s\r\n", msg)
+}
+
+func writeArity(w *bufio.Writer, cmd string) {
+	writeError(w, fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd)))
+}
+
+func writeInt(w *bufio.Writer, n int) {
+	fmt.Fprintf(w, ":%d\r\n", n)
+}
+
+func writeBulk(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
+}
+
+func writeNil(w *bufio.Writer) {
+	w.WriteString("$-1\r\n")
+}
@@ -0,0 +1,277 @@
+package cache
+
+import (
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage"
+	"L0-wbtech/pkg/logger/sl"
+	"bufio"
+	"context"
+	"encoding/binary"
+	stdErrors "errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// A snapshot file holds, with integers in big endian:
+//
+//	magic    "L0CS"
+//	version  uint16
+//	mark     int64, the storage high-water mark in Unix nanoseconds
+//	count    uint32
+//	orders   count times a uint32 length and a msgpack encoded order
+//	checksum uint32, CRC-32C of everything before it
+const (
+	snapshotMagic   = "L0CS"
+	snapshotVersion = 1
+	snapshotHeader  = len(snapshotMagic) + 2 + 8 + 4
+)
+
+var (
+	ErrNoSnapshot      = stdErrors.New("cache: no snapshot")
+	ErrCorruptSnapshot = stdErrors.New("cache: corrupt snapshot")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// WriteSnapshot replaces the snapshot at path atomically: a crash leaves
+// either the old file or the new one.
+func WriteSnapshot(path string, mark time.Time, orders []*model.Order) error {
+	const op = "cache.WriteSnapshot"
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	sum := crc32.New(castagnoli)
+	w := bufio.NewWriter(io.MultiWriter(f, sum))
+
+	header := make([]byte, 0, snapshotHeader)
+	header = append(header, snapshotMagic...)
+	header = binary.BigEndian.AppendUint16(header, snapshotVersion)
+	header = binary.BigEndian.AppendUint64(header, uint64(mark.UnixNano()))
+	header = binary.BigEndian.AppendUint32(header, uint32(len(orders)))
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var size [4]byte
+	for _, order := range orders {
+		data, err := encodeOrder(order)
+		if err != nil {
+			return fmt.Errorf("%s: encode order %s: %w", op, order.OrderUID, err)
+		}
+		binary.BigEndian.PutUint32(size[:], uint32(len(data)))
+		if _, err := w.Write(size[:]); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := f.Write(sum.Sum(nil)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ReadSnapshot returns ErrNoSnapshot when there is no file at path and
+// ErrCorruptSnapshot when it fails the checksum or cannot be decoded.
+func ReadSnapshot(path string) (time.Time, []*model.Order, error) {
+	const op = "cache.ReadSnapshot"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if stdErrors.Is(err, fs.ErrNotExist) {
+			return time.Time{}, nil, ErrNoSnapshot
+		}
+		return time.Time{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	corrupt := func(reason string) (time.Time, []*model.Order, error) {
+		return time.Time{}, nil, fmt.Errorf("%s: %w: %s", op, ErrCorruptSnapshot, reason)
+	}
+
+	if len(data) < snapshotHeader+crc32.Size {
+		return corrupt("truncated")
+	}
+	body, sum := data[:len(data)-crc32.Size], data[len(data)-crc32.Size:]
+	if crc32.Checksum(body, castagnoli) != binary.BigEndian.Uint32(sum) {
+		return corrupt("checksum mismatch")
+	}
+	if string(body[:len(snapshotMagic)]) != snapshotMagic {
+		return corrupt("not a cache snapshot")
+	}
+	body = body[len(snapshotMagic):]
+	if version := binary.BigEndian.Uint16(body); version != snapshotVersion {
+		return corrupt(fmt.Sprintf("unsupported version %d", version))
+	}
+	mark := time.Unix(0, int64(binary.BigEndian.Uint64(body[2:]))).UTC()
+	count := binary.BigEndian.Uint32(body[10:])
+	body = body[14:]
+
+	orders := make([]*model.Order, 0, min(int(count), len(body)/4))
+	for range count {
+		if len(body) < 4 {
+			return corrupt("truncated order")
+		}
+		size := binary.BigEndian.Uint32(body)
+		body = body[4:]
+		if uint32(len(body)) < size {
+			return corrupt("truncated order")
+		}
+		order, err := decodeOrder(body[:size])
+		if err != nil {
+			return corrupt(err.Error())
+		}
+		orders = append(orders, order)
+		body = body[size:]
+	}
+	if len(body) != 0 {
+		return corrupt("trailing data")
+	}
+
+	return mark, orders, nil
+}
+
+type SnapshotConfig struct {
+	Path     string
+	Interval time.Duration
+	// Overlap is subtracted from the high-water mark when catching up, to
+	// cover writes that committed around the time of the snapshot.
+	Overlap time.Duration
+}
+
+// Snapshotter periodically saves the cache to a file and restores it from
+// there at startup, fetching from storage only what changed since.
+type Snapshotter struct {
+	cache   Cache
+	tracker storage.ChangeTracker
+	cfg     SnapshotConfig
+	log     *slog.Logger
+	mu      sync.Mutex
+}
+
+func NewSnapshotter(cache Cache, tracker storage.ChangeTracker, cfg SnapshotConfig, log *slog.Logger) *Snapshotter {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	if cfg.Overlap <= 0 {
+		cfg.Overlap = time.Minute
+	}
+	return &Snapshotter{
+		cache:   cache,
+		tracker: tracker,
+		cfg:     cfg,
+		log:     log,
+	}
+}
+
+// Restore loads the snapshot into the cache and catches up from storage.
+// On error the cache must be rebuilt from storage instead.
+func (s *Snapshotter) Restore(ctx context.Context) error {
+	const op = "cache.Snapshotter.Restore"
+	log := s.log.With(slog.String("op", op))
+
+	start := time.Now()
+	mark, orders, err := ReadSnapshot(s.cfg.Path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	changed, err := s.tracker.OrdersChangedSince(ctx, mark.Add(-s.cfg.Overlap))
+	if err != nil {
+		return fmt.Errorf("%s: catch up: %w", op, err)
+	}
+
+	for _, order := range orders {
+		s.cache.Set(order)
+	}
+	for _, order := range changed {
+		s.cache.Set(order)
+	}
+
+	log.Info("Cache restored from snapshot",
+		"path", s.cfg.Path,
+		"high_water_mark", mark,
+		"snapshot_orders", len(orders),
+		"changed_orders", len(changed),
+		"duration", time.Since(start))
+	return nil
+}
+
+// Save writes the cache to the snapshot file. The high-water mark is read
+// before the cache, so a write racing with the snapshot is caught up on the
+// next restore rather than lost.
+func (s *Snapshotter) Save(ctx context.Context) error {
+	const op = "cache.Snapshotter.Save"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mark, err := s.tracker.HighWaterMark(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var orders []*model.Order
+	s.cache.Range(func(order *model.Order) bool {
+		orders = append(orders, order)
+		return true
+	})
+
+	if err := WriteSnapshot(s.cfg.Path, mark, orders); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Debug("Cache snapshot saved", "orders", len(orders), "high_water_mark", mark)
+	return nil
+}
+
+func (s *Snapshotter) Run(ctx context.Context) {
+	const op = "cache.Snapshotter.Run"
+	log := s.log.With(slog.String("op", op))
+
+	log.Info("Starting cache snapshots", "path", s.cfg.Path, "interval", s.cfg.Interval)
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Save(ctx); err != nil && ctx.Err() == nil {
+				log.Error("Failed to save cache snapshot", sl.Err(err))
+			}
+		}
+	}
+}
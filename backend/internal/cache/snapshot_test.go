@@ -0,0 +1,240 @@
+package cache_test
+
+import (
+	"L0-wbtech/internal/cache"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage/storagetest"
+	"context"
+	"encoding/binary"
+	stdErrors "errors"
+	"hash/crc32"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Offsets into the snapshot layout documented in snapshot.go.
+const (
+	versionAt = 4
+	countAt   = 14
+	ordersAt  = 18
+)
+
+var snapshotMark = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func writeSnapshot(t *testing.T, orders ...*model.Order) (string, []byte) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	if err := cache.WriteSnapshot(path, snapshotMark, orders); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, data
+}
+
+// reseal replaces the checksum of a snapshot whose body was edited, so the
+// edit reaches the decoder.
+func reseal(data []byte) []byte {
+	body := data[:len(data)-crc32.Size]
+	return binary.BigEndian.AppendUint32(body, crc32.Checksum(body, crc32.MakeTable(crc32.Castagnoli)))
+}
+
+func body(data []byte) []byte {
+	return append([]byte(nil), data[:len(data)-crc32.Size]...)
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	gen := storagetest.NewOrders()
+	want := []*model.Order{gen.Next(), gen.Next(), gen.Next()}
+	path, _ := writeSnapshot(t, want...)
+
+	mark, got, err := cache.ReadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mark.Equal(snapshotMark) {
+		t.Errorf("mark %v, want %v", mark, snapshotMark)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("read %d orders, want %d", len(got), len(want))
+	}
+	for i := range want {
+		mustEqual(t, want[i], got[i])
+	}
+}
+
+func TestSnapshotRejectsDamage(t *testing.T) {
+	gen := storagetest.NewOrders()
+	_, valid := writeSnapshot(t, gen.Next(), gen.Next())
+	orderSize := int(binary.BigEndian.Uint32(valid[ordersAt:]))
+
+	for _, tc := range []struct {
+		name   string
+		damage func(data []byte) []byte
+		reason string
+	}{
+		{"empty file", func([]byte) []byte { return nil }, "truncated"},
+		{"cut inside the header", func(d []byte) []byte { return d[:ordersAt-2] }, "truncated"},
+		{"cut inside an order", func(d []byte) []byte { return d[:ordersAt+orderSize/2] }, "checksum mismatch"},
+		{"flipped bit", func(d []byte) []byte {
+			d[ordersAt+4] ^= 0x01
+			return d
+		}, "checksum mismatch"},
+		{"wrong checksum", func(d []byte) []byte {
+			d[len(d)-1]++
+			return d
+		}, "checksum mismatch"},
+		{"wrong magic", func(d []byte) []byte {
+			copy(d, "XXXX")
+			return reseal(d)
+		}, "not a cache snapshot"},
+		{"newer version", func(d []byte) []byte {
+			binary.BigEndian.PutUint16(d[versionAt:], 2)
+			return reseal(d)
+		}, "unsupported version 2"},
+		{"last order cut, checksum intact", func(d []byte) []byte {
+			b := body(d)
+			return reseal(append(b[:len(b)-3], make([]byte, crc32.Size)...))
+		}, "truncated order"},
+		{"count larger than the orders", func(d []byte) []byte {
+			binary.BigEndian.PutUint32(d[countAt:], 3)
+			return reseal(d)
+		}, "truncated order"},
+		{"trailing data", func(d []byte) []byte {
+			b := append(body(d), 0xde, 0xad)
+			return reseal(append(b, make([]byte, crc32.Size)...))
+		}, "trailing data"},
+		{"undecodable order", func(d []byte) []byte {
+			for i := range orderSize {
+				d[ordersAt+4+i] = 0xc1
+			}
+			return reseal(d)
+		}, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cache.snapshot")
+			data := tc.damage(append([]byte(nil), valid...))
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, orders, err := cache.ReadSnapshot(path)
+			if !stdErrors.Is(err, cache.ErrCorruptSnapshot) {
+				t.Fatalf("ReadSnapshot returned %v, want %v", err, cache.ErrCorruptSnapshot)
+			}
+			if !strings.Contains(err.Error(), tc.reason) {
+				t.Errorf("error %q does not say %q", err, tc.reason)
+			}
+			if orders != nil {
+				t.Errorf("ReadSnapshot returned %d orders with the error", len(orders))
+			}
+		})
+	}
+}
+
+func TestSnapshotMissing(t *testing.T) {
+	_, _, err := cache.ReadSnapshot(filepath.Join(t.TempDir(), "missing"))
+	if !stdErrors.Is(err, cache.ErrNoSnapshot) {
+		t.Errorf("ReadSnapshot returned %v, want %v", err, cache.ErrNoSnapshot)
+	}
+}
+
+// tracker reports the orders of changed as written after since, unless
+// since is later than at.
+type tracker struct {
+	at      time.Time
+	changed map[string]*model.Order
+	since   time.Time
+}
+
+func (f *tracker) HighWaterMark(context.Context) (time.Time, error) {
+	return f.at, nil
+}
+
+func (f *tracker) OrdersChangedSince(_ context.Context, since time.Time) (map[string]*model.Order, error) {
+	f.since = since
+	if since.After(f.at) {
+		return nil, nil
+	}
+	return f.changed, nil
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	const overlap = time.Minute
+
+	gen := storagetest.NewOrders()
+	kept, stale := gen.Next(), gen.Next()
+	_, valid := writeSnapshot(t, kept, stale)
+
+	// The order changed after the snapshot was taken, and another one was
+	// created.
+	updated := stale.Clone()
+	updated.TrackNumber = "UPDATED"
+	created := gen.Next()
+
+	for _, tc := range []struct {
+		name    string
+		file    []byte
+		wantErr error
+		want    []*model.Order
+	}{
+		{"catches up a stale snapshot", valid, nil, []*model.Order{kept, updated, created}},
+		{"missing file", nil, cache.ErrNoSnapshot, nil},
+		{"corrupt file", valid[:len(valid)-1], cache.ErrCorruptSnapshot, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			snapshot := filepath.Join(dir, "cache.snapshot")
+			if tc.file != nil {
+				if err := os.WriteFile(snapshot, tc.file, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c := cache.NewCache()
+			tr := &tracker{
+				at:      snapshotMark.Add(time.Hour),
+				changed: map[string]*model.Order{updated.OrderUID: updated, created.OrderUID: created},
+			}
+			s := cache.NewSnapshotter(c, tr, cache.SnapshotConfig{Path: snapshot, Overlap: overlap}, slog.New(slog.DiscardHandler))
+
+			err := s.Restore(context.Background())
+			if tc.wantErr != nil {
+				// The caller falls back to a full load, so nothing may have
+				// been half restored.
+				if !stdErrors.Is(err, tc.wantErr) {
+					t.Fatalf("Restore returned %v, want %v", err, tc.wantErr)
+				}
+				if n := c.Len(); n != 0 {
+					t.Errorf("cache holds %d orders after a failed restore", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := snapshotMark.Add(-overlap); !tr.since.Equal(want) {
+				t.Errorf("caught up since %v, want the mark minus the overlap %v", tr.since, want)
+			}
+			if n := c.Len(); n != len(tc.want) {
+				t.Errorf("cache holds %d orders, want %d", n, len(tc.want))
+			}
+			for _, want := range tc.want {
+				got, ok := c.Get(want.OrderUID)
+				if !ok {
+					t.Errorf("order %s not restored", want.OrderUID)
+					continue
+				}
+				mustEqual(t, want, got)
+			}
+		})
+	}
+}
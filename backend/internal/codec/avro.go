@@ -0,0 +1,66 @@
+package codec
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hamba/avro/v2"
+)
+
+type avroDecoder struct {
+	schemas   SchemaSource
+	defaultID int
+	parsed    compiledCache[avro.Schema]
+}
+
+func NewAvroDecoder(schemas SchemaSource, defaultSchemaID int) Decoder {
+	return &avroDecoder{
+		schemas:   schemas,
+		defaultID: defaultSchemaID,
+	}
+}
+
+func (d *avroDecoder) Decode(ctx context.Context, _ string, data []byte, v any) error {
+	const op = "codec.avroDecoder.Decode"
+
+	id, payload, _, err := resolveSchemaID(data, d.defaultID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	schema, err := d.schema(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var tree any
+	if err := avro.Unmarshal(schema, payload, &tree); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := remap(tree, v); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (d *avroDecoder) schema(ctx context.Context, id int) (avro.Schema, error) {
+	if schema, ok := d.parsed.get(id); ok {
+		return schema, nil
+	}
+
+	raw, err := d.schemas.Schema(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if raw.Type != SchemaAvro {
+		return nil, fmt.Errorf("schema %d is %s, not AVRO", id, raw.Type)
+	}
+
+	schema, err := avro.Parse(raw.Text)
+	if err != nil {
+		return nil, fmt.Errorf("parse schema %d: %w", id, err)
+	}
+	d.parsed.put(id, schema)
+	return schema, nil
+}
@@ -0,0 +1,169 @@
+package codec
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatAuto     Format = "auto"
+	FormatJSON     Format = "json"
+	FormatProtobuf Format = "protobuf"
+	FormatAvro     Format = "avro"
+)
+
+var (
+	ErrUnknownFormat      = stdErrors.New("unknown message format")
+	ErrUnsupportedContent = stdErrors.New("unsupported content type")
+)
+
+// Decoder turns a raw message payload into v. contentType is the value of
+// the message Content-Type header and may be empty.
+type Decoder interface {
+	Decode(ctx context.Context, contentType string, data []byte, v any) error
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return FormatJSON, nil
+	case FormatAuto, FormatJSON, FormatProtobuf, FormatAvro:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownFormat, s)
+	}
+}
+
+// FormatFromContentType maps a Content-Type header onto a Format.
+func FormatFromContentType(contentType string) (Format, bool) {
+	if contentType == "" {
+		return "", false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	switch mediaType {
+	case "application/json", "text/json":
+		return FormatJSON, true
+	case "application/protobuf", "application/x-protobuf", "application/vnd.google.protobuf":
+		return FormatProtobuf, true
+	case "application/avro", "avro/binary", "application/vnd.apache.avro+binary":
+		return FormatAvro, true
+	default:
+		return "", false
+	}
+}
+
+// NewDecoder builds the decoder for format. With a defaultSchemaID, Protobuf
+// and Avro payloads are plain encodings of that schema; without one they
+// must be framed in the schema registry wire format.
+func NewDecoder(format Format, schemas SchemaSource, defaultSchemaID int) (Decoder, error) {
+	const op = "codec.NewDecoder"
+
+	switch format {
+	case FormatJSON:
+		return NewJSONDecoder(), nil
+	case FormatProtobuf:
+		if schemas == nil {
+			return nil, fmt.Errorf("%s: protobuf requires a schema source", op)
+		}
+		return NewProtobufDecoder(schemas, defaultSchemaID), nil
+	case FormatAvro:
+		if schemas == nil {
+			return nil, fmt.Errorf("%s: avro requires a schema source", op)
+		}
+		return NewAvroDecoder(schemas, defaultSchemaID), nil
+	case FormatAuto:
+		return newAutoDecoder(schemas, defaultSchemaID), nil
+	default:
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrUnknownFormat, format)
+	}
+}
+
+// autoDecoder picks the format from the Content-Type header. Without a
+// header, the default schema decides when one is configured; otherwise
+// registry framed payloads are resolved through the schema source and
+// anything else is treated as JSON.
+type autoDecoder struct {
+	json      Decoder
+	protobuf  Decoder
+	avro      Decoder
+	schemas   SchemaSource
+	defaultID int
+}
+
+func newAutoDecoder(schemas SchemaSource, defaultSchemaID int) *autoDecoder {
+	d := &autoDecoder{
+		json:      NewJSONDecoder(),
+		schemas:   schemas,
+		defaultID: defaultSchemaID,
+	}
+	if schemas != nil {
+		d.protobuf = NewProtobufDecoder(schemas, defaultSchemaID)
+		d.avro = NewAvroDecoder(schemas, defaultSchemaID)
+	}
+	return d
+}
+
+func (d *autoDecoder) Decode(ctx context.Context, contentType string, data []byte, v any) error {
+	const op = "codec.autoDecoder.Decode"
+
+	format, ok := FormatFromContentType(contentType)
+	if !ok {
+		if contentType != "" {
+			return fmt.Errorf("%s: %w: %q", op, ErrUnsupportedContent, contentType)
+		}
+
+		var err error
+		if format, err = d.sniff(ctx, data); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	var dec Decoder
+	switch format {
+	case FormatJSON:
+		dec = d.json
+	case FormatProtobuf:
+		dec = d.protobuf
+	case FormatAvro:
+		dec = d.avro
+	}
+	if dec == nil {
+		return fmt.Errorf("%s: %w: no schema source configured for %s", op, ErrUnsupportedContent, format)
+	}
+
+	return dec.Decode(ctx, contentType, data, v)
+}
+
+// sniff picks the format of a payload that came without a Content-Type.
+func (d *autoDecoder) sniff(ctx context.Context, data []byte) (Format, error) {
+	if d.schemas == nil {
+		return FormatJSON, nil
+	}
+
+	id := d.defaultID
+	if id <= 0 {
+		if !IsFramed(data) {
+			return FormatJSON, nil
+		}
+		var err error
+		if id, _, err = ParseFrame(data); err != nil {
+			return "", err
+		}
+	}
+
+	schema, err := d.schemas.Schema(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return schema.Type.Format(), nil
+}
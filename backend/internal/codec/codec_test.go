@@ -0,0 +1,315 @@
+package codec
+
+import (
+	"L0-wbtech/internal/model"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/hamba/avro/v2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	avroSchemaID  = 1
+	protoSchemaID = 2
+	schemasDir    = "../../schemas"
+)
+
+func loadOrder(t *testing.T) ([]byte, model.Order) {
+	t.Helper()
+
+	data, err := os.ReadFile("../../order.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order model.Order
+	if err := json.Unmarshal(data, &order); err != nil {
+		t.Fatal(err)
+	}
+	return data, order
+}
+
+func assertOrder(t *testing.T, got, want model.Order) {
+	t.Helper()
+
+	if !got.DateCreated.Equal(want.DateCreated) {
+		t.Errorf("date_created = %v, want %v", got.DateCreated, want.DateCreated)
+	}
+	got.DateCreated = want.DateCreated
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded order:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestJSONDecoder(t *testing.T) {
+	data, want := loadOrder(t)
+
+	var got model.Order
+	if err := NewJSONDecoder().Decode(context.Background(), "application/json", data, &got); err != nil {
+		t.Fatal(err)
+	}
+	assertOrder(t, got, want)
+}
+
+func TestProtobufDecoder(t *testing.T) {
+	ctx := context.Background()
+	data, order := loadOrder(t)
+
+	schemas, err := NewDirSource(schemasDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := schemas.Schema(ctx, protoSchemaID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := CompileProto(ctx, "order.proto", schema.Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orderPayload := protoPayload(t, file.Messages().Get(0), data)
+	itemJSON, err := json.Marshal(order.Items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemPayload := protoPayload(t, file.Messages().Get(3), itemJSON)
+
+	t.Run("framed", func(t *testing.T) {
+		var got model.Order
+		framed := Frame(protoSchemaID, append(AppendMessageIndexes(nil, []int{0}), orderPayload...))
+		if err := NewProtobufDecoder(schemas, 0).Decode(ctx, "", framed, &got); err != nil {
+			t.Fatal(err)
+		}
+		assertOrder(t, got, order)
+	})
+
+	t.Run("framed message index", func(t *testing.T) {
+		var got model.Item
+		framed := Frame(protoSchemaID, append(AppendMessageIndexes(nil, []int{3}), itemPayload...))
+		if err := NewProtobufDecoder(schemas, 0).Decode(ctx, "", framed, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, order.Items[0]) {
+			t.Errorf("decoded item:\n got %+v\nwant %+v", got, order.Items[0])
+		}
+	})
+
+	t.Run("unframed", func(t *testing.T) {
+		var got model.Order
+		if err := NewProtobufDecoder(schemas, protoSchemaID).Decode(ctx, "", orderPayload, &got); err != nil {
+			t.Fatal(err)
+		}
+		assertOrder(t, got, order)
+	})
+
+	t.Run("unframed without default schema", func(t *testing.T) {
+		var got model.Order
+		if err := NewProtobufDecoder(schemas, 0).Decode(ctx, "", orderPayload, &got); err == nil {
+			t.Fatal("decoded an unframed payload without a schema")
+		}
+	})
+}
+
+func protoPayload(t *testing.T, desc protoreflect.MessageDescriptor, data []byte) []byte {
+	t.Helper()
+
+	msg := dynamicpb.NewMessage(desc)
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, msg); err != nil {
+		t.Fatal(err)
+	}
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestAvroDecoder(t *testing.T) {
+	ctx := context.Background()
+	_, order := loadOrder(t)
+
+	registry := newRegistryStub(t)
+	schema, err := registry.Schema(ctx, avroSchemaID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := avro.Parse(schema.Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := avroPayload(t, parsed, order)
+
+	t.Run("framed", func(t *testing.T) {
+		var got model.Order
+		if err := NewAvroDecoder(registry, 0).Decode(ctx, "", Frame(avroSchemaID, payload), &got); err != nil {
+			t.Fatal(err)
+		}
+		assertOrder(t, got, order)
+	})
+
+	t.Run("unframed", func(t *testing.T) {
+		var got model.Order
+		if err := NewAvroDecoder(registry, avroSchemaID).Decode(ctx, "", payload, &got); err != nil {
+			t.Fatal(err)
+		}
+		assertOrder(t, got, order)
+	})
+
+	// An empty first string encodes as a zero length, which looks like the
+	// magic byte of a frame.
+	t.Run("unframed leading zero", func(t *testing.T) {
+		want := order
+		want.OrderUID = ""
+		payload := avroPayload(t, parsed, want)
+		if !IsFramed(payload) {
+			t.Fatal("payload does not start like a frame")
+		}
+
+		var got model.Order
+		if err := NewAvroDecoder(registry, avroSchemaID).Decode(ctx, "", payload, &got); err != nil {
+			t.Fatal(err)
+		}
+		assertOrder(t, got, want)
+	})
+}
+
+func TestAutoDecoder(t *testing.T) {
+	ctx := context.Background()
+	data, order := loadOrder(t)
+
+	registry := newRegistryStub(t)
+	schema, err := registry.Schema(ctx, avroSchemaID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := avro.Parse(schema.Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leadingZero := order
+	leadingZero.OrderUID = ""
+
+	tests := []struct {
+		name        string
+		defaultID   int
+		contentType string
+		data        []byte
+		want        model.Order
+	}{
+		{name: "json without header", data: data, want: order},
+		{name: "json header", contentType: "application/json; charset=utf-8", data: data, want: order},
+		{name: "avro header", contentType: "avro/binary", data: Frame(avroSchemaID, avroPayload(t, parsed, order)), want: order},
+		{name: "framed avro", data: Frame(avroSchemaID, avroPayload(t, parsed, order)), want: order},
+		{name: "default schema", defaultID: avroSchemaID, data: avroPayload(t, parsed, leadingZero), want: leadingZero},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec, err := NewDecoder(FormatAuto, registry, tt.defaultID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got model.Order
+			if err := dec.Decode(ctx, tt.contentType, tt.data, &got); err != nil {
+				t.Fatal(err)
+			}
+			assertOrder(t, got, tt.want)
+		})
+	}
+}
+
+// newRegistryStub serves the Avro schema from schemas/ the way a schema
+// registry does.
+func newRegistryStub(t *testing.T) *RegistryClient {
+	t.Helper()
+
+	text, err := os.ReadFile(schemasDir + "/1-order.avsc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /schemas/ids/1", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+		_ = json.NewEncoder(w).Encode(registrySchemaResponse{Schema: string(text)})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return NewRegistryClient(srv.URL, 0)
+}
+
+func avroPayload(t *testing.T, schema avro.Schema, o model.Order) []byte {
+	t.Helper()
+
+	items := make([]any, 0, len(o.Items))
+	for _, item := range o.Items {
+		items = append(items, map[string]any{
+			"chrt_id":      item.ChrtID,
+			"track_number": item.TrackNumber,
+			"price":        item.Price,
+			"rid":          item.Rid,
+			"name":         item.Name,
+			"sale":         item.Sale,
+			"size":         item.Size,
+			"total_price":  item.TotalPrice,
+			"nm_id":        item.NmID,
+			"brand":        item.Brand,
+			"status":       item.Status,
+		})
+	}
+
+	record := map[string]any{
+		"order_uid":    o.OrderUID,
+		"track_number": o.TrackNumber,
+		"entry":        o.Entry,
+		"delivery": map[string]any{
+			"name":    o.Delivery.Name,
+			"phone":   o.Delivery.Phone,
+			"zip":     o.Delivery.Zip,
+			"city":    o.Delivery.City,
+			"address": o.Delivery.Address,
+			"region":  o.Delivery.Region,
+			"email":   o.Delivery.Email,
+		},
+		"payment": map[string]any{
+			"transaction":   o.Payment.Transaction,
+			"request_id":    o.Payment.RequestID,
+			"currency":      o.Payment.Currency,
+			"provider":      o.Payment.Provider,
+			"amount":        o.Payment.Amount,
+			"payment_dt":    o.Payment.PaymentDt,
+			"bank":          o.Payment.Bank,
+			"delivery_cost": o.Payment.DeliveryCost,
+			"goods_total":   o.Payment.GoodsTotal,
+			"custom_fee":    o.Payment.CustomFee,
+		},
+		"items":              items,
+		"locale":             o.Locale,
+		"internal_signature": o.InternalSignature,
+		"customer_id":        o.CustomerID,
+		"delivery_service":   o.DeliveryService,
+		"shardkey":           o.Shardkey,
+		"sm_id":              o.SmID,
+		"date_created":       o.DateCreated,
+		"oof_shard":          o.OofShard,
+	}
+
+	payload, err := avro.Marshal(schema, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
@@ -0,0 +1,75 @@
+package codec
+
+import (
+	"L0-wbtech/pkg/errors"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DirSource serves schemas from a local directory so decoding keeps working
+// without a registry. Files are named "<id>-<name>.<ext>", where the
+// extension selects the schema type: .avsc, .proto or .json.
+type DirSource struct {
+	schemas map[int]Schema
+}
+
+func NewDirSource(dir string) (*DirSource, error) {
+	const op = "codec.NewDirSource"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	src := &DirSource{schemas: make(map[int]Schema)}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+		var schemaType SchemaType
+		switch filepath.Ext(name) {
+		case ".avsc":
+			schemaType = SchemaAvro
+		case ".proto":
+			schemaType = SchemaProtobuf
+		case ".json":
+			schemaType = SchemaJSON
+		default:
+			continue
+		}
+
+		prefix, _, found := strings.Cut(name, "-")
+		if !found {
+			continue
+		}
+		id, err := strconv.Atoi(prefix)
+		if err != nil || id <= 0 {
+			continue
+		}
+		if prev, ok := src.schemas[id]; ok {
+			return nil, fmt.Errorf("%s: duplicate schema id %d (%s type %s)", op, id, name, prev.Type)
+		}
+
+		text, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		src.schemas[id] = Schema{ID: id, Type: schemaType, Text: string(text)}
+	}
+
+	return src, nil
+}
+
+func (s *DirSource) Schema(_ context.Context, id int) (Schema, error) {
+	schema, ok := s.schemas[id]
+	if !ok {
+		return Schema{}, fmt.Errorf("codec.DirSource.Schema: schema %d: %w", id, errors.ErrNotFound)
+	}
+	return schema, nil
+}
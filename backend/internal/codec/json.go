@@ -0,0 +1,30 @@
+package codec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+type jsonDecoder struct{}
+
+func NewJSONDecoder() Decoder {
+	return jsonDecoder{}
+}
+
+func (jsonDecoder) Decode(_ context.Context, _ string, data []byte, v any) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("codec.jsonDecoder.Decode: %w", err)
+	}
+	return nil
+}
+
+// remap hands a generic value tree decoded from a schema based format over to
+// v through its json tags, so the same model types serve every format.
+func remap(tree any, v any) error {
+	raw, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,170 @@
+package codec
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// protobufDecoder decodes protobuf payloads without generated code: the
+// .proto schema is compiled at runtime and the message is read dynamically.
+type protobufDecoder struct {
+	schemas   SchemaSource
+	defaultID int
+	files     compiledCache[protoreflect.FileDescriptor]
+}
+
+func NewProtobufDecoder(schemas SchemaSource, defaultSchemaID int) Decoder {
+	return &protobufDecoder{
+		schemas:   schemas,
+		defaultID: defaultSchemaID,
+	}
+}
+
+func (d *protobufDecoder) Decode(ctx context.Context, _ string, data []byte, v any) error {
+	const op = "codec.protobufDecoder.Decode"
+
+	id, payload, framed, err := resolveSchemaID(data, d.defaultID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	indexes := []int{0}
+	if framed {
+		indexes, payload, err = parseMessageIndexes(payload)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	file, err := d.file(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	desc, err := messageByIndexes(file, indexes)
+	if err != nil {
+		return fmt.Errorf("%s: schema %d: %w", op, id, err)
+	}
+
+	msg := dynamicpb.NewMessage(desc)
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := remap(messageToTree(msg), v); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (d *protobufDecoder) file(ctx context.Context, id int) (protoreflect.FileDescriptor, error) {
+	if fd, ok := d.files.get(id); ok {
+		return fd, nil
+	}
+
+	schema, err := d.schemas.Schema(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if schema.Type != SchemaProtobuf {
+		return nil, fmt.Errorf("schema %d is %s, not PROTOBUF", id, schema.Type)
+	}
+
+	fd, err := CompileProto(ctx, fmt.Sprintf("schema_%d.proto", id), schema.Text)
+	if err != nil {
+		return nil, err
+	}
+	d.files.put(id, fd)
+	return fd, nil
+}
+
+// CompileProto compiles a single .proto source. Only the well-known types
+// can be imported.
+func CompileProto(ctx context.Context, name, source string) (protoreflect.FileDescriptor, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{name: source}),
+		}),
+	}
+
+	files, err := compiler.Compile(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("compile %s: %w", name, err)
+	}
+	return files[0], nil
+}
+
+func messageByIndexes(file protoreflect.FileDescriptor, indexes []int) (protoreflect.MessageDescriptor, error) {
+	messages := file.Messages()
+	var desc protoreflect.MessageDescriptor
+	for _, idx := range indexes {
+		if idx >= messages.Len() {
+			return nil, fmt.Errorf("message index %v out of range", indexes)
+		}
+		desc = messages.Get(idx)
+		messages = desc.Messages()
+	}
+	if desc == nil {
+		return nil, fmt.Errorf("schema declares no messages")
+	}
+	return desc, nil
+}
+
+// messageToTree converts a message into plain maps keyed by proto field
+// names, which match the snake_case json tags of the model.
+func messageToTree(msg protoreflect.Message) map[string]any {
+	out := make(map[string]any)
+	msg.Range(func(fd protoreflect.FieldDescriptor, val protoreflect.Value) bool {
+		out[string(fd.Name())] = fieldToTree(fd, val)
+		return true
+	})
+	return out
+}
+
+func fieldToTree(fd protoreflect.FieldDescriptor, val protoreflect.Value) any {
+	switch {
+	case fd.IsList():
+		list := val.List()
+		out := make([]any, list.Len())
+		for i := range out {
+			out[i] = scalarToTree(fd, list.Get(i))
+		}
+		return out
+	case fd.IsMap():
+		out := make(map[string]any)
+		val.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			out[k.String()] = scalarToTree(fd.MapValue(), v)
+			return true
+		})
+		return out
+	default:
+		return scalarToTree(fd, val)
+	}
+}
+
+func scalarToTree(fd protoreflect.FieldDescriptor, val protoreflect.Value) any {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		msg := val.Message()
+		if msg.Descriptor().FullName() == "google.protobuf.Timestamp" {
+			fields := msg.Descriptor().Fields()
+			seconds := msg.Get(fields.ByName("seconds")).Int()
+			nanos := msg.Get(fields.ByName("nanos")).Int()
+			return time.Unix(seconds, nanos).UTC()
+		}
+		return messageToTree(msg)
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(val.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return int32(val.Enum())
+	default:
+		return val.Interface()
+	}
+}
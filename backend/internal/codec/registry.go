@@ -0,0 +1,77 @@
+package codec
+
+import (
+	"L0-wbtech/pkg/errors"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RegistryClient resolves schema IDs against a Confluent compatible schema
+// registry over its REST API.
+type RegistryClient struct {
+	baseURL string
+	client  *http.Client
+	cache   compiledCache[Schema]
+}
+
+func NewRegistryClient(baseURL string, timeout time.Duration) *RegistryClient {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &RegistryClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+type registrySchemaResponse struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType"`
+}
+
+func (r *RegistryClient) Schema(ctx context.Context, id int) (Schema, error) {
+	const op = "codec.RegistryClient.Schema"
+
+	if schema, ok := r.cache.get(id); ok {
+		return schema, nil
+	}
+
+	url := fmt.Sprintf("%s/schemas/ids/%d", r.baseURL, id)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Schema{}, fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return Schema{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return Schema{}, fmt.Errorf("%s: schema %d: %w", op, id, errors.ErrNotFound)
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Schema{}, fmt.Errorf("%s: registry returned %d: %s", op, resp.StatusCode, body)
+	}
+
+	var payload registrySchemaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return Schema{}, fmt.Errorf("%s: decode response: %w", op, err)
+	}
+
+	schema := Schema{
+		ID:   id,
+		Type: parseSchemaType(payload.SchemaType),
+		Text: payload.Schema,
+	}
+	r.cache.put(id, schema)
+	return schema, nil
+}
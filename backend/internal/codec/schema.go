@@ -0,0 +1,140 @@
+package codec
+
+import (
+	"L0-wbtech/internal/config"
+	"L0-wbtech/pkg/errors"
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+type SchemaType string
+
+const (
+	SchemaAvro     SchemaType = "AVRO"
+	SchemaProtobuf SchemaType = "PROTOBUF"
+	SchemaJSON     SchemaType = "JSON"
+)
+
+func (t SchemaType) Format() Format {
+	switch t {
+	case SchemaProtobuf:
+		return FormatProtobuf
+	case SchemaJSON:
+		return FormatJSON
+	default:
+		return FormatAvro
+	}
+}
+
+func parseSchemaType(s string) SchemaType {
+	switch strings.ToUpper(s) {
+	case "PROTOBUF":
+		return SchemaProtobuf
+	case "JSON":
+		return SchemaJSON
+	default:
+		// The registry omits schemaType for Avro schemas.
+		return SchemaAvro
+	}
+}
+
+type Schema struct {
+	ID   int
+	Type SchemaType
+	Text string
+}
+
+type SchemaSource interface {
+	Schema(ctx context.Context, id int) (Schema, error)
+}
+
+// ChainSources asks every source in turn and returns the first schema found.
+// Sources are expected to wrap errors.ErrNotFound for unknown IDs.
+func ChainSources(sources ...SchemaSource) SchemaSource {
+	return chain(sources)
+}
+
+type chain []SchemaSource
+
+func (c chain) Schema(ctx context.Context, id int) (Schema, error) {
+	const op = "codec.chain.Schema"
+
+	for _, src := range c {
+		schema, err := src.Schema(ctx, id)
+		if err == nil {
+			return schema, nil
+		}
+		if !stdErrors.Is(err, errors.ErrNotFound) {
+			return Schema{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return Schema{}, fmt.Errorf("%s: schema %d: %w", op, id, errors.ErrNotFound)
+}
+
+// compiledCache keeps parsed schemas per ID; schemas are immutable once
+// registered, so entries never expire.
+type compiledCache[T any] struct {
+	mu    sync.RWMutex
+	items map[int]T
+}
+
+func (c *compiledCache[T]) get(id int) (T, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.items[id]
+	return v, ok
+}
+
+func (c *compiledCache[T]) put(id int, v T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.items == nil {
+		c.items = make(map[int]T)
+	}
+	c.items[id] = v
+}
+
+// resolveSchemaID returns the schema ID and payload of data and whether it
+// came from a registry frame. A configured defaultID takes precedence:
+// payloads are then never sniffed for a frame, since an unframed record can
+// start with a zero byte too.
+func resolveSchemaID(data []byte, defaultID int) (int, []byte, bool, error) {
+	if defaultID > 0 {
+		return defaultID, data, false, nil
+	}
+	if !IsFramed(data) {
+		return 0, nil, false, fmt.Errorf("%w: payload is not framed and no default schema is configured", ErrInvalidFrame)
+	}
+	id, payload, err := ParseFrame(data)
+	return id, payload, true, err
+}
+
+// NewSchemaSource builds the configured schema source. The local directory
+// is consulted before the registry; nil is returned when neither is set.
+func NewSchemaSource(cfg config.SchemaConfig) (SchemaSource, error) {
+	const op = "codec.NewSchemaSource"
+
+	var sources []SchemaSource
+	if cfg.Dir != "" {
+		dir, err := NewDirSource(cfg.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		sources = append(sources, dir)
+	}
+	if cfg.RegistryURL != "" {
+		sources = append(sources, NewRegistryClient(cfg.RegistryURL, cfg.Timeout))
+	}
+
+	switch len(sources) {
+	case 0:
+		return nil, nil
+	case 1:
+		return sources[0], nil
+	default:
+		return ChainSources(sources...), nil
+	}
+}
@@ -0,0 +1,77 @@
+package codec
+
+import (
+	"encoding/binary"
+	stdErrors "errors"
+	"fmt"
+)
+
+// Confluent schema registry wire format: a zero magic byte followed by a
+// big-endian 4 byte schema ID and the encoded payload. Protobuf payloads
+// additionally carry the message index path right after the schema ID.
+const (
+	magicByte   = 0x0
+	frameHeader = 5
+)
+
+var ErrInvalidFrame = stdErrors.New("invalid schema registry frame")
+
+func IsFramed(data []byte) bool {
+	return len(data) >= frameHeader && data[0] == magicByte
+}
+
+// ParseFrame splits a framed message into its schema ID and payload.
+func ParseFrame(data []byte) (int, []byte, error) {
+	if !IsFramed(data) {
+		return 0, nil, ErrInvalidFrame
+	}
+	return int(binary.BigEndian.Uint32(data[1:frameHeader])), data[frameHeader:], nil
+}
+
+// Frame is the inverse of ParseFrame.
+func Frame(schemaID int, payload []byte) []byte {
+	out := make([]byte, frameHeader, frameHeader+len(payload))
+	out[0] = magicByte
+	binary.BigEndian.PutUint32(out[1:frameHeader], uint32(schemaID))
+	return append(out, payload...)
+}
+
+// parseMessageIndexes reads the protobuf message index path. A single zero
+// byte is the shorthand for [0], the first message of the schema.
+func parseMessageIndexes(data []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(data)
+	if n <= 0 {
+		return nil, nil, fmt.Errorf("%w: bad message index count", ErrInvalidFrame)
+	}
+	data = data[n:]
+
+	if count == 0 {
+		return []int{0}, data, nil
+	}
+	if count < 0 || count > int64(len(data)) {
+		return nil, nil, fmt.Errorf("%w: message index count %d", ErrInvalidFrame, count)
+	}
+
+	indexes := make([]int, count)
+	for i := range indexes {
+		idx, n := binary.Varint(data)
+		if n <= 0 || idx < 0 {
+			return nil, nil, fmt.Errorf("%w: bad message index", ErrInvalidFrame)
+		}
+		indexes[i] = int(idx)
+		data = data[n:]
+	}
+	return indexes, data, nil
+}
+
+// AppendMessageIndexes encodes a protobuf message index path.
+func AppendMessageIndexes(dst []byte, indexes []int) []byte {
+	if len(indexes) == 0 || (len(indexes) == 1 && indexes[0] == 0) {
+		return append(dst, 0)
+	}
+	dst = binary.AppendVarint(dst, int64(len(indexes)))
+	for _, idx := range indexes {
+		dst = binary.AppendVarint(dst, int64(idx))
+	}
+	return dst
+}
@@ -4,20 +4,43 @@ import (
 	"flag"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	Env        string       `yaml:"env" env-default:"local"`
-	Server     ServerConfig `yaml:"server"`
-	Database   Postgres     `yaml:"postgres"`
-	Kafka      KafkaConfig  `yaml:"kafka"`
-	Migrations string       `yaml:"migrations" env-default:"./migrations"`
+	Env        string           `yaml:"env" env-default:"local"`
+	Server     ServerConfig     `yaml:"server"`
+	Storage    StorageConfig    `yaml:"storage"`
+	Database   Postgres         `yaml:"postgres"`
+	Kafka      KafkaConfig      `yaml:"kafka"`
+	Ingest     IngestConfig     `yaml:"ingest"`
+	Outbox     OutboxConfig     `yaml:"outbox"`
+	Webhooks   WebhookConfig    `yaml:"webhooks"`
+	Stream     StreamConfig     `yaml:"stream"`
+	Cache      CacheConfig      `yaml:"cache"`
+	Supervisor SupervisorConfig `yaml:"supervisor"`
+	Shutdown   ShutdownConfig   `yaml:"shutdown"`
+	Migrations string           `yaml:"migrations" env-default:"./migrations"`
 }
 
 type ServerConfig struct {
-	Port string `yaml:"port"`
+	Port       string `yaml:"port"`
+	GRPCPort   string `yaml:"grpc_port"`
+	AdminToken string `env:"ADMIN_TOKEN"`
+}
+
+// StorageConfig selects the order storage: "postgres" or "sqlite". SQLite
+// keeps everything in one file and needs no database server.
+type StorageConfig struct {
+	Driver string `yaml:"driver" env:"STORAGE_DRIVER" env-default:"postgres"`
+	SQLite SQLite `yaml:"sqlite"`
+}
+
+type SQLite struct {
+	Path        string        `yaml:"path" env:"SQLITE_PATH" env-default:"./data/orders.db"`
+	BusyTimeout time.Duration `yaml:"busy_timeout" env-default:"5s"`
 }
 
 type Postgres struct {
@@ -29,10 +52,206 @@ type Postgres struct {
 	SSLMode  string `yaml:"sslmode"`
 }
 
+// KafkaConfig describes the cluster and the subscriptions. InitTimeout
+// bounds the wait for the brokers at startup; DialTimeout bounds each
+// connection, TLS handshake and SASL exchange included.
 type KafkaConfig struct {
-	Brokers []string `yaml:"brokers"`
-	Topic   string   `yaml:"topic"`
-	GroupID string   `yaml:"group_id"`
+	Brokers     []string      `yaml:"brokers"`
+	Topic       string        `yaml:"topic"`
+	GroupID     string        `yaml:"group_id"`
+	InitTimeout time.Duration `yaml:"init_timeout" env-default:"30s"`
+	DialTimeout time.Duration `yaml:"dial_timeout" env-default:"60s"`
+	Encoding    string        `yaml:"encoding" env-default:"json"`
+	SchemaID    int           `yaml:"schema_id"`
+	Schemas     SchemaConfig  `yaml:"schemas"`
+	Topics      []TopicConfig `yaml:"topics"`
+	Reader      KafkaReader   `yaml:"reader"`
+	TLS         KafkaTLS      `yaml:"tls"`
+	SASL        KafkaSASL     `yaml:"sasl"`
+}
+
+// KafkaReader tunes the consumer. StartOffset applies to partitions the
+// group has no committed offset for: "earliest", "latest", or an RFC 3339
+// timestamp to start at the first message written since. A zero
+// CommitInterval commits every message before the next is handled.
+type KafkaReader struct {
+	MinBytes          int           `yaml:"min_bytes" env-default:"10000"`
+	MaxBytes          int           `yaml:"max_bytes" env-default:"10000000"`
+	MaxWait           time.Duration `yaml:"max_wait" env-default:"30s"`
+	QueueCapacity     int           `yaml:"queue_capacity" env-default:"100"`
+	StartOffset       string        `yaml:"start_offset" env:"KAFKA_START_OFFSET" env-default:"latest"`
+	CommitInterval    time.Duration `yaml:"commit_interval"`
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval" env-default:"3s"`
+	SessionTimeout    time.Duration `yaml:"session_timeout" env-default:"30s"`
+	RebalanceTimeout  time.Duration `yaml:"rebalance_timeout" env-default:"30s"`
+}
+
+// KafkaTLS encrypts broker connections. CAFile is trusted in addition to
+// the system roots; CertFile and KeyFile authenticate the client.
+type KafkaTLS struct {
+	Enabled            bool   `yaml:"enabled" env:"KAFKA_TLS_ENABLED"`
+	CAFile             string `yaml:"ca_file" env:"KAFKA_TLS_CA_FILE"`
+	CertFile           string `yaml:"cert_file" env:"KAFKA_TLS_CERT_FILE"`
+	KeyFile            string `yaml:"key_file" env:"KAFKA_TLS_KEY_FILE"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// KafkaSASL authenticates to the brokers with "plain", "scram-sha-256" or
+// "scram-sha-512". An empty mechanism disables SASL.
+type KafkaSASL struct {
+	Mechanism string `yaml:"mechanism" env:"KAFKA_SASL_MECHANISM"`
+	Username  string `yaml:"username" env:"KAFKA_SASL_USERNAME"`
+	Password  string `env:"KAFKA_SASL_PASSWORD"`
+}
+
+// TopicConfig declares one subscription. Empty encoding and schema_id fall
+// back to the kafka level settings.
+type TopicConfig struct {
+	Name         string        `yaml:"name"`
+	Handler      string        `yaml:"handler"`
+	Encoding     string        `yaml:"encoding"`
+	SchemaID     int           `yaml:"schema_id"`
+	OnError      string        `yaml:"on_error"`
+	MaxRetries   int           `yaml:"max_retries"`
+	RetryBackoff time.Duration `yaml:"retry_backoff"`
+}
+
+type SchemaConfig struct {
+	RegistryURL string        `yaml:"registry_url" env:"SCHEMA_REGISTRY_URL"`
+	Dir         string        `yaml:"dir"`
+	Timeout     time.Duration `yaml:"timeout" env-default:"5s"`
+}
+
+// IngestConfig selects where orders come from: "kafka", "files" or
+// "inbox". The alternatives to Kafka still take their topics and handlers
+// from the kafka section; they just do not connect to brokers. A deployment
+// without Kafka should disable the outbox relay as well; outbox retention
+// then stops waiting for records to be published.
+type IngestConfig struct {
+	Source string            `yaml:"source" env:"INGEST_SOURCE" env-default:"kafka"`
+	Files  FileIngestConfig  `yaml:"files"`
+	Inbox  InboxIngestConfig `yaml:"inbox"`
+}
+
+type FileIngestConfig struct {
+	Dir          string        `yaml:"dir" env-default:"./inbox"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"2s"`
+}
+
+type InboxIngestConfig struct {
+	BatchSize    int           `yaml:"batch_size" env-default:"100"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"5s"`
+	Lease        time.Duration `yaml:"lease" env-default:"5m"`
+}
+
+// OutboxConfig controls the Kafka relay of domain events. Retention
+// applies either way: every change writes an outbox record, which is
+// deleted once it is older than Retention and the relay and the webhook
+// dispatcher, where enabled, are done with it.
+type OutboxConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	Topic        string        `yaml:"topic" env-default:"order-events"`
+	BatchSize    int           `yaml:"batch_size" env-default:"100"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"1s"`
+	Retention    time.Duration `yaml:"retention" env-default:"24h"`
+}
+
+// WebhookConfig controls partner webhooks. Delivery records are kept for
+// DeliveryRetention.
+type WebhookConfig struct {
+	Enabled           bool          `yaml:"enabled"`
+	Workers           int           `yaml:"workers" env-default:"4"`
+	Timeout           time.Duration `yaml:"timeout" env-default:"10s"`
+	MaxAttempts       int           `yaml:"max_attempts" env-default:"5"`
+	InitialBackoff    time.Duration `yaml:"initial_backoff" env-default:"1s"`
+	MaxBackoff        time.Duration `yaml:"max_backoff" env-default:"1m"`
+	DisableAfter      int           `yaml:"disable_after" env-default:"10"`
+	PollInterval      time.Duration `yaml:"poll_interval" env-default:"1s"`
+	BatchSize         int           `yaml:"batch_size" env-default:"100"`
+	DeliveryRetention time.Duration `yaml:"delivery_retention" env-default:"168h"`
+}
+
+type StreamConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	ClientBuffer int           `yaml:"client_buffer" env-default:"64"`
+	History      int           `yaml:"history" env-default:"1024"`
+	Heartbeat    time.Duration `yaml:"heartbeat" env-default:"15s"`
+	// AllowedOrigins lists the browser origins, such as
+	// "https://ops.example.com", that may open the feed besides the API's
+	// own.
+	AllowedOrigins []string `yaml:"allowed_origins" env:"STREAM_ALLOWED_ORIGINS" env-separator:","`
+	// Token guards GET /orders/stream and defaults to the admin token.
+	// Browsers pass it in the stream_token cookie or the token query
+	// parameter.
+	Token string `env:"STREAM_TOKEN"`
+}
+
+// CacheConfig controls the order cache: "memory" keeps it per process,
+// "resp" shares it between replicas through a Redis compatible server. A
+// small local LRU sits in front of either. With coherence on, replicas
+// sharing a Postgres database refresh their caches from its change
+// notifications.
+type CacheConfig struct {
+	Backend     string        `yaml:"backend" env:"CACHE_BACKEND" env-default:"memory"`
+	Coherence   bool          `yaml:"coherence" env:"CACHE_COHERENCE"`
+	L1Size      int           `yaml:"l1_size" env-default:"1024"`
+	L1TTL       time.Duration `yaml:"l1_ttl" env-default:"30s"`
+	NegativeTTL time.Duration `yaml:"negative_ttl" env-default:"5s"`
+	RESP        RESPCache     `yaml:"resp"`
+	Snapshot    CacheSnapshot `yaml:"snapshot"`
+}
+
+// CacheSnapshot periodically saves the in-memory cache to a file that is
+// loaded at startup instead of reading every order from storage.
+type CacheSnapshot struct {
+	Enabled  bool          `yaml:"enabled"`
+	Path     string        `yaml:"path" env:"CACHE_SNAPSHOT_PATH" env-default:"./data/cache.snapshot"`
+	Interval time.Duration `yaml:"interval" env-default:"5m"`
+	Overlap  time.Duration `yaml:"overlap" env-default:"1m"`
+}
+
+type RESPCache struct {
+	Addr     string        `yaml:"addr" env:"CACHE_ADDR" env-default:"localhost:6379"`
+	Password string        `env:"CACHE_PASSWORD"`
+	DB       int           `yaml:"db"`
+	Prefix   string        `yaml:"prefix" env-default:"orders:"`
+	TTL      time.Duration `yaml:"ttl" env-default:"24h"`
+	Timeout  time.Duration `yaml:"timeout" env-default:"200ms"`
+	PoolSize int           `yaml:"pool_size" env-default:"10"`
+}
+
+// SupervisorConfig controls restarts of background jobs that fail. The
+// servers and the ingest source are never restarted: their failure shuts
+// the application down.
+type SupervisorConfig struct {
+	MaxRestarts    int           `yaml:"max_restarts" env-default:"5"`
+	RestartBackoff time.Duration `yaml:"restart_backoff" env-default:"1s"`
+}
+
+// ShutdownConfig bounds each stage of a graceful shutdown. Ingest is how
+// long messages in flight get to finish before they are abandoned and
+// redelivered after the restart.
+type ShutdownConfig struct {
+	Ingest     time.Duration `yaml:"ingest" env-default:"30s"`
+	HTTP       time.Duration `yaml:"http" env-default:"10s"`
+	Background time.Duration `yaml:"background" env-default:"5s"`
+	Outbox     time.Duration `yaml:"outbox" env-default:"10s"`
+	Metrics    time.Duration `yaml:"metrics" env-default:"5s"`
+	Snapshot   time.Duration `yaml:"snapshot" env-default:"30s"`
+	Storage    time.Duration `yaml:"storage" env-default:"5s"`
+}
+
+// Subscriptions returns the configured topics. The single legacy topic key
+// maps onto an order creation subscription.
+func (k KafkaConfig) Subscriptions() []TopicConfig {
+	if len(k.Topics) > 0 {
+		return k.Topics
+	}
+	if k.Topic == "" {
+		return nil
+	}
+	return []TopicConfig{{Name: k.Topic, Handler: "create"}}
 }
 
 func MustLoad() *Config {
@@ -56,6 +275,11 @@ func MustLoad() *Config {
 
 	loadSecrets(&cfg)
 
+	if err := cfg.validate(); err != nil {
+		slog.Error("Invalid config", "error", err)
+		os.Exit(1)
+	}
+
 	return &cfg
 }
 
@@ -71,6 +295,9 @@ func fetchConfigPath() string {
 }
 
 func loadSecrets(cfg *Config) {
+	if cfg.Storage.Driver == "sqlite" {
+		return
+	}
 	if cfg.Database.Password == "" {
 		if password := os.Getenv("POSTGRES_PASSWORD"); password != "" {
 			cfg.Database.Password = password
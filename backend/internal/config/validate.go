@@ -0,0 +1,88 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// validate rejects settings that would otherwise only fail once the
+// service is running.
+func (c *Config) validate() error {
+	var errs []error
+	if c.UsesKafka() && len(c.Kafka.Brokers) == 0 {
+		errs = append(errs, errors.New("kafka.brokers: at least one broker is required"))
+	}
+	errs = append(errs, c.Kafka.validate()...)
+	return errors.Join(errs...)
+}
+
+// UsesKafka reports whether the service connects to brokers, either to
+// consume orders or to publish events.
+func (c *Config) UsesKafka() bool {
+	return c.Ingest.Source == "kafka" || c.Ingest.Source == "" || c.Outbox.Enabled
+}
+
+func (k KafkaConfig) validate() []error {
+	var errs []error
+	if k.InitTimeout <= 0 {
+		errs = append(errs, errors.New("kafka.init_timeout: must be positive"))
+	}
+	if k.DialTimeout <= 0 {
+		errs = append(errs, errors.New("kafka.dial_timeout: must be positive"))
+	}
+
+	r := k.Reader
+	if r.MinBytes <= 0 || r.MaxBytes <= 0 || r.MinBytes > r.MaxBytes {
+		errs = append(errs, fmt.Errorf("kafka.reader: min_bytes %d and max_bytes %d must be positive, min not above max", r.MinBytes, r.MaxBytes))
+	}
+	if r.MaxWait <= 0 {
+		errs = append(errs, errors.New("kafka.reader.max_wait: must be positive"))
+	}
+	if r.CommitInterval < 0 {
+		errs = append(errs, errors.New("kafka.reader.commit_interval: must not be negative"))
+	}
+	if r.HeartbeatInterval >= r.SessionTimeout {
+		errs = append(errs, errors.New("kafka.reader: heartbeat_interval must be shorter than session_timeout"))
+	}
+	if _, ok := r.StartAt(); !ok && r.StartOffset != "earliest" && r.StartOffset != "latest" {
+		errs = append(errs, fmt.Errorf("kafka.reader.start_offset: %q is neither earliest, latest nor an RFC 3339 timestamp", r.StartOffset))
+	}
+
+	if k.TLS.Enabled {
+		if (k.TLS.CertFile == "") != (k.TLS.KeyFile == "") {
+			errs = append(errs, errors.New("kafka.tls: cert_file and key_file go together"))
+		}
+		files := []struct{ key, path string }{
+			{"ca_file", k.TLS.CAFile},
+			{"cert_file", k.TLS.CertFile},
+			{"key_file", k.TLS.KeyFile},
+		}
+		for _, f := range files {
+			if f.path == "" {
+				continue
+			}
+			if _, err := os.Stat(f.path); err != nil {
+				errs = append(errs, fmt.Errorf("kafka.tls.%s: %w", f.key, err))
+			}
+		}
+	}
+
+	switch k.SASL.Mechanism {
+	case "":
+	case "plain", "scram-sha-256", "scram-sha-512":
+		if k.SASL.Username == "" || k.SASL.Password == "" {
+			errs = append(errs, errors.New("kafka.sasl: username and KAFKA_SASL_PASSWORD are required"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("kafka.sasl.mechanism: unknown mechanism %q", k.SASL.Mechanism))
+	}
+	return errs
+}
+
+// StartAt returns the time of a timestamp start offset.
+func (r KafkaReader) StartAt() (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, r.StartOffset)
+	return t, err == nil
+}
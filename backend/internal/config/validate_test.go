@@ -0,0 +1,140 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig returns the settings the defaults produce, with a broker.
+func validConfig() Config {
+	return Config{
+		Kafka: KafkaConfig{
+			Brokers:     []string{"localhost:9092"},
+			InitTimeout: 30 * time.Second,
+			DialTimeout: time.Minute,
+			Reader: KafkaReader{
+				MinBytes:          10_000,
+				MaxBytes:          10_000_000,
+				MaxWait:           30 * time.Second,
+				StartOffset:       "latest",
+				HeartbeatInterval: 3 * time.Second,
+				SessionTimeout:    30 * time.Second,
+			},
+		},
+		Ingest: IngestConfig{Source: "kafka"},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "exists.pem")
+	if err := os.WriteFile(existing, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.pem")
+
+	for _, tc := range []struct {
+		name   string
+		change func(c *Config)
+		// want lists what the error must mention; none means valid.
+		want []string
+	}{
+		{"defaults", func(*Config) {}, nil},
+		{"no brokers for kafka ingest", func(c *Config) { c.Kafka.Brokers = nil }, []string{"kafka.brokers"}},
+		{"no brokers for the default ingest", func(c *Config) { c.Kafka.Brokers, c.Ingest.Source = nil, "" }, []string{"kafka.brokers"}},
+		{"no brokers for the outbox", func(c *Config) {
+			c.Kafka.Brokers, c.Ingest.Source, c.Outbox.Enabled = nil, "files", true
+		}, []string{"kafka.brokers"}},
+		{"no brokers without kafka", func(c *Config) { c.Kafka.Brokers, c.Ingest.Source = nil, "files" }, nil},
+
+		{"zero init timeout", func(c *Config) { c.Kafka.InitTimeout = 0 }, []string{"kafka.init_timeout"}},
+		{"negative dial timeout", func(c *Config) { c.Kafka.DialTimeout = -time.Second }, []string{"kafka.dial_timeout"}},
+
+		{"zero min bytes", func(c *Config) { c.Kafka.Reader.MinBytes = 0 }, []string{"min_bytes 0"}},
+		{"zero max bytes", func(c *Config) { c.Kafka.Reader.MaxBytes = 0 }, []string{"max_bytes 0"}},
+		{"min bytes above max", func(c *Config) { c.Kafka.Reader.MinBytes = c.Kafka.Reader.MaxBytes + 1 }, []string{"kafka.reader: min_bytes"}},
+		{"min bytes equal to max", func(c *Config) { c.Kafka.Reader.MinBytes = c.Kafka.Reader.MaxBytes }, nil},
+		{"zero max wait", func(c *Config) { c.Kafka.Reader.MaxWait = 0 }, []string{"kafka.reader.max_wait"}},
+		{"negative commit interval", func(c *Config) { c.Kafka.Reader.CommitInterval = -time.Second }, []string{"kafka.reader.commit_interval"}},
+		{"heartbeat as long as the session", func(c *Config) {
+			c.Kafka.Reader.HeartbeatInterval = c.Kafka.Reader.SessionTimeout
+		}, []string{"heartbeat_interval"}},
+		{"heartbeat longer than the session", func(c *Config) {
+			c.Kafka.Reader.HeartbeatInterval = c.Kafka.Reader.SessionTimeout + time.Second
+		}, []string{"heartbeat_interval"}},
+
+		{"earliest start offset", func(c *Config) { c.Kafka.Reader.StartOffset = "earliest" }, nil},
+		{"timestamp start offset", func(c *Config) { c.Kafka.Reader.StartOffset = "2024-05-01T00:00:00Z" }, nil},
+		{"unknown start offset", func(c *Config) { c.Kafka.Reader.StartOffset = "oldest" }, []string{"kafka.reader.start_offset", `"oldest"`}},
+		{"timestamp without zone", func(c *Config) { c.Kafka.Reader.StartOffset = "2024-05-01T00:00:00" }, []string{"kafka.reader.start_offset"}},
+
+		{"tls without files", func(c *Config) { c.Kafka.TLS.Enabled = true }, nil},
+		{"tls with every file", func(c *Config) {
+			c.Kafka.TLS = KafkaTLS{Enabled: true, CAFile: existing, CertFile: existing, KeyFile: existing}
+		}, nil},
+		{"tls cert without key", func(c *Config) {
+			c.Kafka.TLS = KafkaTLS{Enabled: true, CertFile: existing}
+		}, []string{"cert_file and key_file"}},
+		{"tls key without cert", func(c *Config) {
+			c.Kafka.TLS = KafkaTLS{Enabled: true, KeyFile: existing}
+		}, []string{"cert_file and key_file"}},
+		{"tls missing ca file", func(c *Config) {
+			c.Kafka.TLS = KafkaTLS{Enabled: true, CAFile: missing}
+		}, []string{"kafka.tls.ca_file", missing}},
+		{"tls missing cert file", func(c *Config) {
+			c.Kafka.TLS = KafkaTLS{Enabled: true, CertFile: missing, KeyFile: existing}
+		}, []string{"kafka.tls.cert_file"}},
+		{"tls missing key file", func(c *Config) {
+			c.Kafka.TLS = KafkaTLS{Enabled: true, CertFile: existing, KeyFile: missing}
+		}, []string{"kafka.tls.key_file"}},
+		{"missing files with tls disabled", func(c *Config) {
+			c.Kafka.TLS = KafkaTLS{CAFile: missing, CertFile: missing}
+		}, nil},
+
+		{"sasl plain", func(c *Config) {
+			c.Kafka.SASL = KafkaSASL{Mechanism: "plain", Username: "orders", Password: "secret"}
+		}, nil},
+		{"sasl without username", func(c *Config) {
+			c.Kafka.SASL = KafkaSASL{Mechanism: "scram-sha-256", Password: "secret"}
+		}, []string{"kafka.sasl: username"}},
+		{"sasl without password", func(c *Config) {
+			c.Kafka.SASL = KafkaSASL{Mechanism: "scram-sha-512", Username: "orders"}
+		}, []string{"KAFKA_SASL_PASSWORD"}},
+		{"sasl unknown mechanism", func(c *Config) {
+			c.Kafka.SASL = KafkaSASL{Mechanism: "gssapi", Username: "orders", Password: "secret"}
+		}, []string{"kafka.sasl.mechanism", `"gssapi"`}},
+		{"credentials without a mechanism", func(c *Config) {
+			c.Kafka.SASL = KafkaSASL{Username: "orders"}
+		}, nil},
+
+		{"every problem is reported", func(c *Config) {
+			c.Kafka.Brokers = nil
+			c.Kafka.Reader.MaxWait = 0
+			c.Kafka.SASL.Mechanism = "plain"
+		}, []string{"kafka.brokers", "kafka.reader.max_wait", "kafka.sasl: username"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.change(&cfg)
+
+			err := cfg.validate()
+			if len(tc.want) == 0 {
+				if err != nil {
+					t.Errorf("validate: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("validate accepted the config, want an error mentioning %q", tc.want)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("validate returned %q, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
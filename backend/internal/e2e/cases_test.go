@@ -0,0 +1,217 @@
+package e2e
+
+import (
+	"L0-wbtech/internal/kafka"
+	"L0-wbtech/internal/loadgen"
+	"L0-wbtech/internal/model"
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type Message struct {
+	Topic string
+	Key   string
+	Value []byte
+}
+
+// Expect describes the state of one order once every message of a case has
+// been consumed.
+type Expect struct {
+	OrderUID string
+	// Code is the status of GET /order/:order_uid.
+	Code int
+	// The remaining fields are checked only when Code is 200.
+	Status model.OrderStatus
+	Amount *int
+	// Events is the number of outbox records written for the order.
+	Events int
+}
+
+type Case struct {
+	Name     string
+	Messages []Message
+	Expect   []Expect
+}
+
+// Cases returns the end-to-end scenarios. Orders come from a seeded
+// generator, so the table is the same on every call.
+func Cases() []Case {
+	gen := loadgen.NewGenerator(loadgen.GeneratorConfig{Seed: 38})
+
+	valid := gen.Order()
+	duplicate := gen.Order()
+	shipped := gen.Order()
+	cancelled := gen.Order()
+	invalid := gen.Order()
+	invalid.Items = nil
+	late := gen.Order()
+
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	zero := 0
+
+	return []Case{
+		{
+			Name:     "valid order is stored and served",
+			Messages: []Message{orderMessage(valid)},
+			Expect: []Expect{
+				{OrderUID: valid.OrderUID, Code: http.StatusOK, Status: model.StatusCreated, Amount: &valid.Payment.Amount, Events: 1},
+			},
+		},
+		{
+			Name:     "redelivered order is stored once",
+			Messages: []Message{orderMessage(duplicate), orderMessage(duplicate)},
+			Expect: []Expect{
+				{OrderUID: duplicate.OrderUID, Code: http.StatusOK, Status: model.StatusCreated, Events: 1},
+			},
+		},
+		{
+			Name: "malformed and invalid payloads are dropped",
+			Messages: []Message{
+				{Topic: TopicOrders, Key: "broken", Value: []byte(`{"order_uid": "broken",`)},
+				orderMessage(invalid),
+				orderMessage(valid),
+			},
+			Expect: []Expect{
+				{OrderUID: "broken", Code: http.StatusNotFound},
+				{OrderUID: invalid.OrderUID, Code: http.StatusNotFound},
+				{OrderUID: valid.OrderUID, Code: http.StatusOK, Status: model.StatusCreated, Events: 1},
+			},
+		},
+		{
+			Name: "status updates follow the lifecycle",
+			Messages: []Message{
+				orderMessage(shipped),
+				statusMessage(shipped.OrderUID, model.StatusPaid, at),
+				statusMessage(shipped.OrderUID, model.StatusAssembling, at.Add(time.Hour)),
+				statusMessage(shipped.OrderUID, model.StatusShipped, at.Add(2*time.Hour)),
+				statusMessage(shipped.OrderUID, model.StatusCreated, at.Add(3*time.Hour)),
+			},
+			Expect: []Expect{
+				{OrderUID: shipped.OrderUID, Code: http.StatusOK, Status: model.StatusShipped, Events: 4},
+			},
+		},
+		{
+			Name: "cancellation refunds the order",
+			Messages: []Message{
+				orderMessage(cancelled),
+				statusMessage(cancelled.OrderUID, model.StatusPaid, at),
+				cancelMessage(cancelled.OrderUID, at.Add(time.Hour)),
+			},
+			Expect: []Expect{
+				{OrderUID: cancelled.OrderUID, Code: http.StatusOK, Status: model.StatusCancelled, Amount: &zero, Events: 3},
+			},
+		},
+		{
+			Name: "events for unknown orders are dropped",
+			Messages: []Message{
+				statusMessage(late.OrderUID, model.StatusPaid, at),
+				cancelMessage(late.OrderUID, at),
+			},
+			Expect: []Expect{
+				{OrderUID: late.OrderUID, Code: http.StatusNotFound},
+			},
+		},
+	}
+}
+
+// Run plays c against a fresh harness and reports every unmet expectation.
+func Run(ctx context.Context, c Case, log *slog.Logger) error {
+	h, err := Start(log)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+
+	// Topics are consumed concurrently, so each message is awaited before
+	// the next one to keep cross-topic order deterministic.
+	for _, msg := range c.Messages {
+		h.Publish(msg.Topic, msg.Key, msg.Value)
+		if err := h.Sync(ctx); err != nil {
+			return fmt.Errorf("%s: %w", c.Name, err)
+		}
+	}
+
+	var errs []error
+	for _, want := range c.Expect {
+		if err := h.check(want); err != nil {
+			errs = append(errs, fmt.Errorf("%s: order %s: %w", c.Name, want.OrderUID, err))
+		}
+	}
+	return stdErrors.Join(errs...)
+}
+
+func (h *Harness) check(want Expect) error {
+	rec := h.Get("/order/" + want.OrderUID)
+	if rec.Code != want.Code {
+		return fmt.Errorf("GET returned %d, want %d: %s", rec.Code, want.Code, rec.Body.String())
+	}
+	if want.Code != http.StatusOK {
+		return nil
+	}
+
+	var got model.Order
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	if got.Status != want.Status {
+		return fmt.Errorf("status %q, want %q", got.Status, want.Status)
+	}
+	if want.Amount != nil && got.Payment.Amount != *want.Amount {
+		return fmt.Errorf("amount %d, want %d", got.Payment.Amount, *want.Amount)
+	}
+
+	cached, ok := h.Cache.Get(want.OrderUID)
+	if !ok {
+		return fmt.Errorf("order is not cached")
+	}
+	if cached.Status != got.Status || cached.Payment.Amount != got.Payment.Amount {
+		return fmt.Errorf("cache holds %q/%d, storage %q/%d",
+			cached.Status, cached.Payment.Amount, got.Status, got.Payment.Amount)
+	}
+
+	events := 0
+	for _, rec := range h.Storage.Outbox() {
+		if rec.OrderUID == want.OrderUID {
+			events++
+		}
+	}
+	if events != want.Events {
+		return fmt.Errorf("%d outbox events, want %d", events, want.Events)
+	}
+	return nil
+}
+
+func orderMessage(order *model.Order) Message {
+	return Message{Topic: TopicOrders, Key: order.OrderUID, Value: mustMarshal(order)}
+}
+
+func statusMessage(orderUID string, status model.OrderStatus, at time.Time) Message {
+	return Message{Topic: TopicStatus, Key: orderUID, Value: mustMarshal(kafka.StatusEvent{
+		OrderUID:  orderUID,
+		Status:    string(status),
+		Actor:     "e2e",
+		ChangedAt: at,
+	})}
+}
+
+func cancelMessage(orderUID string, at time.Time) Message {
+	return Message{Topic: TopicCancel, Key: orderUID, Value: mustMarshal(kafka.CancelEvent{
+		OrderUID:    orderUID,
+		Reason:      "customer request",
+		Actor:       "e2e",
+		CancelledAt: at,
+	})}
+}
+
+func mustMarshal(v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,23 @@
+package e2e
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestPipeline(t *testing.T) {
+	log := slog.New(slog.DiscardHandler)
+
+	for _, c := range Cases() {
+		t.Run(c.Name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			if err := Run(ctx, c, log); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Package e2e runs the order pipeline end to end without external services:
+// messages are published to an in-memory Kafka log, consumed by the real
+// consumer and handlers, persisted in memory storage and read back through
+// the HTTP API.
+package e2e
+
+import (
+	"L0-wbtech/internal/cache"
+	"L0-wbtech/internal/config"
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/handler"
+	"L0-wbtech/internal/kafka"
+	"L0-wbtech/internal/kafka/kafkatest"
+	"L0-wbtech/internal/service"
+	"L0-wbtech/internal/storage/memory"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	TopicOrders = "orders"
+	TopicStatus = "order-status"
+	TopicCancel = "order-cancel"
+
+	groupID = "e2e"
+)
+
+type Harness struct {
+	Storage *memory.Storage
+	Cache   cache.Cache
+	Service service.Service
+	Kafka   *kafkatest.Log
+
+	router   *gin.Engine
+	consumer *kafka.Consumer
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// Start wires a fresh pipeline and starts consuming. Close stops it.
+func Start(log *slog.Logger) (*Harness, error) {
+	const op = "e2e.Start"
+
+	store := memory.New()
+	orderCache := cache.NewCache()
+	orders := cache.NewReadThrough(orderCache, store, cache.ReadThroughConfig{}, log)
+	svc := service.NewOrderService(store, orders, event.NewBus(log), log)
+
+	subscriptions, err := kafka.BuildSubscriptions(config.KafkaConfig{
+		Encoding: "json",
+		Topics: []config.TopicConfig{
+			{Name: TopicOrders, Handler: string(kafka.HandlerCreate)},
+			{Name: TopicStatus, Handler: string(kafka.HandlerStatus)},
+			{Name: TopicCancel, Handler: string(kafka.HandlerCancel)},
+		},
+	}, nil, svc, log)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	broker := kafkatest.NewLog(3)
+	consumer := kafka.NewConsumerWithReaders(subscriptions, broker.ReaderFactory(groupID), log)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler.New(svc, log).RegisterRoutes(router)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Harness{
+		Storage:  store,
+		Cache:    orderCache,
+		Service:  svc,
+		Kafka:    broker,
+		router:   router,
+		consumer: consumer,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(h.done)
+		consumer.Start(ctx)
+	}()
+
+	return h, nil
+}
+
+// Publish appends a JSON message to topic, keyed like the real producers.
+func (h *Harness) Publish(topic, key string, value []byte) {
+	h.Kafka.Produce(topic, []byte(key), value)
+}
+
+// Sync blocks until the consumer has committed everything published so far.
+func (h *Harness) Sync(ctx context.Context) error {
+	return h.Kafka.WaitCommitted(ctx, groupID, TopicOrders, TopicStatus, TopicCancel)
+}
+
+// Get serves a request through the HTTP API.
+func (h *Harness) Get(path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func (h *Harness) Close() error {
+	h.cancel()
+	<-h.done
+	return h.consumer.Close()
+}
@@ -0,0 +1,72 @@
+package event
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// Bus fans events out to in-process subscribers. Publishing never blocks:
+// a subscriber whose buffer is full misses the event.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+	log  *slog.Logger
+}
+
+func NewBus(log *slog.Logger) *Bus {
+	return &Bus{
+		subs: make(map[*Subscription]struct{}),
+		log:  log,
+	}
+}
+
+type Subscription struct {
+	C       <-chan Event
+	ch      chan Event
+	bus     *Bus
+	dropped atomic.Int64
+	once    sync.Once
+}
+
+func (b *Bus) Subscribe(buffer int) *Subscription {
+	ch := make(chan Event, buffer)
+	sub := &Subscription{C: ch, ch: ch, bus: b}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+func (b *Bus) Publish(_ context.Context, e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subs {
+		select {
+		case sub.ch <- e:
+		default:
+			if sub.dropped.Add(1) == 1 {
+				b.log.Warn("Event subscriber is falling behind, dropping events",
+					"type", e.Type, "order_uid", e.OrderUID)
+			}
+		}
+	}
+}
+
+// Dropped reports how many events the subscriber missed.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+		close(s.ch)
+	})
+}
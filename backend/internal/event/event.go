@@ -0,0 +1,53 @@
+package event
+
+import (
+	"L0-wbtech/internal/model"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+type Type string
+
+const (
+	OrderCreated       Type = "order.created"
+	OrderStatusChanged Type = "order.status_changed"
+	OrderCancelled     Type = "order.cancelled"
+	OrderItemsReturned Type = "order.items_returned"
+)
+
+// Event is a domain event about a single order. Order holds the state of
+// the order after the change and is filled in by storage, inside the
+// transaction that also writes the event to the outbox.
+type Event struct {
+	ID         string              `json:"id"`
+	Type       Type                `json:"type"`
+	OrderUID   string              `json:"order_uid"`
+	OccurredAt time.Time           `json:"occurred_at"`
+	Actor      string              `json:"actor,omitempty"`
+	Reason     string              `json:"reason,omitempty"`
+	Status     *model.StatusChange `json:"status,omitempty"`
+	Items      []model.ItemRef     `json:"items,omitempty"`
+	Refund     int                 `json:"refund,omitempty"`
+	Order      *model.Order        `json:"order,omitempty"`
+}
+
+func New(t Type, orderUID string) Event {
+	return Event{
+		ID:         NewID(),
+		Type:       t,
+		OrderUID:   orderUID,
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+func NewID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, e Event)
+}
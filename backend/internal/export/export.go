@@ -0,0 +1,128 @@
+package export
+
+import (
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatNDJSON  Format = "ndjson"
+	FormatCSV     Format = "csv"
+	FormatParquet Format = "parquet"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatNDJSON, FormatCSV, FormatParquet:
+		return f, nil
+	case "":
+		return FormatNDJSON, nil
+	default:
+		return "", fmt.Errorf("export: unknown format %q", s)
+	}
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// Writer encodes orders one at a time. Close flushes buffered data and
+// writes any trailer, but does not close the underlying io.Writer.
+type Writer interface {
+	Write(order *model.Order) error
+	Close() error
+}
+
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatNDJSON:
+		return newNDJSONWriter(w), nil
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatParquet:
+		return newParquetWriter(w), nil
+	default:
+		return nil, fmt.Errorf("export: unknown format %q", format)
+	}
+}
+
+type Exporter struct {
+	source storage.OrderStreamer
+	log    *slog.Logger
+}
+
+func NewExporter(source storage.OrderStreamer, log *slog.Logger) *Exporter {
+	return &Exporter{
+		source: source,
+		log:    log,
+	}
+}
+
+// Export streams the orders matching filter to w and returns how many were
+// written.
+func (e *Exporter) Export(ctx context.Context, filter storage.OrderFilter, format Format, w io.Writer) (int, error) {
+	const op = "export.Exporter.Export"
+	log := e.log.With(
+		slog.String("op", op),
+		slog.String("format", string(format)),
+	)
+
+	enc, err := NewWriter(format, w)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	count := 0
+	err = e.source.StreamOrders(ctx, filter, func(order *model.Order) error {
+		if err := enc.Write(order); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := enc.Close(); err != nil {
+		return count, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("Orders exported", "count", count)
+	return count, nil
+}
+
+// ParseTime accepts either an RFC 3339 timestamp or a plain date, which is
+// taken as midnight UTC. An empty string yields the zero time.
+func ParseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("export: invalid time %q, want RFC 3339 or YYYY-MM-DD", s)
+	}
+	return t, nil
+}
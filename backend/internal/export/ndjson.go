@@ -0,0 +1,26 @@
+package export
+
+import (
+	"L0-wbtech/internal/model"
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+type ndjsonWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	buf := bufio.NewWriter(w)
+	return &ndjsonWriter{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+func (w *ndjsonWriter) Write(order *model.Order) error {
+	return w.enc.Encode(order)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.buf.Flush()
+}
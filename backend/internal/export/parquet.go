@@ -0,0 +1,34 @@
+package export
+
+import (
+	"L0-wbtech/internal/model"
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRowGroupSize bounds how many rows are buffered before a row group
+// is flushed to the underlying writer.
+const parquetRowGroupSize = 10_000
+
+type parquetWriter struct {
+	w *parquet.GenericWriter[Row]
+}
+
+func newParquetWriter(w io.Writer) *parquetWriter {
+	return &parquetWriter{
+		w: parquet.NewGenericWriter[Row](w,
+			parquet.MaxRowsPerRowGroup(parquetRowGroupSize),
+			parquet.Compression(&parquet.Zstd),
+		),
+	}
+}
+
+func (w *parquetWriter) Write(order *model.Order) error {
+	_, err := w.w.Write(Rows(order))
+	return err
+}
+
+func (w *parquetWriter) Close() error {
+	return w.w.Close()
+}
@@ -0,0 +1,178 @@
+package export
+
+import (
+	"L0-wbtech/internal/model"
+	"strconv"
+	"time"
+)
+
+// Row is one flattened line of the CSV and Parquet exports: an item together
+// with its order, delivery and payment columns. Orders without items produce
+// a single row with empty item columns.
+type Row struct {
+	OrderUID          string    `parquet:"order_uid"`
+	TrackNumber       string    `parquet:"track_number"`
+	Entry             string    `parquet:"entry"`
+	Locale            string    `parquet:"locale"`
+	InternalSignature string    `parquet:"internal_signature"`
+	CustomerID        string    `parquet:"customer_id"`
+	DeliveryService   string    `parquet:"delivery_service"`
+	Shardkey          string    `parquet:"shardkey"`
+	SmID              int64     `parquet:"sm_id"`
+	DateCreated       time.Time `parquet:"date_created,timestamp(millisecond)"`
+	OofShard          string    `parquet:"oof_shard"`
+	Status            string    `parquet:"status"`
+	StatusUpdatedAt   time.Time `parquet:"status_updated_at,timestamp(millisecond)"`
+
+	DeliveryName    string `parquet:"delivery_name"`
+	DeliveryPhone   string `parquet:"delivery_phone"`
+	DeliveryZip     string `parquet:"delivery_zip"`
+	DeliveryCity    string `parquet:"delivery_city"`
+	DeliveryAddress string `parquet:"delivery_address"`
+	DeliveryRegion  string `parquet:"delivery_region"`
+	DeliveryEmail   string `parquet:"delivery_email"`
+
+	PaymentTransaction  string `parquet:"payment_transaction"`
+	PaymentRequestID    string `parquet:"payment_request_id"`
+	PaymentCurrency     string `parquet:"payment_currency"`
+	PaymentProvider     string `parquet:"payment_provider"`
+	PaymentAmount       int64  `parquet:"payment_amount"`
+	PaymentDt           int64  `parquet:"payment_dt"`
+	PaymentBank         string `parquet:"payment_bank"`
+	PaymentDeliveryCost int64  `parquet:"payment_delivery_cost"`
+	PaymentGoodsTotal   int64  `parquet:"payment_goods_total"`
+	PaymentCustomFee    int64  `parquet:"payment_custom_fee"`
+
+	ItemChrtID      *int64     `parquet:"item_chrt_id,optional"`
+	ItemTrackNumber *string    `parquet:"item_track_number,optional"`
+	ItemPrice       *int64     `parquet:"item_price,optional"`
+	ItemRid         *string    `parquet:"item_rid,optional"`
+	ItemName        *string    `parquet:"item_name,optional"`
+	ItemSale        *int64     `parquet:"item_sale,optional"`
+	ItemSize        *string    `parquet:"item_size,optional"`
+	ItemTotalPrice  *int64     `parquet:"item_total_price,optional"`
+	ItemNmID        *int64     `parquet:"item_nm_id,optional"`
+	ItemBrand       *string    `parquet:"item_brand,optional"`
+	ItemStatus      *int64     `parquet:"item_status,optional"`
+	ItemReturnedAt  *time.Time `parquet:"item_returned_at,optional"`
+}
+
+// Rows flattens order into one row per item.
+func Rows(order *model.Order) []Row {
+	base := Row{
+		OrderUID:          order.OrderUID,
+		TrackNumber:       order.TrackNumber,
+		Entry:             order.Entry,
+		Locale:            order.Locale,
+		InternalSignature: order.InternalSignature,
+		CustomerID:        order.CustomerID,
+		DeliveryService:   order.DeliveryService,
+		Shardkey:          order.Shardkey,
+		SmID:              int64(order.SmID),
+		DateCreated:       order.DateCreated,
+		OofShard:          order.OofShard,
+		Status:            string(order.Status),
+		StatusUpdatedAt:   order.StatusUpdatedAt,
+
+		DeliveryName:    order.Delivery.Name,
+		DeliveryPhone:   order.Delivery.Phone,
+		DeliveryZip:     order.Delivery.Zip,
+		DeliveryCity:    order.Delivery.City,
+		DeliveryAddress: order.Delivery.Address,
+		DeliveryRegion:  order.Delivery.Region,
+		DeliveryEmail:   order.Delivery.Email,
+
+		PaymentTransaction:  order.Payment.Transaction,
+		PaymentRequestID:    order.Payment.RequestID,
+		PaymentCurrency:     order.Payment.Currency,
+		PaymentProvider:     order.Payment.Provider,
+		PaymentAmount:       int64(order.Payment.Amount),
+		PaymentDt:           order.Payment.PaymentDt,
+		PaymentBank:         order.Payment.Bank,
+		PaymentDeliveryCost: int64(order.Payment.DeliveryCost),
+		PaymentGoodsTotal:   int64(order.Payment.GoodsTotal),
+		PaymentCustomFee:    int64(order.Payment.CustomFee),
+	}
+
+	if len(order.Items) == 0 {
+		return []Row{base}
+	}
+
+	rows := make([]Row, 0, len(order.Items))
+	for _, item := range order.Items {
+		row := base
+		row.ItemChrtID = &item.ChrtID
+		row.ItemTrackNumber = &item.TrackNumber
+		row.ItemPrice = ptr(int64(item.Price))
+		row.ItemRid = &item.Rid
+		row.ItemName = &item.Name
+		row.ItemSale = ptr(int64(item.Sale))
+		row.ItemSize = &item.Size
+		row.ItemTotalPrice = ptr(int64(item.TotalPrice))
+		row.ItemNmID = &item.NmID
+		row.ItemBrand = &item.Brand
+		row.ItemStatus = ptr(int64(item.Status))
+		row.ItemReturnedAt = item.ReturnedAt
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+var csvHeader = []string{
+	"order_uid", "track_number", "entry", "locale", "internal_signature",
+	"customer_id", "delivery_service", "shardkey", "sm_id", "date_created",
+	"oof_shard", "status", "status_updated_at",
+	"delivery_name", "delivery_phone", "delivery_zip", "delivery_city",
+	"delivery_address", "delivery_region", "delivery_email",
+	"payment_transaction", "payment_request_id", "payment_currency",
+	"payment_provider", "payment_amount", "payment_dt", "payment_bank",
+	"payment_delivery_cost", "payment_goods_total", "payment_custom_fee",
+	"item_chrt_id", "item_track_number", "item_price", "item_rid", "item_name",
+	"item_sale", "item_size", "item_total_price", "item_nm_id", "item_brand",
+	"item_status", "item_returned_at",
+}
+
+func (r Row) csvRecord() []string {
+	return []string{
+		r.OrderUID, r.TrackNumber, r.Entry, r.Locale, r.InternalSignature,
+		r.CustomerID, r.DeliveryService, r.Shardkey, itoa(r.SmID), formatTime(&r.DateCreated),
+		r.OofShard, r.Status, formatTime(&r.StatusUpdatedAt),
+		r.DeliveryName, r.DeliveryPhone, r.DeliveryZip, r.DeliveryCity,
+		r.DeliveryAddress, r.DeliveryRegion, r.DeliveryEmail,
+		r.PaymentTransaction, r.PaymentRequestID, r.PaymentCurrency,
+		r.PaymentProvider, itoa(r.PaymentAmount), itoa(r.PaymentDt), r.PaymentBank,
+		itoa(r.PaymentDeliveryCost), itoa(r.PaymentGoodsTotal), itoa(r.PaymentCustomFee),
+		optInt(r.ItemChrtID), optString(r.ItemTrackNumber), optInt(r.ItemPrice), optString(r.ItemRid), optString(r.ItemName),
+		optInt(r.ItemSale), optString(r.ItemSize), optInt(r.ItemTotalPrice), optInt(r.ItemNmID), optString(r.ItemBrand),
+		optInt(r.ItemStatus), formatTime(r.ItemReturnedAt),
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func itoa(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
+
+func optInt(v *int64) string {
+	if v == nil {
+		return ""
+	}
+	return itoa(*v)
+}
+
+func optString(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,93 @@
+package grpcapi
+
+import (
+	orderv1 "L0-wbtech/api/order/v1"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/stream"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func orderToProto(o *model.Order) *orderv1.Order {
+	if o == nil {
+		return nil
+	}
+
+	items := make([]*orderv1.Item, 0, len(o.Items))
+	for _, item := range o.Items {
+		items = append(items, &orderv1.Item{
+			ChrtId:      item.ChrtID,
+			TrackNumber: item.TrackNumber,
+			Price:       int64(item.Price),
+			Rid:         item.Rid,
+			Name:        item.Name,
+			Sale:        int64(item.Sale),
+			Size:        item.Size,
+			TotalPrice:  int64(item.TotalPrice),
+			NmId:        item.NmID,
+			Brand:       item.Brand,
+			Status:      int64(item.Status),
+			ReturnedAt:  timestampOrNil(item.ReturnedAt),
+		})
+	}
+
+	return &orderv1.Order{
+		OrderUid:    o.OrderUID,
+		TrackNumber: o.TrackNumber,
+		Entry:       o.Entry,
+		Delivery: &orderv1.Delivery{
+			Name:    o.Delivery.Name,
+			Phone:   o.Delivery.Phone,
+			Zip:     o.Delivery.Zip,
+			City:    o.Delivery.City,
+			Address: o.Delivery.Address,
+			Region:  o.Delivery.Region,
+			Email:   o.Delivery.Email,
+		},
+		Payment: &orderv1.Payment{
+			Transaction:  o.Payment.Transaction,
+			RequestId:    o.Payment.RequestID,
+			Currency:     o.Payment.Currency,
+			Provider:     o.Payment.Provider,
+			Amount:       int64(o.Payment.Amount),
+			PaymentDt:    o.Payment.PaymentDt,
+			Bank:         o.Payment.Bank,
+			DeliveryCost: int64(o.Payment.DeliveryCost),
+			GoodsTotal:   int64(o.Payment.GoodsTotal),
+			CustomFee:    int64(o.Payment.CustomFee),
+		},
+		Items:             items,
+		Locale:            o.Locale,
+		InternalSignature: o.InternalSignature,
+		CustomerId:        o.CustomerID,
+		DeliveryService:   o.DeliveryService,
+		Shardkey:          o.Shardkey,
+		SmId:              int64(o.SmID),
+		DateCreated:       timestamppb.New(o.DateCreated),
+		OofShard:          o.OofShard,
+		Status:            string(o.Status),
+		StatusUpdatedAt:   timestamppb.New(o.StatusUpdatedAt),
+	}
+}
+
+func eventToProto(msg stream.Message) *orderv1.OrderEvent {
+	e := msg.Event
+	return &orderv1.OrderEvent{
+		Id:         msg.ID,
+		EventId:    e.ID,
+		Type:       string(e.Type),
+		OrderUid:   e.OrderUID,
+		OccurredAt: timestamppb.New(e.OccurredAt),
+		Actor:      e.Actor,
+		Reason:     e.Reason,
+		Order:      orderToProto(e.Order),
+	}
+}
+
+func timestampOrNil(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,165 @@
+package grpcapi
+
+import (
+	orderv1 "L0-wbtech/api/order/v1"
+	"L0-wbtech/internal/service"
+	"L0-wbtech/internal/stream"
+	"L0-wbtech/pkg/errors"
+	"L0-wbtech/pkg/logger/sl"
+	"context"
+	"encoding/base64"
+	stdErrors "errors"
+	"log/slog"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+	maxBatchSize    = 1000
+)
+
+type orderServer struct {
+	orderv1.UnimplementedOrderServiceServer
+
+	service service.Service
+	hub     *stream.Hub
+	log     *slog.Logger
+}
+
+func (s *orderServer) GetOrder(ctx context.Context, req *orderv1.GetOrderRequest) (*orderv1.Order, error) {
+	const op = "grpcapi.orderServer.GetOrder"
+	log := s.log.With(slog.String("op", op))
+
+	if req.GetOrderUid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_uid is required")
+	}
+
+	order, err := s.service.GetOrder(ctx, req.GetOrderUid())
+	if err != nil {
+		return nil, toStatus(log, err)
+	}
+
+	return orderToProto(order), nil
+}
+
+func (s *orderServer) ListOrders(ctx context.Context, req *orderv1.ListOrdersRequest) (*orderv1.ListOrdersResponse, error) {
+	const op = "grpcapi.orderServer.ListOrders"
+	log := s.log.With(slog.String("op", op))
+
+	pageSize := int(req.GetPageSize())
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+
+	after, err := base64.RawURLEncoding.DecodeString(req.GetPageToken())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+	}
+
+	orders, err := s.service.ListOrders(ctx, string(after), pageSize)
+	if err != nil {
+		return nil, toStatus(log, err)
+	}
+
+	resp := &orderv1.ListOrdersResponse{Orders: make([]*orderv1.Order, 0, len(orders))}
+	for _, order := range orders {
+		resp.Orders = append(resp.Orders, orderToProto(order))
+	}
+	if len(orders) == pageSize {
+		last := orders[len(orders)-1].OrderUID
+		resp.NextPageToken = base64.RawURLEncoding.EncodeToString([]byte(last))
+	}
+
+	return resp, nil
+}
+
+func (s *orderServer) BatchGetOrders(ctx context.Context, req *orderv1.BatchGetOrdersRequest) (*orderv1.BatchGetOrdersResponse, error) {
+	const op = "grpcapi.orderServer.BatchGetOrders"
+	log := s.log.With(slog.String("op", op))
+
+	if len(req.GetOrderUids()) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d order_uids per request", maxBatchSize)
+	}
+
+	orders, missing, err := s.service.GetOrders(ctx, req.GetOrderUids())
+	if err != nil {
+		return nil, toStatus(log, err)
+	}
+
+	resp := &orderv1.BatchGetOrdersResponse{
+		Orders:      make([]*orderv1.Order, 0, len(orders)),
+		MissingUids: missing,
+	}
+	for _, order := range orders {
+		resp.Orders = append(resp.Orders, orderToProto(order))
+	}
+
+	return resp, nil
+}
+
+func (s *orderServer) WatchOrders(req *orderv1.WatchOrdersRequest, srv orderv1.OrderService_WatchOrdersServer) error {
+	const op = "grpcapi.orderServer.WatchOrders"
+	log := s.log.With(slog.String("op", op))
+
+	if s.hub == nil {
+		return status.Error(codes.Unavailable, "order stream is disabled")
+	}
+
+	client, backlog, err := s.hub.Subscribe(stream.Filter{
+		DeliveryService: req.GetDeliveryService(),
+		CustomerID:      req.GetCustomerId(),
+		Entry:           req.GetEntry(),
+	}, req.GetLastEventId())
+	if err != nil {
+		return status.Error(codes.Unavailable, "order stream is shutting down")
+	}
+	defer s.hub.Unsubscribe(client)
+
+	for _, msg := range backlog {
+		if err := srv.Send(eventToProto(msg)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-srv.Context().Done():
+			return nil
+		case msg, ok := <-client.C:
+			if !ok {
+				if client.Lagged() {
+					log.Warn("gRPC watcher fell behind, closing stream")
+					return status.Error(codes.ResourceExhausted, "client too slow, resume with last_event_id")
+				}
+				return status.Error(codes.Unavailable, "server shutting down")
+			}
+			if err := srv.Send(eventToProto(msg)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toStatus(log *slog.Logger, err error) error {
+	switch {
+	case stdErrors.Is(err, errors.ErrNotFound):
+		return status.Error(codes.NotFound, "order not found")
+	case stdErrors.Is(err, errors.ErrInvalidInput):
+		return status.Error(codes.InvalidArgument, "invalid request")
+	case stdErrors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request cancelled")
+	case stdErrors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	default:
+		log.Error("Request failed", sl.Err(err))
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
@@ -0,0 +1,82 @@
+package grpcapi
+
+import (
+	orderv1 "L0-wbtech/api/order/v1"
+	"L0-wbtech/internal/service"
+	"L0-wbtech/internal/stream"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Server serves the order API over gRPC together with the standard health
+// and reflection services.
+type Server struct {
+	grpc   *grpc.Server
+	health *health.Server
+	log    *slog.Logger
+}
+
+// NewServer registers the order service backed by svc. WatchOrders is
+// served from hub and reports Unavailable when hub is nil.
+func NewServer(svc service.Service, hub *stream.Hub, log *slog.Logger) *Server {
+	srv := grpc.NewServer()
+
+	orderv1.RegisterOrderServiceServer(srv, &orderServer{
+		service: svc,
+		hub:     hub,
+		log:     log,
+	})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(orderv1.OrderService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, healthServer)
+
+	reflection.Register(srv)
+
+	return &Server{
+		grpc:   srv,
+		health: healthServer,
+		log:    log,
+	}
+}
+
+func (s *Server) ListenAndServe(port string) error {
+	const op = "grpcapi.Server.ListenAndServe"
+
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.grpc.Serve(lis); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Shutdown reports NOT_SERVING to health checks and waits for in-flight
+// calls to finish, cancelling whatever is left once ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.grpc.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"L0-wbtech/internal/model"
+	"L0-wbtech/pkg/errors"
+	"L0-wbtech/pkg/logger/sl"
+	"crypto/subtle"
+	stdErrors "errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const adminActor = "admin"
+
+type updateStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
+	Actor  string `json:"actor"`
+}
+
+func (h *APIHandler) UpdateStatus(c *gin.Context) {
+	const op = "handler.APIHandler.UpdateStatus"
+	orderUID := c.Param("order_uid")
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("order_uid", orderUID),
+	)
+
+	var req updateStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warn("invalid request body", sl.Err(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status is required"})
+		return
+	}
+
+	order, err := h.service.UpdateStatus(c.Request.Context(), orderUID, model.StatusChange{
+		To:     model.OrderStatus(req.Status),
+		Reason: req.Reason,
+		Actor:  actorOrDefault(req.Actor),
+		Source: "admin",
+	})
+	if err != nil {
+		switch {
+		case stdErrors.Is(err, errors.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		case stdErrors.Is(err, errors.ErrInvalidInput):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown status"})
+		case stdErrors.Is(err, errors.ErrInvalidTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": "status transition not allowed"})
+		default:
+			log.Error("failed to update status", sl.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// adminAuth guards admin routes with a static bearer token.
+func adminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		provided, found := strings.CutPrefix(header, "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+type cancelOrderRequest struct {
+	Reason string `json:"reason" binding:"required"`
+	Actor  string `json:"actor"`
+}
+
+func (h *APIHandler) CancelOrder(c *gin.Context) {
+	const op = "handler.APIHandler.CancelOrder"
+	orderUID := c.Param("order_uid")
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("order_uid", orderUID),
+	)
+
+	var req cancelOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warn("invalid request body", sl.Err(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+
+	order, err := h.service.CancelOrder(c.Request.Context(), orderUID, model.Cancellation{
+		Reason: req.Reason,
+		Actor:  actorOrDefault(req.Actor),
+		Source: "admin",
+	})
+	if err != nil {
+		h.writeMutationError(c, log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+type returnItemsRequest struct {
+	Items  []model.ItemRef `json:"items" binding:"required,min=1"`
+	Reason string          `json:"reason" binding:"required"`
+	Actor  string          `json:"actor"`
+}
+
+func (h *APIHandler) ReturnItems(c *gin.Context) {
+	const op = "handler.APIHandler.ReturnItems"
+	orderUID := c.Param("order_uid")
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("order_uid", orderUID),
+	)
+
+	var req returnItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warn("invalid request body", sl.Err(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "items and reason are required"})
+		return
+	}
+
+	order, err := h.service.ReturnItems(c.Request.Context(), orderUID, model.ItemReturn{
+		Items:  req.Items,
+		Reason: req.Reason,
+		Actor:  actorOrDefault(req.Actor),
+		Source: "admin",
+	})
+	if err != nil {
+		h.writeMutationError(c, log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func actorOrDefault(actor string) string {
+	if actor == "" {
+		return adminActor
+	}
+	return actor
+}
+
+func (h *APIHandler) writeMutationError(c *gin.Context, log *slog.Logger, err error) {
+	switch {
+	case stdErrors.Is(err, errors.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "order or item not found"})
+	case stdErrors.Is(err, errors.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+	case stdErrors.Is(err, errors.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": "operation not allowed in the current order state"})
+	default:
+		log.Error("failed to update order", sl.Err(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	}
+}
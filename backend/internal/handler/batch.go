@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"L0-wbtech/internal/model"
+	"L0-wbtech/pkg/logger/sl"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxBatchGetSize = 1000
+
+type batchGetRequest struct {
+	OrderUIDs []string `json:"order_uids" binding:"required"`
+}
+
+type batchGetResponse struct {
+	Orders   []*model.Order `json:"orders"`
+	NotFound []string       `json:"not_found"`
+}
+
+// ordersAction dispatches custom methods on the orders collection, such as
+// POST /orders:batchGet. Gin has no way to escape the colon, so the route
+// captures everything after "/orders" as a parameter, which also matches
+// paths like /ordersbatchGet; only the exact method names are served.
+func (h *APIHandler) ordersAction(c *gin.Context) {
+	action := c.Param("action")
+	if !strings.HasPrefix(action, ":") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	switch action {
+	case ":batchGet":
+		h.BatchGetOrders(c)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown method"})
+	}
+}
+
+func (h *APIHandler) BatchGetOrders(c *gin.Context) {
+	const op = "handler.APIHandler.BatchGetOrders"
+	log := h.log.With(slog.String("op", op))
+
+	var req batchGetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warn("invalid request body", sl.Err(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order_uids is required"})
+		return
+	}
+	if len(req.OrderUIDs) > maxBatchGetSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("at most %d order_uids per request", maxBatchGetSize),
+		})
+		return
+	}
+
+	orders, missing, err := h.service.GetOrders(c.Request.Context(), req.OrderUIDs)
+	if err != nil {
+		log.Error("failed to get orders", sl.Err(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if missing == nil {
+		missing = []string{}
+	}
+	c.JSON(http.StatusOK, batchGetResponse{Orders: orders, NotFound: missing})
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"L0-wbtech/internal/cache"
+	stdErrors "errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CacheHandler struct {
+	admin *cache.Admin
+	log   *slog.Logger
+}
+
+func NewCacheHandler(admin *cache.Admin, log *slog.Logger) *CacheHandler {
+	return &CacheHandler{
+		admin: admin,
+		log:   log,
+	}
+}
+
+func (h *CacheHandler) RegisterRoutes(admin *gin.RouterGroup) {
+
+	admin.GET("/cache/stats", h.Stats)
+	admin.GET("/cache/orders/:order_uid", h.Inspect)
+	admin.DELETE("/cache/orders/:order_uid", h.Evict)
+	admin.DELETE("/cache/orders", h.EvictAll)
+	admin.POST("/cache/warm", h.Warm)
+	admin.GET("/cache/warm", h.WarmProgress)
+
+}
+
+func (h *CacheHandler) Stats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.admin.Stats())
+}
+
+func (h *CacheHandler) Inspect(c *gin.Context) {
+	orderUID := c.Param("order_uid")
+	l1, l2 := h.admin.Contains(orderUID)
+	c.JSON(http.StatusOK, gin.H{
+		"order_uid": orderUID,
+		"cached":    l1 || l2,
+		"l1":        l1,
+		"l2":        l2,
+	})
+}
+
+func (h *CacheHandler) Evict(c *gin.Context) {
+	const op = "handler.CacheHandler.Evict"
+	orderUID := c.Param("order_uid")
+
+	evicted := h.admin.Evict(orderUID)
+	h.log.Info("cache entry evicted", slog.String("op", op), "order_uid", orderUID, "was_cached", evicted)
+	c.JSON(http.StatusOK, gin.H{"order_uid": orderUID, "evicted": evicted})
+}
+
+func (h *CacheHandler) EvictAll(c *gin.Context) {
+	const op = "handler.CacheHandler.EvictAll"
+
+	n := h.admin.EvictAll()
+	h.log.Warn("cache cleared", slog.String("op", op), "evicted", n)
+	c.JSON(http.StatusOK, gin.H{"evicted": n})
+}
+
+// Warm starts a re-warm that outlives the request; GET /cache/warm reports
+// its progress.
+func (h *CacheHandler) Warm(c *gin.Context) {
+	progress, err := h.admin.Warm()
+	if err != nil {
+		if stdErrors.Is(err, cache.ErrWarmRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": "warm already running", "progress": progress})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	c.JSON(http.StatusAccepted, progress)
+}
+
+func (h *CacheHandler) WarmProgress(c *gin.Context) {
+	c.JSON(http.StatusOK, h.admin.WarmProgress())
+}
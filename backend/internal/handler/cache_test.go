@@ -0,0 +1,269 @@
+package handler_test
+
+import (
+	"L0-wbtech/internal/cache"
+	"L0-wbtech/internal/handler"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage/memory"
+	"L0-wbtech/internal/storage/storagetest"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const adminToken = "test-admin-token"
+
+// blockingLister holds every page until the context is done.
+type blockingLister struct {
+	started chan struct{}
+}
+
+func (l *blockingLister) ListOrders(ctx context.Context, _ string, _ int) ([]*model.Order, error) {
+	close(l.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+type cacheAPI struct {
+	t      *testing.T
+	router *gin.Engine
+	cache  cache.Cache
+	loader *cache.ReadThrough
+	admin  *cache.Admin
+}
+
+// newCacheAPI serves the cache admin routes over a storage holding n
+// orders, with every order loaded into the cache. A non-nil lister replaces
+// the storage for re-warms.
+func newCacheAPI(t *testing.T, n int, lister cache.Lister) (*cacheAPI, []string) {
+	t.Helper()
+
+	log := slog.New(slog.DiscardHandler)
+	store := memory.New()
+	gen := storagetest.NewOrders()
+	uids := make([]string, n)
+	for i := range uids {
+		order := gen.Next()
+		if err := store.CreateOrder(context.Background(), order, nil); err != nil {
+			t.Fatal(err)
+		}
+		uids[i] = order.OrderUID
+	}
+	if lister == nil {
+		lister = store
+	}
+
+	c := cache.NewCache()
+	loader := cache.NewReadThrough(c, store, cache.ReadThroughConfig{}, log)
+	for _, uid := range uids {
+		if _, err := loader.Load(context.Background(), uid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	admin := cache.NewAdmin(loader, c, lister, log)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := handler.New(nil, log).RegisterAdminRoutes(router, adminToken)
+	handler.NewCacheHandler(admin, log).RegisterRoutes(group)
+
+	return &cacheAPI{t: t, router: router, cache: c, loader: loader, admin: admin}, uids
+}
+
+// runAdmin runs the re-warms until the returned function is called, which
+// waits for Run to return.
+func (api *cacheAPI) runAdmin() (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		api.admin.Run(ctx)
+	}()
+	stop = func() {
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			api.t.Fatal("cache admin did not stop")
+		}
+	}
+	api.t.Cleanup(stop)
+	return stop
+}
+
+func (api *cacheAPI) do(method, path, auth string, out any) int {
+	api.t.Helper()
+
+	req := httptest.NewRequest(method, path, nil)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	rec := httptest.NewRecorder()
+	api.router.ServeHTTP(rec, req)
+
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			api.t.Fatalf("%s %s: decode %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func (api *cacheAPI) authorized(method, path string, out any) int {
+	api.t.Helper()
+	return api.do(method, path, "Bearer "+adminToken, out)
+}
+
+func (api *cacheAPI) waitWarm(t *testing.T, state cache.WarmState) cache.WarmProgress {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var progress cache.WarmProgress
+		if code := api.authorized(http.MethodGet, "/admin/cache/warm", &progress); code != http.StatusOK {
+			t.Fatalf("GET /admin/cache/warm returned %d", code)
+		}
+		if progress.State == state {
+			return progress
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("warm is %+v, want %s", progress, state)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCacheAdminRejectsBadTokens(t *testing.T) {
+	api, _ := newCacheAPI(t, 2, nil)
+	api.runAdmin()
+
+	routes := []struct{ method, path string }{
+		{http.MethodGet, "/admin/cache/stats"},
+		{http.MethodGet, "/admin/cache/orders/some-uid"},
+		{http.MethodDelete, "/admin/cache/orders/some-uid"},
+		{http.MethodDelete, "/admin/cache/orders"},
+		{http.MethodPost, "/admin/cache/warm"},
+		{http.MethodGet, "/admin/cache/warm"},
+	}
+	for _, tc := range []struct {
+		name string
+		auth string
+	}{
+		{"no header", ""},
+		{"wrong token", "Bearer wrong"},
+		{"token without scheme", adminToken},
+		{"basic scheme", "Basic " + adminToken},
+		{"token prefix", "Bearer " + adminToken[:len(adminToken)-1]},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, r := range routes {
+				if code := api.do(r.method, r.path, tc.auth, nil); code != http.StatusUnauthorized {
+					t.Errorf("%s %s returned %d, want %d", r.method, r.path, code, http.StatusUnauthorized)
+				}
+			}
+		})
+	}
+
+	// None of the rejected requests reached the cache.
+	if n := api.cache.Len(); n != 2 {
+		t.Errorf("cache holds %d orders after rejected requests, want 2", n)
+	}
+	if progress := api.admin.WarmProgress(); progress.State != cache.WarmIdle {
+		t.Errorf("warm is %s after rejected requests, want %s", progress.State, cache.WarmIdle)
+	}
+}
+
+func TestCacheAdminClearAndRewarm(t *testing.T) {
+	api, uids := newCacheAPI(t, 3, nil)
+	api.runAdmin()
+
+	var evicted struct {
+		OrderUID string `json:"order_uid"`
+		Evicted  bool   `json:"evicted"`
+	}
+	if code := api.authorized(http.MethodDelete, "/admin/cache/orders/"+uids[0], &evicted); code != http.StatusOK || !evicted.Evicted {
+		t.Fatalf("evicting a cached order returned %d %+v", code, evicted)
+	}
+	if l1, l2 := api.loader.Contains(uids[0]); l1 || l2 {
+		t.Errorf("evicted order still in L1 %t, L2 %t", l1, l2)
+	}
+
+	var cleared struct {
+		Evicted int `json:"evicted"`
+	}
+	if code := api.authorized(http.MethodDelete, "/admin/cache/orders", &cleared); code != http.StatusOK || cleared.Evicted != 2 {
+		t.Fatalf("clearing the cache returned %d %+v, want 2 evicted", code, cleared)
+	}
+	var stats cache.Stats
+	api.authorized(http.MethodGet, "/admin/cache/stats", &stats)
+	if stats.Size != 0 || stats.L1Size != 0 {
+		t.Errorf("stats after clearing %+v, want an empty cache", stats)
+	}
+
+	var progress cache.WarmProgress
+	if code := api.authorized(http.MethodPost, "/admin/cache/warm", &progress); code != http.StatusAccepted {
+		t.Fatalf("POST /admin/cache/warm returned %d", code)
+	}
+	if progress.State != cache.WarmRunning || progress.StartedAt == nil {
+		t.Errorf("warm started as %+v", progress)
+	}
+
+	progress = api.waitWarm(t, cache.WarmDone)
+	if progress.Loaded != len(uids) || progress.FinishedAt == nil {
+		t.Errorf("finished warm %+v, want %d loaded", progress, len(uids))
+	}
+	api.authorized(http.MethodGet, "/admin/cache/stats", &stats)
+	if stats.Size != len(uids) {
+		t.Errorf("cache holds %d orders after the warm, want %d", stats.Size, len(uids))
+	}
+	for _, uid := range uids {
+		var inspect struct {
+			Cached bool `json:"cached"`
+			L2     bool `json:"l2"`
+		}
+		api.authorized(http.MethodGet, "/admin/cache/orders/"+uid, &inspect)
+		if !inspect.Cached || !inspect.L2 {
+			t.Errorf("order %s after the warm: %+v, want it in L2", uid, inspect)
+		}
+	}
+}
+
+func TestCacheAdminShutdownCancelsWarm(t *testing.T) {
+	lister := &blockingLister{started: make(chan struct{})}
+	api, _ := newCacheAPI(t, 1, lister)
+	stop := api.runAdmin()
+
+	if code := api.authorized(http.MethodPost, "/admin/cache/warm", nil); code != http.StatusAccepted {
+		t.Fatalf("POST /admin/cache/warm returned %d", code)
+	}
+	select {
+	case <-lister.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("warm did not start")
+	}
+
+	var running struct {
+		Error    string             `json:"error"`
+		Progress cache.WarmProgress `json:"progress"`
+	}
+	if code := api.authorized(http.MethodPost, "/admin/cache/warm", &running); code != http.StatusConflict {
+		t.Errorf("second warm returned %d, want %d", code, http.StatusConflict)
+	}
+	if running.Progress.State != cache.WarmRunning {
+		t.Errorf("conflict reports %+v, want the running warm", running.Progress)
+	}
+
+	// Shutdown cancels the warm and waits for it.
+	stop()
+	progress := api.admin.WarmProgress()
+	if progress.State != cache.WarmFailed || !strings.Contains(progress.Error, context.Canceled.Error()) {
+		t.Errorf("warm after shutdown %+v, want failed with %v", progress, context.Canceled)
+	}
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"L0-wbtech/internal/export"
+	"L0-wbtech/internal/storage"
+	"L0-wbtech/pkg/logger/sl"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportHandler struct {
+	exporter *export.Exporter
+	log      *slog.Logger
+}
+
+func NewExportHandler(exporter *export.Exporter, log *slog.Logger) *ExportHandler {
+	return &ExportHandler{
+		exporter: exporter,
+		log:      log,
+	}
+}
+
+func (h *ExportHandler) RegisterRoutes(admin *gin.RouterGroup) {
+
+	admin.GET("/orders/export", h.Export)
+
+}
+
+// Export streams matching orders straight from storage. Errors after the
+// first byte can only be logged, so clients should check that the body is
+// complete, for example by the Parquet footer or the trailing newline.
+func (h *ExportHandler) Export(c *gin.Context) {
+	const op = "handler.ExportHandler.Export"
+	log := h.log.With(slog.String("op", op))
+
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, err := export.ParseTime(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	to, err := export.ParseTime(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := storage.OrderFilter{
+		From:            from,
+		To:              to,
+		DeliveryService: c.Query("delivery_service"),
+		CustomerID:      c.Query("customer_id"),
+	}
+
+	filename := fmt.Sprintf("orders-%s.%s", time.Now().UTC().Format("20060102-150405"), format.Extension())
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	count, err := h.exporter.Export(c.Request.Context(), filter, format, c.Writer)
+	if err != nil {
+		log.Error("export aborted", sl.Err(err), "written", count)
+		return
+	}
+}
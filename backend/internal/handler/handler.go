@@ -40,7 +40,7 @@ func (h *APIHandler) GetOrder(c *gin.Context) {
 
 	start := time.Now()
 
-	order, err := h.service.GetOrder(context.Background(), orderUID)
+	data, err := h.service.GetOrderJSON(context.Background(), orderUID)
 
 	dataFetchTime := time.Since(start)
 	c.Set("data_fetch_start", start)
@@ -57,7 +57,7 @@ func (h *APIHandler) GetOrder(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, order)
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
 
 	log.Debug("Data fetch completed",
 		"order_uid", orderUID,
@@ -68,7 +68,7 @@ func (h *APIHandler) GetOrder(c *gin.Context) {
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		c.Next()
 	}
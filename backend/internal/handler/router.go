@@ -9,5 +9,19 @@ func (h *APIHandler) RegisterRoutes(router *gin.Engine) {
 	router.Use(corsMiddleware())
 
 	router.GET("/order/:order_uid", h.GetOrder)
+	router.POST("/orders:action", h.ordersAction)
 
 }
+
+// RegisterAdminRoutes mounts the token protected admin API and returns its
+// group so other handlers can register admin routes of their own.
+func (h *APIHandler) RegisterAdminRoutes(router *gin.Engine, token string) *gin.RouterGroup {
+
+	admin := router.Group("/admin", adminAuth(token))
+
+	admin.POST("/orders/:order_uid/status", h.UpdateStatus)
+	admin.POST("/orders/:order_uid/cancel", h.CancelOrder)
+	admin.POST("/orders/:order_uid/returns", h.ReturnItems)
+
+	return admin
+}
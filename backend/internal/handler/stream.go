@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/stream"
+	"L0-wbtech/pkg/logger/sl"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteTimeout    = 10 * time.Second
+	streamTokenCookie = "stream_token"
+	streamTokenParam  = "token"
+)
+
+type StreamHandler struct {
+	hub       *stream.Hub
+	heartbeat time.Duration
+	origins   map[string]struct{}
+	upgrader  websocket.Upgrader
+	log       *slog.Logger
+}
+
+func NewStreamHandler(hub *stream.Hub, heartbeat time.Duration, allowedOrigins []string, log *slog.Logger) *StreamHandler {
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+	h := &StreamHandler{
+		hub:       hub,
+		heartbeat: heartbeat,
+		origins:   make(map[string]struct{}, len(allowedOrigins)),
+		log:       log,
+	}
+	for _, origin := range allowedOrigins {
+		h.origins[strings.ToLower(strings.TrimRight(origin, "/"))] = struct{}{}
+	}
+	h.upgrader.CheckOrigin = h.checkOrigin
+	return h
+}
+
+// RegisterRoutes mounts the feed behind token: it carries every order
+// change, customer data included.
+func (h *StreamHandler) RegisterRoutes(router *gin.Engine, token string) {
+
+	router.GET("/orders/stream", streamAuth(token), h.Stream)
+
+}
+
+// streamAuth accepts the token as a bearer header, the stream_token cookie
+// or the token query parameter, since EventSource and browser WebSockets
+// cannot set headers.
+func streamAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found {
+			provided, _ = c.Cookie(streamTokenCookie)
+		}
+		if provided == "" {
+			provided = c.Query(streamTokenParam)
+		}
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// checkOrigin only restricts browsers. Other clients send no Origin and
+// are let through; a browser always sends one, and it must be the API's
+// own host or an allowlisted origin.
+func (h *StreamHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	_, ok := h.origins[strings.ToLower(origin)]
+	return ok
+}
+
+type streamMessage struct {
+	ID    string      `json:"id"`
+	Event event.Event `json:"event"`
+}
+
+// Stream serves the live order feed as a WebSocket when the request asks for
+// an upgrade and as Server-Sent Events otherwise. Clients resume with the
+// Last-Event-ID header or the last_event_id query parameter.
+func (h *StreamHandler) Stream(c *gin.Context) {
+	const op = "handler.StreamHandler.Stream"
+	log := h.log.With(slog.String("op", op))
+
+	if !h.checkOrigin(c.Request) {
+		log.Warn("Stream request from a disallowed origin", "origin", c.GetHeader("Origin"))
+		c.JSON(http.StatusForbidden, gin.H{"error": "origin not allowed"})
+		return
+	}
+
+	filter := stream.Filter{
+		DeliveryService: c.Query("delivery_service"),
+		CustomerID:      c.Query("customer_id"),
+		Entry:           c.Query("entry"),
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	client, backlog, err := h.hub.Subscribe(filter, lastEventID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "stream is shutting down"})
+		return
+	}
+	defer h.hub.Unsubscribe(client)
+
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		h.serveWebSocket(c, log, client, backlog)
+		return
+	}
+	h.serveSSE(c, log, client, backlog)
+}
+
+func (h *StreamHandler) serveSSE(c *gin.Context, log *slog.Logger, client *stream.Client, backlog []stream.Message) {
+	w := c.Writer
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 3000\n\n")
+	for _, msg := range backlog {
+		if err := writeSSE(w, msg); err != nil {
+			return
+		}
+	}
+	w.Flush()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			w.Flush()
+		case msg, ok := <-client.C:
+			if !ok {
+				if client.Lagged() {
+					log.Warn("SSE client fell behind, closing stream")
+				}
+				return
+			}
+			if err := writeSSE(w, msg); err != nil {
+				log.Debug("SSE write failed", sl.Err(err))
+				return
+			}
+			w.Flush()
+		}
+	}
+}
+
+func writeSSE(w gin.ResponseWriter, msg stream.Message) error {
+	data, err := json.Marshal(msg.Event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", msg.ID, msg.Event.Type, data)
+	return err
+}
+
+func (h *StreamHandler) serveWebSocket(c *gin.Context, log *slog.Logger, client *stream.Client, backlog []stream.Message) {
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Warn("WebSocket upgrade failed", sl.Err(err))
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	// The read loop only handles control frames and notices disconnects.
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(msg stream.Message) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		return conn.WriteJSON(streamMessage{ID: msg.ID, Event: msg.Event})
+	}
+
+	for _, msg := range backlog {
+		if err := write(msg); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		case msg, ok := <-client.C:
+			if !ok {
+				reason := "server shutting down"
+				if client.Lagged() {
+					log.Warn("WebSocket client fell behind, closing stream")
+					reason = "client too slow, resume with last_event_id"
+				}
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, reason),
+					time.Now().Add(wsWriteTimeout))
+				return
+			}
+			if err := write(msg); err != nil {
+				log.Debug("WebSocket write failed", sl.Err(err))
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"L0-wbtech/internal/webhook"
+	"L0-wbtech/pkg/errors"
+	"L0-wbtech/pkg/logger/sl"
+	stdErrors "errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultDeliveriesLimit = 50
+
+type WebhookHandler struct {
+	webhooks *webhook.Service
+	log      *slog.Logger
+}
+
+func NewWebhookHandler(webhooks *webhook.Service, log *slog.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		webhooks: webhooks,
+		log:      log,
+	}
+}
+
+func (h *WebhookHandler) RegisterRoutes(admin *gin.RouterGroup) {
+
+	admin.POST("/webhooks", h.Create)
+	admin.GET("/webhooks", h.List)
+	admin.GET("/webhooks/:id", h.Get)
+	admin.DELETE("/webhooks/:id", h.Delete)
+	admin.POST("/webhooks/:id/enable", h.Enable)
+	admin.POST("/webhooks/:id/disable", h.Disable)
+	admin.GET("/webhooks/:id/deliveries", h.Deliveries)
+
+}
+
+type createWebhookRequest struct {
+	URL             string   `json:"url" binding:"required"`
+	Secret          string   `json:"secret"`
+	Events          []string `json:"events"`
+	DeliveryService string   `json:"delivery_service"`
+}
+
+type createWebhookResponse struct {
+	*webhook.Subscription
+	Secret string `json:"secret"`
+}
+
+func (h *WebhookHandler) Create(c *gin.Context) {
+	const op = "handler.WebhookHandler.Create"
+	log := h.log.With(slog.String("op", op))
+
+	var req createWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warn("invalid request body", sl.Err(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url is required"})
+		return
+	}
+
+	sub := &webhook.Subscription{
+		URL:             req.URL,
+		Secret:          req.Secret,
+		Events:          req.Events,
+		DeliveryService: req.DeliveryService,
+	}
+	secret, err := h.webhooks.Create(c.Request.Context(), sub)
+	if err != nil {
+		h.writeError(c, log, err)
+		return
+	}
+
+	log.Info("webhook registered", "webhook_id", sub.ID, "url", sub.URL)
+	c.JSON(http.StatusCreated, createWebhookResponse{Subscription: sub, Secret: secret})
+}
+
+func (h *WebhookHandler) List(c *gin.Context) {
+	const op = "handler.WebhookHandler.List"
+	log := h.log.With(slog.String("op", op))
+
+	subs, err := h.webhooks.List(c.Request.Context())
+	if err != nil {
+		h.writeError(c, log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": subs})
+}
+
+func (h *WebhookHandler) Get(c *gin.Context) {
+	const op = "handler.WebhookHandler.Get"
+	log := h.log.With(slog.String("op", op))
+
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+
+	sub, err := h.webhooks.Get(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	const op = "handler.WebhookHandler.Delete"
+	log := h.log.With(slog.String("op", op))
+
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhooks.Delete(c.Request.Context(), id); err != nil {
+		h.writeError(c, log, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) Enable(c *gin.Context) {
+	h.setActive(c, true)
+}
+
+func (h *WebhookHandler) Disable(c *gin.Context) {
+	h.setActive(c, false)
+}
+
+func (h *WebhookHandler) setActive(c *gin.Context, active bool) {
+	const op = "handler.WebhookHandler.setActive"
+	log := h.log.With(slog.String("op", op))
+
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhooks.SetActive(c.Request.Context(), id, active); err != nil {
+		h.writeError(c, log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": id, "active": active})
+}
+
+func (h *WebhookHandler) Deliveries(c *gin.Context) {
+	const op = "handler.WebhookHandler.Deliveries"
+	log := h.log.With(slog.String("op", op))
+
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+
+	limit := defaultDeliveriesLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := h.webhooks.Deliveries(c.Request.Context(), id, limit)
+	if err != nil {
+		h.writeError(c, log, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+func webhookID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *WebhookHandler) writeError(c *gin.Context, log *slog.Logger, err error) {
+	switch {
+	case stdErrors.Is(err, errors.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+	case stdErrors.Is(err, errors.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Error("webhook request failed", sl.Err(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	}
+}
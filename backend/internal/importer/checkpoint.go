@@ -0,0 +1,90 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Checkpoint remembers how far each file got. Next is the index of the
+// first record not yet committed. Rejects marks how long the rejects file
+// was at that point: lines past it belong to records that will be read
+// again, so a resumed run cuts them off.
+type Checkpoint struct {
+	path    string
+	Files   map[string]FileProgress `json:"files"`
+	Rejects *RejectsMark            `json:"rejects,omitempty"`
+}
+
+type RejectsMark struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+type FileProgress struct {
+	Next int  `json:"next"`
+	Done bool `json:"done"`
+}
+
+// LoadCheckpoint reads the checkpoint at path, starting empty if the file
+// does not exist. An empty path gives a checkpoint that is never saved.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	cp := &Checkpoint{path: path, Files: make(map[string]FileProgress)}
+	if path == "" {
+		return cp, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, err
+	}
+	if cp.Files == nil {
+		cp.Files = make(map[string]FileProgress)
+	}
+	return cp, nil
+}
+
+func (c *Checkpoint) Get(file string) FileProgress {
+	return c.Files[file]
+}
+
+// Set records progress for file together with the rejects file state it
+// matches and persists the checkpoint atomically.
+func (c *Checkpoint) Set(file string, progress FileProgress, rejects RejectsMark) error {
+	c.Files[file] = progress
+	c.Rejects = &rejects
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
@@ -0,0 +1,331 @@
+package importer
+
+import (
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage"
+	"L0-wbtech/pkg/logger/sl"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	BatchSize int
+	// CheckpointPath enables resuming; empty imports from scratch.
+	CheckpointPath   string
+	RejectsPath      string
+	ProgressInterval time.Duration
+}
+
+type Stats struct {
+	Files      int
+	Read       int
+	Inserted   int
+	Duplicates int
+	Rejected   int
+}
+
+// Importer loads orders from files into storage. Every order is validated
+// and written in batches; records that cannot be decoded, validated or
+// stored are appended to the rejects file together with the reason.
+type Importer struct {
+	store storage.BatchWriter
+	cfg   Config
+	log   *slog.Logger
+
+	mu    sync.Mutex
+	stats Stats
+
+	checkpoint *Checkpoint
+	rejects    *os.File
+}
+
+type pending struct {
+	file  string
+	rec   record
+	order *model.Order
+}
+
+func New(store storage.BatchWriter, cfg Config, log *slog.Logger) *Importer {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.ProgressInterval <= 0 {
+		cfg.ProgressInterval = 5 * time.Second
+	}
+	return &Importer{
+		store: store,
+		cfg:   cfg,
+		log:   log,
+	}
+}
+
+func (im *Importer) Run(ctx context.Context, files []string) (Stats, error) {
+	const op = "importer.Importer.Run"
+	log := im.log.With(slog.String("op", op))
+
+	cp, err := LoadCheckpoint(im.cfg.CheckpointPath)
+	if err != nil {
+		return Stats{}, fmt.Errorf("%s: load checkpoint: %w", op, err)
+	}
+	im.checkpoint = cp
+
+	im.rejects, err = os.OpenFile(im.cfg.RejectsPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return Stats{}, fmt.Errorf("%s: open rejects file: %w", op, err)
+	}
+	defer im.rejects.Close()
+
+	if err := im.rewindRejects(); err != nil {
+		return Stats{}, fmt.Errorf("%s: rewind rejects file: %w", op, err)
+	}
+
+	start := time.Now()
+	stop := im.reportProgress(log, start)
+	defer stop()
+
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return im.snapshot(), fmt.Errorf("%s: %w", op, err)
+		}
+
+		progress := cp.Get(file)
+		if progress.Done {
+			log.Info("Skipping imported file", "file", file)
+			continue
+		}
+		if progress.Next > 0 {
+			log.Info("Resuming file", "file", file, "from_record", progress.Next)
+		} else {
+			log.Info("Importing file", "file", file)
+		}
+
+		if err := im.importFile(ctx, file, progress.Next); err != nil {
+			return im.snapshot(), fmt.Errorf("%s: %s: %w", op, file, err)
+		}
+
+		im.mu.Lock()
+		im.stats.Files++
+		im.mu.Unlock()
+	}
+
+	stats := im.snapshot()
+	log.Info("Import finished",
+		"files", stats.Files,
+		"read", stats.Read,
+		"inserted", stats.Inserted,
+		"duplicates", stats.Duplicates,
+		"rejected", stats.Rejected,
+		"elapsed", time.Since(start).Round(time.Millisecond))
+	return stats, nil
+}
+
+func (im *Importer) importFile(ctx context.Context, file string, skip int) error {
+	batch := make([]pending, 0, im.cfg.BatchSize)
+	next := skip
+
+	commit := func() error {
+		if err := im.flush(ctx, batch); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		return im.save(file, FileProgress{Next: next})
+	}
+
+	err := readFile(file, func(rec record) error {
+		if rec.Index < skip {
+			return nil
+		}
+		next = rec.Index + 1
+
+		im.mu.Lock()
+		im.stats.Read++
+		im.mu.Unlock()
+
+		var order model.Order
+		if err := json.Unmarshal(rec.Raw, &order); err != nil {
+			return im.reject(file, rec, fmt.Errorf("decode: %w", err))
+		}
+		if err := order.Validate(); err != nil {
+			return im.reject(file, rec, fmt.Errorf("invalid order: %w", err))
+		}
+
+		batch = append(batch, pending{file: file, rec: rec, order: &order})
+		if len(batch) < im.cfg.BatchSize {
+			return nil
+		}
+		return commit()
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := commit(); err != nil {
+		return err
+	}
+	return im.save(file, FileProgress{Next: next, Done: true})
+}
+
+// save makes the rejects written so far durable and checkpoints progress
+// along with the rejects file size, so a resume drops the rejects of
+// records it reads again.
+func (im *Importer) save(file string, progress FileProgress) error {
+	if err := im.rejects.Sync(); err != nil {
+		return err
+	}
+	info, err := im.rejects.Stat()
+	if err != nil {
+		return err
+	}
+	return im.checkpoint.Set(file, progress, RejectsMark{Path: im.cfg.RejectsPath, Size: info.Size()})
+}
+
+// rewindRejects drops the rejects a previous run wrote after its last
+// checkpoint. A checkpoint kept for another rejects file, or a file that
+// has been replaced by a shorter one, is left alone.
+func (im *Importer) rewindRejects() error {
+	mark := im.checkpoint.Rejects
+	if mark == nil || mark.Path != im.cfg.RejectsPath {
+		return nil
+	}
+
+	info, err := im.rejects.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() <= mark.Size {
+		return nil
+	}
+
+	im.log.Info("Dropping rejects written after the checkpoint",
+		"file", im.cfg.RejectsPath,
+		"bytes", info.Size()-mark.Size)
+	return im.rejects.Truncate(mark.Size)
+}
+
+// flush writes batch in one transaction. When that fails, orders are
+// retried one by one so a single bad record does not sink its neighbours;
+// if none of them can be stored the failure is systemic and aborts the run.
+func (im *Importer) flush(ctx context.Context, batch []pending) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	orders := make([]*model.Order, len(batch))
+	for i, p := range batch {
+		orders[i] = p.order
+	}
+
+	inserted, err := im.store.CreateOrders(ctx, orders)
+	if err == nil {
+		im.count(len(batch), inserted)
+		return nil
+	}
+	if ctx.Err() != nil {
+		return err
+	}
+
+	im.log.Warn("Batch failed, retrying orders one by one", sl.Err(err), "batch_size", len(batch))
+
+	var failed []pending
+	var failures []error
+	for _, p := range batch {
+		inserted, err := im.store.CreateOrders(ctx, []*model.Order{p.order})
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			failed = append(failed, p)
+			failures = append(failures, err)
+			continue
+		}
+		im.count(1, inserted)
+	}
+
+	if len(failed) == len(batch) {
+		return fmt.Errorf("every order of the batch failed: %w", errors.Join(failures...))
+	}
+	for i, p := range failed {
+		if err := im.reject(p.file, p.rec, fmt.Errorf("store: %w", failures[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type rejection struct {
+	File   string `json:"file"`
+	Record int    `json:"record"`
+	Error  string `json:"error"`
+	Raw    string `json:"raw"`
+}
+
+func (im *Importer) reject(file string, rec record, cause error) error {
+	im.mu.Lock()
+	im.stats.Rejected++
+	im.mu.Unlock()
+
+	line, err := json.Marshal(rejection{
+		File:   file,
+		Record: rec.Index,
+		Error:  cause.Error(),
+		Raw:    string(rec.Raw),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = im.rejects.Write(append(line, '\n'))
+	return err
+}
+
+func (im *Importer) count(written, inserted int) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	im.stats.Inserted += inserted
+	im.stats.Duplicates += written - inserted
+}
+
+func (im *Importer) snapshot() Stats {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	return im.stats
+}
+
+func (im *Importer) reportProgress(log *slog.Logger, start time.Time) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(im.cfg.ProgressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				stats := im.snapshot()
+				elapsed := time.Since(start)
+				log.Info("Import progress",
+					"files", stats.Files,
+					"read", stats.Read,
+					"inserted", stats.Inserted,
+					"duplicates", stats.Duplicates,
+					"rejected", stats.Rejected,
+					"orders_per_sec", fmt.Sprintf("%.1f", float64(stats.Read)/elapsed.Seconds()))
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
@@ -0,0 +1,165 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// record is one raw order read from a file. Index counts records from the
+// start of the file and is what checkpoints refer to.
+type record struct {
+	Index int
+	Raw   json.RawMessage
+}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ListFiles returns path itself or, for a directory, every JSON and NDJSON
+// file below it in lexical order. Gzip-compressed files end in ".gz".
+func ListFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isOrderFile(p) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+func isOrderFile(path string) bool {
+	switch filepath.Ext(strings.TrimSuffix(path, ".gz")) {
+	case ".json", ".ndjson", ".jsonl":
+		return true
+	default:
+		return false
+	}
+}
+
+func isNDJSON(path string) bool {
+	ext := filepath.Ext(strings.TrimSuffix(path, ".gz"))
+	return ext == ".ndjson" || ext == ".jsonl"
+}
+
+// readFile calls fn for every record in path. NDJSON files are read line by
+// line, so a broken line is reported to fn as a record that fails to
+// decode. Other files hold a JSON array or a stream of JSON objects; a
+// syntax error there ends the file with an error.
+func readFile(path string, fn func(record) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = bufio.NewReaderSize(f, 1<<20)
+	if magic, _ := r.(*bufio.Reader).Peek(2); bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	if isNDJSON(path) {
+		return readLines(r, fn)
+	}
+	return readJSON(r, fn)
+}
+
+func readLines(r io.Reader, fn func(record) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 64<<20)
+
+	index := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		raw := make(json.RawMessage, len(line))
+		copy(raw, line)
+		if err := fn(record{Index: index, Raw: raw}); err != nil {
+			return err
+		}
+		index++
+	}
+	return scanner.Err()
+}
+
+func readJSON(r io.Reader, fn func(record) error) error {
+	br := bufio.NewReader(r)
+	first, err := peekNonSpace(br)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(br)
+
+	inArray := first == '['
+	if inArray {
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	}
+
+	for index := 0; ; index++ {
+		if inArray && !dec.More() {
+			break
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if err == io.EOF && !inArray {
+				return nil
+			}
+			return fmt.Errorf("record %d: %w", index, err)
+		}
+		if err := fn(record{Index: index, Raw: raw}); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, r.UnreadByte()
+	}
+}
@@ -0,0 +1,192 @@
+package ingest
+
+import (
+	"L0-wbtech/internal/kafka"
+	"L0-wbtech/pkg/logger/sl"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+const (
+	doneDir   = "done"
+	failedDir = "failed"
+)
+
+type FileConfig struct {
+	Dir          string
+	PollInterval time.Duration
+}
+
+// FileSource ingests files dropped into a directory per topic: a file in
+// <dir>/<topic> is one message for that topic's subscription. Processed
+// files move to <dir>/<topic>/done, rejected ones to <dir>/<topic>/failed
+// next to a .error file with the reason. Files whose name starts with a dot
+// or ends in .tmp are ignored, so writers can create a file under a
+// temporary name and rename it when complete.
+type FileSource struct {
+	subscriptions []kafka.Subscription
+	cfg           FileConfig
+	log           *slog.Logger
+	stopper       stopper
+}
+
+func NewFileSource(subscriptions []kafka.Subscription, cfg FileConfig, log *slog.Logger) (*FileSource, error) {
+	const op = "ingest.NewFileSource"
+
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("%s: directory is required", op)
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 2 * time.Second
+	}
+
+	for _, sub := range subscriptions {
+		for _, dir := range []string{doneDir, failedDir} {
+			if err := os.MkdirAll(filepath.Join(cfg.Dir, sub.Topic, dir), 0o755); err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+
+	return &FileSource{
+		subscriptions: subscriptions,
+		cfg:           cfg,
+		log:           log,
+		stopper:       newStopper(),
+	}, nil
+}
+
+func (s *FileSource) Start(ctx context.Context) error {
+	const op = "ingest.FileSource.Start"
+	log := s.log.With(slog.String("op", op))
+
+	fetchCtx, done := s.stopper.fetching(ctx)
+	defer done()
+
+	log.Info("Watching inbox directory", "dir", s.cfg.Dir, "poll_interval", s.cfg.PollInterval)
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, sub := range s.subscriptions {
+			s.scan(ctx, fetchCtx, sub)
+		}
+
+		select {
+		case <-fetchCtx.Done():
+			log.Info("File source stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan processes the pending files of one topic in name order. It stops at
+// a file the error policy keeps, so later files wait behind it like later
+// offsets of a partition. Files are handled under ctx; once fetchCtx is done
+// no further file is picked up.
+func (s *FileSource) scan(ctx, fetchCtx context.Context, sub kafka.Subscription) {
+	const op = "ingest.FileSource.scan"
+	log := s.log.With(slog.String("op", op), slog.String("topic", sub.Topic))
+
+	dir := filepath.Join(s.cfg.Dir, sub.Topic)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Error("Failed to list inbox directory", sl.Err(err))
+		return
+	}
+
+	for _, entry := range entries {
+		if fetchCtx.Err() != nil {
+			return
+		}
+		name := entry.Name()
+		if !entry.Type().IsRegular() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+
+		if !s.process(ctx, log, sub, dir, name) {
+			return
+		}
+	}
+}
+
+func (s *FileSource) process(ctx context.Context, log *slog.Logger, sub kafka.Subscription, dir, name string) bool {
+	log = log.With(slog.String("file", name))
+	path := filepath.Join(dir, name)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Error("Failed to read file", sl.Err(err))
+		return false
+	}
+
+	msg := kafkago.Message{
+		Topic: sub.Topic,
+		Key:   []byte(name),
+		Value: data,
+		Time:  time.Now(),
+	}
+	if contentType := contentTypeOf(name); contentType != "" {
+		msg.Headers = []kafkago.Header{{Key: "content-type", Value: []byte(contentType)}}
+	}
+
+	err = sub.Handle(ctx, msg, s.log)
+	switch {
+	case err == nil:
+		if err := os.Rename(path, filepath.Join(dir, doneDir, name)); err != nil {
+			log.Error("Failed to move processed file", sl.Err(err))
+			return false
+		}
+		log.Info("File processed")
+	case ctx.Err() != nil:
+		return false
+	case !sub.Commits(err):
+		log.Error("Failed to handle file, keeping it for retry", sl.Err(err))
+		return false
+	default:
+		log.Error("Rejecting file", sl.Err(err))
+		reason := filepath.Join(dir, failedDir, name+".error")
+		if err := os.WriteFile(reason, []byte(err.Error()+"\n"), 0o644); err != nil {
+			log.Error("Failed to write rejection reason", sl.Err(err))
+		}
+		if err := os.Rename(path, filepath.Join(dir, failedDir, name)); err != nil {
+			log.Error("Failed to move rejected file", sl.Err(err))
+			return false
+		}
+	}
+	return true
+}
+
+// contentTypeOf maps well-known extensions to content types; anything else
+// is left to the decoder to detect.
+func contentTypeOf(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	switch {
+	case ext == ".json":
+		return "application/json"
+	case slices.Contains([]string{".pb", ".protobuf"}, ext):
+		return "application/x-protobuf"
+	case ext == ".avro":
+		return "application/avro"
+	default:
+		return ""
+	}
+}
+
+func (s *FileSource) Stop(ctx context.Context) error {
+	return s.stopper.stop(ctx, "ingest.FileSource.Stop")
+}
+
+func (s *FileSource) Close() error {
+	return nil
+}
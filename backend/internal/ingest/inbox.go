@@ -0,0 +1,168 @@
+package ingest
+
+import (
+	"L0-wbtech/internal/kafka"
+	"L0-wbtech/pkg/logger/sl"
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// ErrRejected marks an inbox message that will never be processed; the
+// store moves it out of the pending set.
+var ErrRejected = stdErrors.New("message rejected")
+
+type InboxMessage struct {
+	ID          int64     `db:"id"`
+	Topic       string    `db:"topic"`
+	ContentType string    `db:"content_type"`
+	Payload     []byte    `db:"payload"`
+	Attempts    int       `db:"attempts"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+type InboxStore interface {
+	// ProcessInbox claims up to limit pending messages for lease, oldest
+	// first and skipping those claimed by other workers, and passes them to
+	// fn one by one outside any transaction. A nil result marks the message
+	// processed and ErrRejected marks it failed; any other error records
+	// the attempt, leaves the message pending and ends the batch. It
+	// returns the number of messages settled.
+	ProcessInbox(ctx context.Context, limit int, lease time.Duration, fn func(ctx context.Context, msg InboxMessage) error) (int, error)
+	// ListenInbox signals on the returned channel when messages arrive,
+	// until ctx is cancelled.
+	ListenInbox(ctx context.Context) (<-chan struct{}, error)
+}
+
+type InboxConfig struct {
+	BatchSize    int
+	PollInterval time.Duration
+	// Lease bounds how long a batch may take, retries included, before
+	// another worker takes over its remaining messages.
+	Lease time.Duration
+}
+
+// InboxSource drains an inbox table. New rows wake it through the store's
+// notifications; polling covers missed notifications and retries.
+type InboxSource struct {
+	store         InboxStore
+	subscriptions map[string]kafka.Subscription
+	cfg           InboxConfig
+	log           *slog.Logger
+	stopper       stopper
+}
+
+func NewInboxSource(store InboxStore, subscriptions []kafka.Subscription, cfg InboxConfig, log *slog.Logger) *InboxSource {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 5 * time.Minute
+	}
+	return &InboxSource{
+		store:         store,
+		subscriptions: bySubscriptionTopic(subscriptions),
+		cfg:           cfg,
+		log:           log,
+		stopper:       newStopper(),
+	}
+}
+
+func (s *InboxSource) Start(ctx context.Context) error {
+	const op = "ingest.InboxSource.Start"
+	log := s.log.With(slog.String("op", op))
+
+	fetchCtx, done := s.stopper.fetching(ctx)
+	defer done()
+
+	notifications, err := s.store.ListenInbox(fetchCtx)
+	if err != nil {
+		log.Warn("Inbox notifications unavailable, polling only", sl.Err(err))
+	}
+
+	log.Info("Draining inbox", "batch_size", s.cfg.BatchSize, "poll_interval", s.cfg.PollInterval)
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.drain(ctx, fetchCtx); err != nil && ctx.Err() == nil {
+			log.Error("Failed to drain inbox", sl.Err(err))
+		}
+
+		select {
+		case <-fetchCtx.Done():
+			log.Info("Inbox source stopped")
+			return nil
+		case <-ticker.C:
+		case <-notifications:
+		}
+	}
+}
+
+// drain processes batches under ctx until the inbox is empty. Once fetchCtx
+// is done the batch in progress completes but no further one is claimed.
+func (s *InboxSource) drain(ctx, fetchCtx context.Context) error {
+	for fetchCtx.Err() == nil {
+		n, err := s.store.ProcessInbox(ctx, s.cfg.BatchSize, s.cfg.Lease, s.handle)
+		if err != nil {
+			return err
+		}
+		if n < s.cfg.BatchSize {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *InboxSource) handle(ctx context.Context, m InboxMessage) error {
+	log := s.log.With(
+		slog.String("topic", m.Topic),
+		slog.Int64("inbox_id", m.ID),
+	)
+
+	sub, ok := s.subscriptions[m.Topic]
+	if !ok {
+		log.Error("Rejecting inbox message for unknown topic")
+		return fmt.Errorf("%w: no subscription for topic %q", ErrRejected, m.Topic)
+	}
+
+	msg := kafkago.Message{
+		Topic:  m.Topic,
+		Offset: m.ID,
+		Value:  m.Payload,
+		Time:   m.CreatedAt,
+	}
+	if m.ContentType != "" {
+		msg.Headers = []kafkago.Header{{Key: "content-type", Value: []byte(m.ContentType)}}
+	}
+
+	err := sub.Handle(ctx, msg, s.log)
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return err
+	case !sub.Commits(err):
+		log.Error("Failed to handle inbox message, keeping it for retry", sl.Err(err))
+		return err
+	default:
+		log.Error("Rejecting inbox message", sl.Err(err))
+		return fmt.Errorf("%w: %w", ErrRejected, err)
+	}
+}
+
+func (s *InboxSource) Stop(ctx context.Context) error {
+	return s.stopper.stop(ctx, "ingest.InboxSource.Stop")
+}
+
+func (s *InboxSource) Close() error {
+	return nil
+}
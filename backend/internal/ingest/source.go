@@ -0,0 +1,77 @@
+// Package ingest provides ways to receive orders other than a Kafka
+// cluster. Every source hands messages to the same subscriptions, with the
+// same handlers and error policies, as kafka.Consumer.
+package ingest
+
+import (
+	"L0-wbtech/internal/kafka"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Source delivers incoming messages to the processing pipeline. Start
+// blocks until ctx is cancelled or Stop is called, and reports why when it
+// stops on its own; Close releases what the source holds. *kafka.Consumer
+// is a Source.
+type Source interface {
+	Start(ctx context.Context) error
+	// Stop stops taking new messages and waits until the ones in flight
+	// are settled and Start has returned, or ctx is done.
+	Stop(ctx context.Context) error
+	Close() error
+}
+
+var _ Source = (*kafka.Consumer)(nil)
+
+func bySubscriptionTopic(subscriptions []kafka.Subscription) map[string]kafka.Subscription {
+	subs := make(map[string]kafka.Subscription, len(subscriptions))
+	for _, sub := range subscriptions {
+		subs[sub.Topic] = sub
+	}
+	return subs
+}
+
+// stopper ends a Start loop on Stop without cancelling the context messages
+// in flight are handled under.
+type stopper struct {
+	once     sync.Once
+	stopping chan struct{}
+	done     chan struct{}
+}
+
+func newStopper() stopper {
+	return stopper{
+		stopping: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// fetching returns a context that ends when ctx does or Stop is called.
+// Start takes new messages only while it is alive and calls the returned
+// function when it returns.
+func (s *stopper) fetching(ctx context.Context) (context.Context, func()) {
+	fetchCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-s.stopping:
+			cancel()
+		case <-fetchCtx.Done():
+		}
+	}()
+	return fetchCtx, func() {
+		cancel()
+		close(s.done)
+	}
+}
+
+func (s *stopper) stop(ctx context.Context, op string) error {
+	s.once.Do(func() { close(s.stopping) })
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", op, ctx.Err())
+	}
+}
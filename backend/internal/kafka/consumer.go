@@ -1,127 +1,323 @@
 package kafka
 
 import (
-	"L0-wbtech/internal/model"
-	"L0-wbtech/internal/service"
+	"L0-wbtech/internal/config"
 	"L0-wbtech/pkg/logger/sl"
 	"context"
-	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/segmentio/kafka-go"
 )
 
+const maxRetryBackoff = time.Minute
+
+type ErrorPolicy string
+
+const (
+	// PolicySkip logs the failed message and commits it.
+	PolicySkip ErrorPolicy = "skip"
+	// PolicyRetry retries the handler with exponential backoff, then skips
+	// the message once MaxRetries is exhausted. Zero retries means forever.
+	PolicyRetry ErrorPolicy = "retry"
+	// PolicyStop leaves the message uncommitted and stops the subscription,
+	// so it is redelivered on the next start.
+	PolicyStop ErrorPolicy = "stop"
+)
+
+type Subscription struct {
+	Topic        string
+	Handler      Handler
+	Policy       ErrorPolicy
+	MaxRetries   int
+	RetryBackoff time.Duration
+}
+
+// Reader is the part of *kafka.Reader the consumer relies on, so that tests
+// can drive it from an in-memory log.
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// ReaderFactory opens a group reader for one topic.
+type ReaderFactory func(topic string) Reader
+
 type Consumer struct {
-	reader       *kafka.Reader
-	orderService service.Service
-	log          *slog.Logger
+	subscriptions []*subscription
+	log           *slog.Logger
+
+	stopOnce sync.Once
+	stopping chan struct{}
+	done     chan struct{}
+}
+
+type subscription struct {
+	Subscription
+	reader Reader
 }
 
 func NewConsumer(
-	brokers []string,
-	topic string,
-	groupID string,
-	service service.Service,
+	cfg config.KafkaConfig,
+	subscriptions []Subscription,
 	log *slog.Logger,
-) *Consumer {
+) (*Consumer, error) {
+	const op = "kafka.NewConsumer"
+
 	log.Info("Creating Kafka consumer",
-		"brokers", brokers,
-		"topic", topic,
-		"groupID", groupID)
-
-	return &Consumer{
-		reader: kafka.NewReader(kafka.ReaderConfig{
-			Brokers:        brokers,
-			Topic:          topic,
-			GroupID:        groupID,
-			MinBytes:       10e3,
-			MaxBytes:       10e6,
-			MaxWait:        30 * time.Second,
-			StartOffset:    kafka.LastOffset,
-			CommitInterval: 0,
-			Dialer: &kafka.Dialer{
-				Timeout:   60 * time.Second,
-				DualStack: true,
-			},
-		}),
-		orderService: service,
-		log:          log,
+		"brokers", cfg.Brokers,
+		"groupID", cfg.GroupID,
+		"start_offset", cfg.Reader.StartOffset,
+		"tls", cfg.TLS.Enabled,
+		"sasl", cfg.SASL.Mechanism)
+
+	dialer, err := newDialer(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// A timestamp start offset is applied by PrepareGroup; partitions it
+	// did not see start from the beginning.
+	startOffset := kafka.FirstOffset
+	if cfg.Reader.StartOffset == "latest" {
+		startOffset = kafka.LastOffset
 	}
+
+	r := cfg.Reader
+	return NewConsumerWithReaders(subscriptions, func(topic string) Reader {
+		return kafka.NewReader(kafka.ReaderConfig{
+			Brokers:           cfg.Brokers,
+			Topic:             topic,
+			GroupID:           cfg.GroupID,
+			MinBytes:          r.MinBytes,
+			MaxBytes:          r.MaxBytes,
+			MaxWait:           r.MaxWait,
+			QueueCapacity:     r.QueueCapacity,
+			StartOffset:       startOffset,
+			CommitInterval:    r.CommitInterval,
+			HeartbeatInterval: r.HeartbeatInterval,
+			SessionTimeout:    r.SessionTimeout,
+			RebalanceTimeout:  r.RebalanceTimeout,
+			Dialer:            dialer,
+		})
+	}, log), nil
 }
 
-func (c *Consumer) Start(ctx context.Context) {
+// NewConsumerWithReaders builds a consumer whose readers come from
+// newReader instead of a Kafka cluster.
+func NewConsumerWithReaders(subscriptions []Subscription, newReader ReaderFactory, log *slog.Logger) *Consumer {
+	c := &Consumer{
+		log:      log,
+		stopping: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	for _, sub := range subscriptions {
+		log.Info("Subscribing to topic",
+			"topic", sub.Topic,
+			"policy", sub.Policy)
+
+		c.subscriptions = append(c.subscriptions, &subscription{
+			Subscription: sub,
+			reader:       newReader(sub.Topic),
+		})
+	}
+
+	return c
+}
+
+func (c *Consumer) Topics() []string {
+	topics := make([]string, 0, len(c.subscriptions))
+	for _, sub := range c.subscriptions {
+		topics = append(topics, sub.Topic)
+	}
+	return topics
+}
 
+// Start consumes every subscription in its own goroutine and blocks until
+// all of them have stopped. Messages are handled and committed under ctx,
+// so cancelling it abandons them; Stop lets them finish instead. A
+// subscription stopped by its error policy stops the others the way Stop
+// does, so the consumer fails as a whole; the returned error names it.
+func (c *Consumer) Start(ctx context.Context) error {
 	const op = "kafka.Consumer.Start"
 	log := c.log.With(slog.String("op", op))
+	defer close(c.done)
 
-	log.Info("Starting Kafka consumer")
+	log.Info("Starting Kafka consumer", "topics", c.Topics())
 
-	for {
+	fetchCtx, stopFetching := context.WithCancel(ctx)
+	defer stopFetching()
+	go func() {
 		select {
-		case <-ctx.Done():
-			log.Info("Stopping Kafka consumer")
-			return
-		default:
-			msg, err := c.reader.FetchMessage(ctx)
-			if err != nil {
-				if ctx.Err() != nil {
-					return
-				}
-				log.Error("Fetch error", sl.Err(err))
-				continue
+		case <-c.stopping:
+			stopFetching()
+		case <-fetchCtx.Done():
+		}
+	}()
+
+	errs := make([]error, len(c.subscriptions))
+	var wg sync.WaitGroup
+	for i, sub := range c.subscriptions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if errs[i] = c.consume(ctx, fetchCtx, sub); errs[i] != nil {
+				stopFetching()
 			}
+		}()
+	}
+	wg.Wait()
 
-			c.processMessage(ctx, msg)
-		}
+	log.Info("Kafka consumer stopped")
+
+	if err := stdErrors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
+	return nil
 }
 
-func (c *Consumer) processMessage(ctx context.Context, msg kafka.Message) {
+// Stop stops fetching new messages and waits until the messages in flight
+// are handled and committed and Start has returned, or ctx is done.
+func (c *Consumer) Stop(ctx context.Context) error {
+	const op = "kafka.Consumer.Stop"
 
-	const op = "kafka.Consumer.processMessage"
-	log := c.log.With(slog.String("op", op))
+	c.stopOnce.Do(func() { close(c.stopping) })
 
-	var order model.Order
-	if err := json.Unmarshal(msg.Value, &order); err != nil {
-		log.Error("Unmarshal error", sl.Err(err), "message", string(msg.Value))
-		if err := c.reader.CommitMessages(ctx, msg); err != nil {
-			log.Error("Commit error", sl.Err(err))
-		}
-		return
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", op, ctx.Err())
 	}
+}
+
+func (c *Consumer) consume(ctx, fetchCtx context.Context, sub *subscription) error {
+	const op = "kafka.Consumer.consume"
+	log := c.log.With(slog.String("op", op), slog.String("topic", sub.Topic))
 
-	if err := order.Validate(); err != nil {
-		log.Error("Invalid order data", sl.Err(err), "order_uid", order.OrderUID)
-		if err := c.reader.CommitMessages(ctx, msg); err != nil {
-			log.Error("Commit error", sl.Err(err))
+	// A reader may still hand out a buffered message after fetchCtx ends,
+	// so check it before every fetch.
+	for fetchCtx.Err() == nil {
+		msg, err := sub.reader.FetchMessage(fetchCtx)
+		if err != nil {
+			if fetchCtx.Err() != nil || stdErrors.Is(err, io.EOF) {
+				break
+			}
+			log.Error("Fetch error", sl.Err(err))
+			continue
 		}
-		return
-	}
 
-	if order.OrderUID == "" {
-		log.Error("Received order with empty UID")
-		if err := c.reader.CommitMessages(ctx, msg); err != nil {
-			log.Error("Commit error", sl.Err(err))
+		if err := c.processMessage(ctx, sub, msg); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Warn("Subscription stopped by error policy", sl.Err(err))
+			return fmt.Errorf("topic %s: %w", sub.Topic, err)
 		}
-		return
 	}
 
-	log = log.With("order_uid", order.OrderUID)
-	log.Info("Processing order")
+	log.Info("Stopping subscription")
+	return nil
+}
+
+// processMessage runs the handler under the subscription error policy and
+// commits the message unless the policy keeps it. A non-nil result stops
+// the subscription.
+func (c *Consumer) processMessage(ctx context.Context, sub *subscription, msg kafka.Message) error {
+	const op = "kafka.Consumer.processMessage"
+	log := c.log.With(
+		slog.String("op", op),
+		slog.String("topic", msg.Topic),
+		slog.Int("partition", msg.Partition),
+		slog.Int64("offset", msg.Offset),
+	)
 
-	if err := c.orderService.CreateOrder(ctx, &order); err != nil {
-		log.Error("Failed to create order", sl.Err(err))
-		return
+	err := sub.Handle(ctx, msg, c.log)
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case stdErrors.Is(err, ErrPermanent):
+		log.Error("Dropping unprocessable message", sl.Err(err), "message", string(msg.Value))
+	case !sub.Commits(err):
+		log.Error("Failed to handle message", sl.Err(err))
+		return err
+	default:
+		log.Error("Failed to handle message, skipping", sl.Err(err))
 	}
 
-	if err := c.reader.CommitMessages(ctx, msg); err != nil {
+	if err := sub.reader.CommitMessages(ctx, msg); err != nil {
 		log.Error("Commit error", sl.Err(err))
 	} else {
 		log.Info("Message committed")
 	}
+	return nil
+}
+
+// Handle runs the handler under the subscription retry policy. Sources
+// other than Kafka use it to process messages the way the consumer does.
+func (sub Subscription) Handle(ctx context.Context, msg kafka.Message, log *slog.Logger) error {
+	err := sub.Handler.Handle(ctx, msg)
+	if err == nil || sub.Policy != PolicyRetry || stdErrors.Is(err, ErrPermanent) {
+		return err
+	}
+
+	backoff := sub.RetryBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	for attempt := 1; sub.MaxRetries == 0 || attempt <= sub.MaxRetries; attempt++ {
+		log.Warn("Retrying message",
+			slog.String("topic", msg.Topic),
+			slog.Int64("offset", msg.Offset),
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			sl.Err(err))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		if err = sub.Handler.Handle(ctx, msg); err == nil || stdErrors.Is(err, ErrPermanent) {
+			return err
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+
+	return err
+}
+
+// Commits reports whether a message whose handling ended with err is done
+// with, either processed or given up on, rather than kept for redelivery.
+func (sub Subscription) Commits(err error) bool {
+	return err == nil || stdErrors.Is(err, ErrPermanent) || sub.Policy != PolicyStop
+}
+
+func contentType(msg kafka.Message) string {
+	for _, h := range msg.Headers {
+		if strings.EqualFold(h.Key, "content-type") {
+			return string(h.Value)
+		}
+	}
+	return ""
 }
 
 func (c *Consumer) Close() error {
-	return c.reader.Close()
+	var errs []error
+	for _, sub := range c.subscriptions {
+		if err := sub.reader.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return stdErrors.Join(errs...)
 }
@@ -0,0 +1,191 @@
+package kafka_test
+
+import (
+	"L0-wbtech/internal/kafka"
+	"L0-wbtech/internal/kafka/kafkatest"
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+const testGroup = "test-group"
+
+func startConsumer(t *testing.T, broker *kafkatest.Log, subs ...kafka.Subscription) (*kafka.Consumer, <-chan error) {
+	t.Helper()
+
+	c := kafka.NewConsumerWithReaders(subs, broker.ReaderFactory(testGroup), slog.New(slog.DiscardHandler))
+	done := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { done <- c.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		c.Close()
+	})
+	return c, done
+}
+
+func wait(t *testing.T, done <-chan error) error {
+	t.Helper()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("consumer did not stop")
+		return nil
+	}
+}
+
+func TestConsumerStopPolicyStopsOtherTopics(t *testing.T) {
+	broker := kafkatest.NewLog(1)
+	broken := kafka.Subscription{
+		Topic:  "broken",
+		Policy: kafka.PolicyStop,
+		Handler: kafka.HandlerFunc(func(context.Context, kafkago.Message) error {
+			return stdErrors.New("database is down")
+		}),
+	}
+	healthy := kafka.Subscription{
+		Topic:   "healthy",
+		Policy:  kafka.PolicySkip,
+		Handler: kafka.HandlerFunc(func(context.Context, kafkago.Message) error { return nil }),
+	}
+
+	_, done := startConsumer(t, broker, broken, healthy)
+	broker.Produce("broken", []byte("key"), []byte("payload"))
+
+	// Start returns while the healthy topic has nothing to fail on.
+	err := wait(t, done)
+	if err == nil || !strings.Contains(err.Error(), "topic broken") {
+		t.Fatalf("Start returned %v, want the error of topic broken", err)
+	}
+	if lag := broker.Lag(testGroup, "broken"); lag != 1 {
+		t.Errorf("broken topic lag %d, want the failed message left uncommitted", lag)
+	}
+}
+
+// flaky fails every message with err until it has been handled fails times.
+type flaky struct {
+	mu       sync.Mutex
+	err      error
+	fails    int
+	attempts map[int64]int
+}
+
+func (h *flaky) Handle(_ context.Context, msg kafkago.Message) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.attempts[msg.Offset]++
+	if h.fails < 0 || h.attempts[msg.Offset] <= h.fails {
+		return h.err
+	}
+	return nil
+}
+
+func (h *flaky) attemptsOf(offset int64) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.attempts[offset]
+}
+
+func TestConsumerErrorPolicies(t *testing.T) {
+	const messages = 2
+
+	errDown := stdErrors.New("database is down")
+	errBad := fmt.Errorf("decode order: %w", kafka.ErrPermanent)
+
+	for _, tc := range []struct {
+		name       string
+		policy     kafka.ErrorPolicy
+		maxRetries int
+		err        error
+		// fails is how many attempts fail per message; -1 fails them all.
+		fails        int
+		wantAttempts int
+		wantStopped  bool
+	}{
+		{"skip commits the failed message", kafka.PolicySkip, 0, errDown, -1, 1, false},
+		{"retry until the handler recovers", kafka.PolicyRetry, 3, errDown, 2, 3, false},
+		{"retry gives up and skips", kafka.PolicyRetry, 2, errDown, -1, 3, false},
+		{"retry drops a permanent error at once", kafka.PolicyRetry, 3, errBad, -1, 1, false},
+		{"stop keeps the message for redelivery", kafka.PolicyStop, 0, errDown, -1, 1, true},
+		{"stop drops a permanent error", kafka.PolicyStop, 0, errBad, -1, 1, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			broker := kafkatest.NewLog(1)
+			handler := &flaky{err: tc.err, fails: tc.fails, attempts: make(map[int64]int)}
+			sub := kafka.Subscription{
+				Topic:        "orders",
+				Handler:      handler,
+				Policy:       tc.policy,
+				MaxRetries:   tc.maxRetries,
+				RetryBackoff: time.Millisecond,
+			}
+			c, done := startConsumer(t, broker, sub)
+			for range messages {
+				broker.Produce("orders", nil, []byte("payload"))
+			}
+
+			if !tc.wantStopped {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := broker.WaitCommitted(ctx, testGroup, "orders"); err != nil {
+					t.Fatal(err)
+				}
+				if err := c.Stop(ctx); err != nil {
+					t.Fatal(err)
+				}
+				if err := wait(t, done); err != nil {
+					t.Errorf("Start returned %v", err)
+				}
+				for offset := range int64(messages) {
+					if n := handler.attemptsOf(offset); n != tc.wantAttempts {
+						t.Errorf("message %d handled %d times, want %d", offset, n, tc.wantAttempts)
+					}
+				}
+				return
+			}
+
+			if err := wait(t, done); err == nil || !stdErrors.Is(err, tc.err) {
+				t.Fatalf("Start returned %v, want %v", err, tc.err)
+			}
+			if lag := broker.Lag(testGroup, "orders"); lag != messages {
+				t.Errorf("lag %d after the stop, want %d", lag, messages)
+			}
+			if n := handler.attemptsOf(1); n != 0 {
+				t.Errorf("message after the failed one handled %d times, want 0", n)
+			}
+			if n := handler.attemptsOf(0); n != tc.wantAttempts {
+				t.Errorf("failed message handled %d times, want %d", n, tc.wantAttempts)
+			}
+
+			// The next start picks up the message the stop left behind.
+			handler.mu.Lock()
+			handler.fails = 0
+			handler.mu.Unlock()
+			c.Close()
+			restarted, redone := startConsumer(t, broker, sub)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := broker.WaitCommitted(ctx, testGroup, "orders"); err != nil {
+				t.Fatal(err)
+			}
+			if err := restarted.Stop(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if err := wait(t, redone); err != nil {
+				t.Errorf("restarted Start returned %v", err)
+			}
+			if n := handler.attemptsOf(0); n != tc.wantAttempts+1 {
+				t.Errorf("kept message handled %d times in all, want %d", n, tc.wantAttempts+1)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package kafka
+
+import (
+	"L0-wbtech/internal/codec"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/service"
+	"L0-wbtech/pkg/errors"
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ErrPermanent marks failures that retrying cannot fix, such as malformed
+// payloads. Such messages are always logged and committed.
+var ErrPermanent = stdErrors.New("permanent message error")
+
+type Handler interface {
+	Handle(ctx context.Context, msg kafka.Message) error
+}
+
+type HandlerFunc func(ctx context.Context, msg kafka.Message) error
+
+func (f HandlerFunc) Handle(ctx context.Context, msg kafka.Message) error {
+	return f(ctx, msg)
+}
+
+type HandlerKind string
+
+const (
+	HandlerCreate   HandlerKind = "create"
+	HandlerCreateV2 HandlerKind = "create_v2"
+	HandlerStatus   HandlerKind = "status"
+	HandlerCancel   HandlerKind = "cancel"
+)
+
+// NewHandler builds the handler of the given kind on top of svc.
+func NewHandler(kind HandlerKind, decoder codec.Decoder, svc service.Service, log *slog.Logger) (Handler, error) {
+	const op = "kafka.NewHandler"
+
+	switch kind {
+	case HandlerCreate:
+		return createHandler(svc, log, func(ctx context.Context, contentType string, data []byte) (*model.Order, error) {
+			var order model.Order
+			if err := decoder.Decode(ctx, contentType, data, &order); err != nil {
+				return nil, err
+			}
+			return &order, nil
+		}), nil
+	case HandlerCreateV2:
+		return createHandler(svc, log, func(ctx context.Context, contentType string, data []byte) (*model.Order, error) {
+			var order orderV2
+			if err := decoder.Decode(ctx, contentType, data, &order); err != nil {
+				return nil, err
+			}
+			return order.toModel(), nil
+		}), nil
+	case HandlerStatus:
+		return statusHandler(decoder, svc, log), nil
+	case HandlerCancel:
+		return cancelHandler(decoder, svc, log), nil
+	default:
+		return nil, fmt.Errorf("%s: unknown handler %q", op, kind)
+	}
+}
+
+type orderDecodeFunc func(ctx context.Context, contentType string, data []byte) (*model.Order, error)
+
+func createHandler(svc service.Service, log *slog.Logger, decode orderDecodeFunc) Handler {
+	return HandlerFunc(func(ctx context.Context, msg kafka.Message) error {
+		const op = "kafka.createHandler"
+
+		order, err := decode(ctx, contentType(msg), msg.Value)
+		if err != nil {
+			return permanent(fmt.Errorf("%s: decode: %w", op, err))
+		}
+
+		if err := order.Validate(); err != nil {
+			return permanent(fmt.Errorf("%s: invalid order %q: %w", op, order.OrderUID, err))
+		}
+
+		log.Info("Processing order", "op", op, "topic", msg.Topic, "order_uid", order.OrderUID)
+
+		if err := svc.CreateOrder(ctx, order); err != nil {
+			if stdErrors.Is(err, errors.ErrInvalidInput) {
+				return permanent(fmt.Errorf("%s: %w", op, err))
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+	})
+}
+
+func statusHandler(decoder codec.Decoder, svc service.Service, log *slog.Logger) Handler {
+	return HandlerFunc(func(ctx context.Context, msg kafka.Message) error {
+		const op = "kafka.statusHandler"
+
+		var event StatusEvent
+		if err := decoder.Decode(ctx, contentType(msg), msg.Value, &event); err != nil {
+			return permanent(fmt.Errorf("%s: decode: %w", op, err))
+		}
+		if event.OrderUID == "" || event.Status == "" {
+			return permanent(fmt.Errorf("%s: order_uid and status are required", op))
+		}
+
+		log.Info("Processing status update", "op", op, "order_uid", event.OrderUID, "status", event.Status)
+
+		_, err := svc.UpdateStatus(ctx, event.OrderUID, model.StatusChange{
+			To:        model.OrderStatus(event.Status),
+			Reason:    event.Reason,
+			Actor:     event.Actor,
+			Source:    "kafka",
+			ChangedAt: event.ChangedAt,
+		})
+		if err != nil {
+			if stdErrors.Is(err, errors.ErrInvalidInput) || stdErrors.Is(err, errors.ErrInvalidTransition) {
+				return permanent(fmt.Errorf("%s: %w", op, err))
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+	})
+}
+
+func cancelHandler(decoder codec.Decoder, svc service.Service, log *slog.Logger) Handler {
+	return HandlerFunc(func(ctx context.Context, msg kafka.Message) error {
+		const op = "kafka.cancelHandler"
+
+		var event CancelEvent
+		if err := decoder.Decode(ctx, contentType(msg), msg.Value, &event); err != nil {
+			return permanent(fmt.Errorf("%s: decode: %w", op, err))
+		}
+		if event.OrderUID == "" {
+			return permanent(fmt.Errorf("%s: order_uid is required", op))
+		}
+
+		log.Info("Processing cancellation", "op", op, "order_uid", event.OrderUID)
+
+		_, err := svc.CancelOrder(ctx, event.OrderUID, model.Cancellation{
+			Reason: event.Reason,
+			Actor:  event.Actor,
+			Source: "kafka",
+			At:     event.CancelledAt,
+		})
+		if err != nil {
+			if stdErrors.Is(err, errors.ErrInvalidInput) || stdErrors.Is(err, errors.ErrInvalidTransition) {
+				return permanent(fmt.Errorf("%s: %w", op, err))
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+	})
+}
+
+func permanent(err error) error {
+	return fmt.Errorf("%w: %w", ErrPermanent, err)
+}
@@ -0,0 +1,181 @@
+// Package kafkatest provides an in-memory partitioned log that stands in for
+// a Kafka cluster in tests. It keeps per-group committed offsets, so readers
+// opened after a restart resume from the last commit and see uncommitted
+// messages again, like a real consumer group.
+package kafkatest
+
+import (
+	"L0-wbtech/internal/kafka"
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// ErrUnavailable is returned by writes while the log plays a broker that is
+// down.
+var ErrUnavailable = stdErrors.New("kafkatest: broker unavailable")
+
+type Log struct {
+	mu         sync.Mutex
+	changed    chan struct{}
+	partitions int
+	topics     map[string][][]kafkago.Message
+	committed  map[offsetKey]int64
+	next       int
+	failWrites int
+}
+
+type offsetKey struct {
+	group     string
+	topic     string
+	partition int
+}
+
+func NewLog(partitions int) *Log {
+	if partitions <= 0 {
+		partitions = 1
+	}
+	return &Log{
+		changed:    make(chan struct{}),
+		partitions: partitions,
+		topics:     make(map[string][][]kafkago.Message),
+		committed:  make(map[offsetKey]int64),
+	}
+}
+
+// Produce appends a message to topic. Messages with a key always land on
+// the same partition; messages without one are spread round-robin.
+func (l *Log) Produce(topic string, key, value []byte, headers ...kafkago.Header) kafkago.Message {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.append(kafkago.Message{Topic: topic, Key: key, Value: value, Headers: headers})
+}
+
+// WriteMessages makes the log usable as a producer. Every message must name
+// its topic.
+func (l *Log) WriteMessages(_ context.Context, msgs ...kafkago.Message) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.failWrites > 0 {
+		l.failWrites--
+		return ErrUnavailable
+	}
+	for _, msg := range msgs {
+		if msg.Topic == "" {
+			return fmt.Errorf("kafkatest: message without topic")
+		}
+	}
+	for _, msg := range msgs {
+		l.append(msg)
+	}
+	return nil
+}
+
+// FailWrites makes the next n calls of WriteMessages fail with
+// ErrUnavailable without writing anything.
+func (l *Log) FailWrites(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.failWrites = n
+}
+
+func (l *Log) append(msg kafkago.Message) kafkago.Message {
+	parts := l.topic(msg.Topic)
+
+	var p int
+	if len(msg.Key) > 0 {
+		h := fnv.New32a()
+		_, _ = h.Write(msg.Key)
+		p = int(h.Sum32() % uint32(l.partitions))
+	} else {
+		p = l.next % l.partitions
+		l.next++
+	}
+
+	msg.Partition = p
+	msg.Offset = int64(len(parts[p]))
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	parts[p] = append(parts[p], msg)
+	l.notify()
+	return msg
+}
+
+func (l *Log) topic(name string) [][]kafkago.Message {
+	parts, ok := l.topics[name]
+	if !ok {
+		parts = make([][]kafkago.Message, l.partitions)
+		l.topics[name] = parts
+	}
+	return parts
+}
+
+// notify wakes everyone waiting for a change. Callers hold l.mu.
+func (l *Log) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// Messages returns every message of topic, partition by partition.
+func (l *Log) Messages(topic string) []kafkago.Message {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var out []kafkago.Message
+	for _, part := range l.topics[topic] {
+		out = append(out, part...)
+	}
+	return out
+}
+
+// Lag returns how many messages of topic group has not committed yet.
+func (l *Log) Lag(group, topic string) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lag(group, topic)
+}
+
+func (l *Log) lag(group, topic string) int64 {
+	var lag int64
+	for p, part := range l.topics[topic] {
+		lag += int64(len(part)) - l.committed[offsetKey{group, topic, p}]
+	}
+	return lag
+}
+
+// WaitCommitted blocks until group has committed every message produced so
+// far to topics.
+func (l *Log) WaitCommitted(ctx context.Context, group string, topics ...string) error {
+	for {
+		l.mu.Lock()
+		var lag int64
+		for _, topic := range topics {
+			lag += l.lag(group, topic)
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		if lag == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("kafkatest: %d messages still uncommitted: %w", lag, ctx.Err())
+		case <-changed:
+		}
+	}
+}
+
+// ReaderFactory returns readers that consume as group.
+func (l *Log) ReaderFactory(group string) kafka.ReaderFactory {
+	return func(topic string) kafka.Reader {
+		return l.Reader(group, topic)
+	}
+}
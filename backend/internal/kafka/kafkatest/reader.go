@@ -0,0 +1,112 @@
+package kafkatest
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Reader consumes one topic as a member of a group. It starts at the
+// group's committed offsets and fetches partitions round-robin.
+type Reader struct {
+	log   *Log
+	group string
+	topic string
+
+	mu       sync.Mutex
+	position map[int]int64
+	next     int
+	closed   bool
+}
+
+func (l *Log) Reader(group, topic string) *Reader {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.topic(topic)
+	position := make(map[int]int64, l.partitions)
+	for p := range l.partitions {
+		position[p] = l.committed[offsetKey{group, topic, p}]
+	}
+
+	return &Reader{
+		log:      l,
+		group:    group,
+		topic:    topic,
+		position: position,
+	}
+}
+
+// FetchMessage returns the next message, blocking until one is produced,
+// ctx is done or the reader is closed.
+func (r *Reader) FetchMessage(ctx context.Context) (kafkago.Message, error) {
+	for {
+		r.log.mu.Lock()
+		msg, ok, closed := r.poll()
+		changed := r.log.changed
+		r.log.mu.Unlock()
+
+		if closed {
+			return kafkago.Message{}, io.EOF
+		}
+		if ok {
+			return msg, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return kafkago.Message{}, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// poll takes the next available message. Callers hold r.log.mu.
+func (r *Reader) poll() (kafkago.Message, bool, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return kafkago.Message{}, false, true
+	}
+
+	parts := r.log.topics[r.topic]
+	for range len(parts) {
+		p := r.next % len(parts)
+		r.next++
+		if pos := r.position[p]; pos < int64(len(parts[p])) {
+			r.position[p] = pos + 1
+			return parts[p][pos], true, false
+		}
+	}
+	return kafkago.Message{}, false, false
+}
+
+// CommitMessages advances the group's offsets past msgs.
+func (r *Reader) CommitMessages(_ context.Context, msgs ...kafkago.Message) error {
+	r.log.mu.Lock()
+	defer r.log.mu.Unlock()
+
+	for _, msg := range msgs {
+		key := offsetKey{r.group, msg.Topic, msg.Partition}
+		if next := msg.Offset + 1; next > r.log.committed[key] {
+			r.log.committed[key] = next
+		}
+	}
+	r.log.notify()
+	return nil
+}
+
+func (r *Reader) Close() error {
+	r.log.mu.Lock()
+	defer r.log.mu.Unlock()
+
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+
+	r.log.notify()
+	return nil
+}
@@ -0,0 +1,67 @@
+package kafka
+
+import (
+	"L0-wbtech/internal/model"
+	"time"
+)
+
+type StatusEvent struct {
+	OrderUID  string    `json:"order_uid"`
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason"`
+	Actor     string    `json:"actor"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type CancelEvent struct {
+	OrderUID    string    `json:"order_uid"`
+	Reason      string    `json:"reason"`
+	Actor       string    `json:"actor"`
+	CancelledAt time.Time `json:"cancelled_at"`
+}
+
+// orderV2 is the payload of the v2 orders topic. It carries the same data as
+// model.Order, with customer and sharding attributes grouped into objects.
+type orderV2 struct {
+	UID               string         `json:"uid"`
+	TrackNumber       string         `json:"track_number"`
+	Entry             string         `json:"entry"`
+	Customer          customerV2     `json:"customer"`
+	Delivery          model.Delivery `json:"delivery"`
+	Payment           model.Payment  `json:"payment"`
+	Items             []model.Item   `json:"items"`
+	InternalSignature string         `json:"internal_signature"`
+	DeliveryService   string         `json:"delivery_service"`
+	Shard             shardV2        `json:"shard"`
+	CreatedAt         time.Time      `json:"created_at"`
+}
+
+type customerV2 struct {
+	ID     string `json:"id"`
+	Locale string `json:"locale"`
+}
+
+type shardV2 struct {
+	Key    string `json:"key"`
+	OofKey string `json:"oof_key"`
+	SmID   int    `json:"sm_id"`
+}
+
+func (o *orderV2) toModel() *model.Order {
+	return &model.Order{
+		OrderUID:          o.UID,
+		TrackNumber:       o.TrackNumber,
+		Entry:             o.Entry,
+		Delivery:          o.Delivery,
+		Payment:           o.Payment,
+		Items:             o.Items,
+		Locale:            o.Customer.Locale,
+		InternalSignature: o.InternalSignature,
+		CustomerID:        o.Customer.ID,
+		DeliveryService:   o.DeliveryService,
+		Shardkey:          o.Shard.Key,
+		SmID:              o.Shard.SmID,
+		DateCreated:       o.CreatedAt,
+		OofShard:          o.Shard.OofKey,
+	}
+}
@@ -0,0 +1,181 @@
+package kafka
+
+import (
+	"L0-wbtech/internal/config"
+	"L0-wbtech/pkg/logger/sl"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const brokerRetryInterval = time.Second
+
+// PrepareGroup waits until the brokers answer, then applies a timestamp
+// start offset: partitions of topics the group has no committed offset for
+// get one at the first message written since that time, or at the end of
+// the partition when there is none. Other start offsets need no
+// preparation. Bound the wait with ctx.
+func PrepareGroup(ctx context.Context, cfg config.KafkaConfig, topics []string, log *slog.Logger) error {
+	const op = "kafka.PrepareGroup"
+	log = log.With(slog.String("op", op))
+
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	client := &kafka.Client{
+		Addr:      kafka.TCP(cfg.Brokers...),
+		Transport: transport,
+	}
+
+	partitions, err := waitForTopics(ctx, client, topics, log)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	at, ok := cfg.Reader.StartAt()
+	if !ok {
+		return nil
+	}
+
+	for _, topic := range topics {
+		n, err := seekGroup(ctx, client, cfg.GroupID, topic, partitions[topic], at)
+		if err != nil {
+			return fmt.Errorf("%s: topic %s: %w", op, topic, err)
+		}
+		if n > 0 {
+			log.Info("Group offsets set from start time",
+				"topic", topic,
+				"partitions", n,
+				"start_at", at)
+		}
+	}
+	return nil
+}
+
+// waitForTopics retries until the brokers return metadata for topics and
+// returns their partitions. A topic that does not exist yet has none.
+func waitForTopics(ctx context.Context, client *kafka.Client, topics []string, log *slog.Logger) (map[string][]int, error) {
+	for {
+		meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: topics})
+		if err == nil {
+			partitions := make(map[string][]int, len(meta.Topics))
+			for _, t := range meta.Topics {
+				for _, p := range t.Partitions {
+					partitions[t.Name] = append(partitions[t.Name], p.ID)
+				}
+			}
+			return partitions, nil
+		}
+
+		log.Warn("Kafka brokers unavailable, retrying", sl.Err(err))
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for brokers: %w", err)
+		case <-time.After(brokerRetryInterval):
+		}
+	}
+}
+
+// seekGroup commits offsets at time at for the partitions without a
+// committed offset and returns how many it set.
+func seekGroup(ctx context.Context, client *kafka.Client, groupID, topic string, partitions []int, at time.Time) (int, error) {
+	if len(partitions) == 0 {
+		return 0, nil
+	}
+
+	committed, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: groupID,
+		Topics:  map[string][]int{topic: partitions},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("fetch offsets: %w", err)
+	}
+	if committed.Error != nil {
+		return 0, fmt.Errorf("fetch offsets: %w", committed.Error)
+	}
+
+	var fresh []kafka.OffsetRequest
+	for _, p := range committed.Topics[topic] {
+		if p.Error != nil {
+			return 0, fmt.Errorf("fetch offset of partition %d: %w", p.Partition, p.Error)
+		}
+		if p.CommittedOffset < 0 {
+			fresh = append(fresh, kafka.TimeOffsetOf(p.Partition, at))
+		}
+	}
+	if len(fresh) == 0 {
+		return 0, nil
+	}
+
+	offsets, err := listOffsets(ctx, client, topic, fresh)
+	if err != nil {
+		return 0, err
+	}
+
+	// Past the last message there is no offset for the time; start at the
+	// end instead.
+	var ends []kafka.OffsetRequest
+	for p, offset := range offsets {
+		if offset < 0 {
+			ends = append(ends, kafka.LastOffsetOf(p))
+		}
+	}
+	if len(ends) > 0 {
+		last, err := listOffsets(ctx, client, topic, ends)
+		if err != nil {
+			return 0, err
+		}
+		for p, offset := range last {
+			offsets[p] = offset
+		}
+	}
+
+	commits := make([]kafka.OffsetCommit, 0, len(offsets))
+	for p, offset := range offsets {
+		commits = append(commits, kafka.OffsetCommit{Partition: p, Offset: offset})
+	}
+
+	// No member holds the group yet, so commit outside any generation.
+	res, err := client.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      groupID,
+		GenerationID: -1,
+		Topics:       map[string][]kafka.OffsetCommit{topic: commits},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("commit offsets: %w", err)
+	}
+	for _, p := range res.Topics[topic] {
+		if p.Error != nil {
+			return 0, fmt.Errorf("commit offset of partition %d: %w", p.Partition, p.Error)
+		}
+	}
+	return len(commits), nil
+}
+
+// listOffsets resolves the requests to one offset per partition, -1 where
+// the broker has none.
+func listOffsets(ctx context.Context, client *kafka.Client, topic string, requests []kafka.OffsetRequest) (map[int]int64, error) {
+	res, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list offsets: %w", err)
+	}
+
+	offsets := make(map[int]int64, len(requests))
+	for _, p := range res.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("list offsets of partition %d: %w", p.Partition, p.Error)
+		}
+		offset := p.LastOffset
+		for o := range p.Offsets {
+			offset = o
+		}
+		offsets[p.Partition] = offset
+	}
+	return offsets, nil
+}
@@ -0,0 +1,29 @@
+package kafka
+
+import (
+	"L0-wbtech/internal/config"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// NewProducer returns a writer that hashes message keys onto partitions, so
+// messages sharing a key keep their relative order.
+func NewProducer(cfg config.KafkaConfig) (*kafka.Writer, error) {
+	const op = "kafka.NewProducer"
+
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &kafka.Writer{
+		Addr:                   kafka.TCP(cfg.Brokers...),
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		BatchTimeout:           10 * time.Millisecond,
+		AllowAutoTopicCreation: true,
+		Transport:              transport,
+	}, nil
+}
@@ -0,0 +1,159 @@
+package kafka_test
+
+import (
+	"L0-wbtech/internal/config"
+	"L0-wbtech/internal/kafka"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeKeyPair writes a self-signed certificate and its key as PEM files.
+func writeKeyPair(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tlsAndSASL is the security a transport was built with.
+type tlsAndSASL struct {
+	tls       *tls.Config
+	mechanism string
+}
+
+func TestTransportSecurity(t *testing.T) {
+	dir := t.TempDir()
+	caFile, _ := writeKeyPair(t, dir, "ca")
+	certFile, keyFile := writeKeyPair(t, dir, "client")
+	_, otherKey := writeKeyPair(t, dir, "other")
+	notPEM := filepath.Join(dir, "not.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.pem")
+
+	for _, tc := range []struct {
+		name string
+		tls  config.KafkaTLS
+		sasl config.KafkaSASL
+		// check inspects a transport that was built; nil expects an error.
+		check func(t *testing.T, tr tlsAndSASL)
+	}{
+		{"plaintext", config.KafkaTLS{}, config.KafkaSASL{}, func(t *testing.T, tr tlsAndSASL) {
+			if tr.tls != nil || tr.mechanism != "" {
+				t.Errorf("plaintext transport has TLS %v and SASL %q", tr.tls != nil, tr.mechanism)
+			}
+		}},
+		{"files ignored with tls disabled", config.KafkaTLS{CAFile: missing}, config.KafkaSASL{}, func(t *testing.T, tr tlsAndSASL) {
+			if tr.tls != nil {
+				t.Error("disabled TLS is configured")
+			}
+		}},
+		{"tls with system roots", config.KafkaTLS{Enabled: true, ServerName: "kafka.internal"}, config.KafkaSASL{}, func(t *testing.T, tr tlsAndSASL) {
+			if tr.tls == nil || tr.tls.MinVersion != tls.VersionTLS12 || tr.tls.ServerName != "kafka.internal" {
+				t.Fatalf("TLS config %+v, want TLS 1.2 or later for kafka.internal", tr.tls)
+			}
+			if tr.tls.RootCAs != nil || len(tr.tls.Certificates) != 0 || tr.tls.InsecureSkipVerify {
+				t.Error("TLS config has roots, client certificates or skips verification")
+			}
+		}},
+		{"tls with a ca file", config.KafkaTLS{Enabled: true, CAFile: caFile}, config.KafkaSASL{}, func(t *testing.T, tr tlsAndSASL) {
+			if tr.tls == nil || tr.tls.RootCAs == nil {
+				t.Error("CA file not trusted")
+			}
+		}},
+		{"tls with a client certificate", config.KafkaTLS{Enabled: true, CertFile: certFile, KeyFile: keyFile}, config.KafkaSASL{}, func(t *testing.T, tr tlsAndSASL) {
+			if tr.tls == nil || len(tr.tls.Certificates) != 1 {
+				t.Error("client certificate not loaded")
+			}
+		}},
+		{"tls missing ca file", config.KafkaTLS{Enabled: true, CAFile: missing}, config.KafkaSASL{}, nil},
+		{"tls ca file without certificates", config.KafkaTLS{Enabled: true, CAFile: notPEM}, config.KafkaSASL{}, nil},
+		{"tls certificate without key", config.KafkaTLS{Enabled: true, CertFile: certFile}, config.KafkaSASL{}, nil},
+		{"tls certificate with another key", config.KafkaTLS{Enabled: true, CertFile: certFile, KeyFile: otherKey}, config.KafkaSASL{}, nil},
+		{"tls missing certificate", config.KafkaTLS{Enabled: true, CertFile: missing, KeyFile: keyFile}, config.KafkaSASL{}, nil},
+
+		{"sasl plain", config.KafkaTLS{}, config.KafkaSASL{Mechanism: "plain", Username: "orders", Password: "secret"}, func(t *testing.T, tr tlsAndSASL) {
+			if tr.mechanism != "PLAIN" {
+				t.Errorf("SASL mechanism %q, want PLAIN", tr.mechanism)
+			}
+		}},
+		{"sasl scram-sha-256", config.KafkaTLS{}, config.KafkaSASL{Mechanism: "scram-sha-256", Username: "orders", Password: "secret"}, func(t *testing.T, tr tlsAndSASL) {
+			if tr.mechanism != "SCRAM-SHA-256" {
+				t.Errorf("SASL mechanism %q, want SCRAM-SHA-256", tr.mechanism)
+			}
+		}},
+		{"sasl scram-sha-512 over tls", config.KafkaTLS{Enabled: true}, config.KafkaSASL{Mechanism: "scram-sha-512", Username: "orders", Password: "secret"}, func(t *testing.T, tr tlsAndSASL) {
+			if tr.tls == nil || tr.mechanism != "SCRAM-SHA-512" {
+				t.Errorf("TLS %v with SASL %q, want TLS with SCRAM-SHA-512", tr.tls != nil, tr.mechanism)
+			}
+		}},
+		{"sasl unknown mechanism", config.KafkaTLS{}, config.KafkaSASL{Mechanism: "gssapi"}, nil},
+		{"sasl fails behind valid tls", config.KafkaTLS{Enabled: true}, config.KafkaSASL{Mechanism: "oauthbearer"}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tr, err := kafka.NewTransport(config.KafkaConfig{DialTimeout: time.Second, TLS: tc.tls, SASL: tc.sasl})
+			if tc.check == nil {
+				if err == nil {
+					t.Fatal("NewTransport accepted the settings, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewTransport: %v", err)
+			}
+			if tr.DialTimeout != time.Second {
+				t.Errorf("dial timeout %v, want %v", tr.DialTimeout, time.Second)
+			}
+			got := tlsAndSASL{tls: tr.TLS}
+			if tr.SASL != nil {
+				got.mechanism = tr.SASL.Name()
+			}
+			tc.check(t, got)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package kafka
+
+import (
+	"L0-wbtech/internal/codec"
+	"L0-wbtech/internal/config"
+	"L0-wbtech/internal/service"
+	"fmt"
+	"log/slog"
+)
+
+// BuildSubscriptions turns the topic configuration into subscriptions, each
+// with its own decoder, handler and error policy.
+func BuildSubscriptions(
+	cfg config.KafkaConfig,
+	schemas codec.SchemaSource,
+	svc service.Service,
+	log *slog.Logger,
+) ([]Subscription, error) {
+	const op = "kafka.BuildSubscriptions"
+
+	topics := cfg.Subscriptions()
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("%s: no topics configured", op)
+	}
+
+	seen := make(map[string]bool, len(topics))
+	subscriptions := make([]Subscription, 0, len(topics))
+	for _, topic := range topics {
+		if topic.Name == "" {
+			return nil, fmt.Errorf("%s: topic name is required", op)
+		}
+		if seen[topic.Name] {
+			return nil, fmt.Errorf("%s: topic %q is declared twice", op, topic.Name)
+		}
+		seen[topic.Name] = true
+
+		encoding := topic.Encoding
+		if encoding == "" {
+			encoding = cfg.Encoding
+		}
+		format, err := codec.ParseFormat(encoding)
+		if err != nil {
+			return nil, fmt.Errorf("%s: topic %q: %w", op, topic.Name, err)
+		}
+
+		schemaID := topic.SchemaID
+		if schemaID == 0 {
+			schemaID = cfg.SchemaID
+		}
+		decoder, err := codec.NewDecoder(format, schemas, schemaID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: topic %q: %w", op, topic.Name, err)
+		}
+
+		kind := HandlerKind(topic.Handler)
+		if kind == "" {
+			kind = HandlerCreate
+		}
+		handler, err := NewHandler(kind, decoder, svc, log)
+		if err != nil {
+			return nil, fmt.Errorf("%s: topic %q: %w", op, topic.Name, err)
+		}
+
+		policy := ErrorPolicy(topic.OnError)
+		switch policy {
+		case "":
+			policy = PolicySkip
+		case PolicySkip, PolicyRetry, PolicyStop:
+		default:
+			return nil, fmt.Errorf("%s: topic %q: unknown error policy %q", op, topic.Name, topic.OnError)
+		}
+
+		subscriptions = append(subscriptions, Subscription{
+			Topic:        topic.Name,
+			Handler:      handler,
+			Policy:       policy,
+			MaxRetries:   topic.MaxRetries,
+			RetryBackoff: topic.RetryBackoff,
+		})
+	}
+
+	return subscriptions, nil
+}
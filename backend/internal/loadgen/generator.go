@@ -0,0 +1,218 @@
+package loadgen
+
+import (
+	"L0-wbtech/internal/model"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Kind string
+
+const (
+	KindValid     Kind = "valid"
+	KindInvalid   Kind = "invalid"
+	KindDuplicate Kind = "duplicate"
+)
+
+// Sample is one generated message. Order is nil for payloads that are not
+// even valid JSON.
+type Sample struct {
+	Kind    Kind
+	Order   *model.Order
+	Payload []byte
+}
+
+type GeneratorConfig struct {
+	Seed uint64
+	// InvalidRate and DuplicateRate are fractions of all samples in [0, 1].
+	InvalidRate   float64
+	DuplicateRate float64
+}
+
+// Generator produces orders that pass Order.Validate and have consistent
+// totals: every item's total_price applies its sale to the price, goods_total
+// sums the items and amount adds delivery and custom fees.
+type Generator struct {
+	rnd    *rand.Rand
+	cfg    GeneratorConfig
+	recent []Sample
+	next   int
+}
+
+const recentSize = 256
+
+func NewGenerator(cfg GeneratorConfig) *Generator {
+	if cfg.Seed == 0 {
+		cfg.Seed = uint64(time.Now().UnixNano())
+	}
+	return &Generator{
+		rnd: rand.New(rand.NewPCG(cfg.Seed, cfg.Seed>>1|1)),
+		cfg: cfg,
+	}
+}
+
+func (g *Generator) Next() Sample {
+	p := g.rnd.Float64()
+	switch {
+	case p < g.cfg.InvalidRate:
+		return g.invalid()
+	case p < g.cfg.InvalidRate+g.cfg.DuplicateRate && len(g.recent) > 0:
+		s := g.recent[g.rnd.IntN(len(g.recent))]
+		s.Kind = KindDuplicate
+		return s
+	}
+
+	order := g.Order()
+	s := Sample{Kind: KindValid, Order: order, Payload: mustMarshal(order)}
+	g.remember(s)
+	return s
+}
+
+func (g *Generator) remember(s Sample) {
+	if len(g.recent) < recentSize {
+		g.recent = append(g.recent, s)
+		return
+	}
+	g.recent[g.next] = s
+	g.next = (g.next + 1) % recentSize
+}
+
+func (g *Generator) invalid() Sample {
+	order := g.Order()
+	switch g.rnd.IntN(5) {
+	case 0:
+		payload := mustMarshal(order)
+		return Sample{Kind: KindInvalid, Payload: payload[:len(payload)/2]}
+	case 1:
+		order.OrderUID = ""
+	case 2:
+		order.TrackNumber = ""
+	case 3:
+		order.Entry = ""
+	default:
+		order.Items = nil
+	}
+	return Sample{Kind: KindInvalid, Order: order, Payload: mustMarshal(order)}
+}
+
+var (
+	locales          = []string{"en", "ru", "kk", "uz", "be", "hy", "ky"}
+	deliveryServices = []string{"meest", "cdek", "boxberry", "dhl", "pochta", "wb"}
+	currencies       = []string{"USD", "RUB", "KZT", "BYN", "UZS"}
+	providers        = []string{"wbpay", "sbp", "visa", "mastercard", "mir"}
+	banks            = []string{"alpha", "sber", "tinkoff", "vtb", "halyk"}
+	entries          = []string{"WBIL", "WBRU", "WBKZ", "WBBY"}
+	firstNames       = []string{"Ivan", "Anna", "Timur", "Olga", "Aigerim", "Dmitry", "Elena", "Rustam"}
+	lastNames        = []string{"Petrov", "Ivanova", "Akhmetov", "Smirnova", "Kim", "Volkov", "Sidorova"}
+	cities           = []struct{ City, Region, Zip string }{
+		{"Moscow", "Moscow", "101000"},
+		{"Kazan", "Tatarstan", "420000"},
+		{"Almaty", "Almaty", "050000"},
+		{"Minsk", "Minsk", "220000"},
+		{"Tashkent", "Tashkent", "100000"},
+		{"Novosibirsk", "Novosibirsk Oblast", "630000"},
+	}
+	streets   = []string{"Lenina", "Mira", "Sadovaya", "Pushkina", "Abaya", "Nezavisimosti"}
+	brands    = []string{"Vivienne Sabo", "Nike", "Adidas", "Xiaomi", "Samsung", "Gloria Jeans", "Lego"}
+	products  = []string{"Mascaras", "Sneakers", "T-shirt", "Phone case", "Headphones", "Backpack", "Mug"}
+	sizes     = []string{"0", "XS", "S", "M", "L", "XL", "42", "44"}
+	emailHost = []string{"gmail.com", "mail.ru", "yandex.ru", "outlook.com"}
+)
+
+// Order returns a fresh valid order.
+func (g *Generator) Order() *model.Order {
+	uid := g.hex(16) + "lg"
+	track := "WB" + strings.ToUpper(g.hex(6))
+	created := time.Now().UTC().Add(-time.Duration(g.rnd.IntN(90*24)) * time.Hour).Truncate(time.Second)
+
+	items := make([]model.Item, 1+g.rnd.IntN(5))
+	goodsTotal := 0
+	for i := range items {
+		price := 100 + g.rnd.IntN(20000)
+		sale := []int{0, 0, 5, 10, 15, 20, 30, 50}[g.rnd.IntN(8)]
+		total := price * (100 - sale) / 100
+		goodsTotal += total
+		items[i] = model.Item{
+			ChrtID:      int64(1_000_000 + g.rnd.IntN(9_000_000)),
+			TrackNumber: track,
+			Price:       price,
+			Rid:         g.hex(10) + "lg",
+			Name:        pick(g, products),
+			Sale:        sale,
+			Size:        pick(g, sizes),
+			TotalPrice:  total,
+			NmID:        int64(100_000 + g.rnd.IntN(9_900_000)),
+			Brand:       pick(g, brands),
+			Status:      202,
+		}
+	}
+
+	deliveryCost := []int{0, 0, 300, 500, 1500}[g.rnd.IntN(5)]
+	customFee := 0
+	if g.rnd.IntN(10) == 0 {
+		customFee = goodsTotal / 20
+	}
+
+	first, last := pick(g, firstNames), pick(g, lastNames)
+	place := cities[g.rnd.IntN(len(cities))]
+	customer := "cust" + strconv.Itoa(g.rnd.IntN(50_000))
+
+	return &model.Order{
+		OrderUID:    uid,
+		TrackNumber: track,
+		Entry:       pick(g, entries),
+		Delivery: model.Delivery{
+			Name:    first + " " + last,
+			Phone:   fmt.Sprintf("+7%010d", g.rnd.Int64N(10_000_000_000)),
+			Zip:     place.Zip,
+			City:    place.City,
+			Address: fmt.Sprintf("%s %d", pick(g, streets), 1+g.rnd.IntN(150)),
+			Region:  place.Region,
+			Email:   strings.ToLower(first+"."+last) + "@" + pick(g, emailHost),
+		},
+		Payment: model.Payment{
+			Transaction:  uid,
+			Currency:     pick(g, currencies),
+			Provider:     pick(g, providers),
+			Amount:       goodsTotal + deliveryCost + customFee,
+			PaymentDt:    created.Unix(),
+			Bank:         pick(g, banks),
+			DeliveryCost: deliveryCost,
+			GoodsTotal:   goodsTotal,
+			CustomFee:    customFee,
+		},
+		Items:           items,
+		Locale:          pick(g, locales),
+		CustomerID:      customer,
+		DeliveryService: pick(g, deliveryServices),
+		Shardkey:        strconv.Itoa(g.rnd.IntN(10)),
+		SmID:            g.rnd.IntN(100),
+		DateCreated:     created,
+		OofShard:        strconv.Itoa(1 + g.rnd.IntN(2)),
+	}
+}
+
+func (g *Generator) hex(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(g.rnd.UintN(256))
+	}
+	return hex.EncodeToString(b)[:n]
+}
+
+func pick[T any](g *Generator, values []T) T {
+	return values[g.rnd.IntN(len(values))]
+}
+
+func mustMarshal(order *model.Order) []byte {
+	data, err := json.Marshal(order)
+	if err != nil {
+		panic(fmt.Sprintf("loadgen: marshal order: %v", err))
+	}
+	return data
+}
@@ -0,0 +1,138 @@
+package loadgen
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Prober measures end-to-end latency by polling GET /order/:order_uid for
+// every produced order until it becomes readable.
+type Prober struct {
+	client  *http.Client
+	baseURL string
+	poll    time.Duration
+	timeout time.Duration
+
+	jobs chan probe
+	wg   sync.WaitGroup
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	timeouts  int
+	dropped   int
+}
+
+type probe struct {
+	uid    string
+	sentAt time.Time
+}
+
+type LatencyReport struct {
+	Count    int
+	Timeouts int
+	Dropped  int
+	P50      time.Duration
+	P90      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+}
+
+func NewProber(baseURL string, workers int, poll, timeout time.Duration) *Prober {
+	p := &Prober{
+		client:  &http.Client{Timeout: 5 * time.Second},
+		baseURL: baseURL,
+		poll:    poll,
+		timeout: timeout,
+		jobs:    make(chan probe, workers*64),
+	}
+	for range workers {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for job := range p.jobs {
+				p.run(job)
+			}
+		}()
+	}
+	return p
+}
+
+// Track schedules uid for probing. When every worker is busy the order is
+// counted as dropped instead of slowing down the producer.
+func (p *Prober) Track(uid string, sentAt time.Time) {
+	select {
+	case p.jobs <- probe{uid: uid, sentAt: sentAt}:
+	default:
+		p.mu.Lock()
+		p.dropped++
+		p.mu.Unlock()
+	}
+}
+
+func (p *Prober) run(job probe) {
+	ctx, cancel := context.WithDeadline(context.Background(), job.sentAt.Add(p.timeout))
+	defer cancel()
+
+	target := p.baseURL + "/order/" + url.PathEscape(job.uid)
+	for {
+		if p.found(ctx, target) {
+			p.mu.Lock()
+			p.latencies = append(p.latencies, time.Since(job.sentAt))
+			p.mu.Unlock()
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			p.mu.Lock()
+			p.timeouts++
+			p.mu.Unlock()
+			return
+		case <-time.After(p.poll):
+		}
+	}
+}
+
+func (p *Prober) found(ctx context.Context, target string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode == http.StatusOK
+}
+
+func (p *Prober) Report() LatencyReport {
+	p.mu.Lock()
+	sorted := slices.Clone(p.latencies)
+	report := LatencyReport{Count: len(sorted), Timeouts: p.timeouts, Dropped: p.dropped}
+	p.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return report
+	}
+	slices.Sort(sorted)
+	at := func(q float64) time.Duration {
+		return sorted[int(q*float64(len(sorted)-1))]
+	}
+	report.P50, report.P90, report.P99 = at(0.50), at(0.90), at(0.99)
+	report.Max = sorted[len(sorted)-1]
+	return report
+}
+
+// Close waits for outstanding probes, each bounded by the probe timeout.
+func (p *Prober) Close() LatencyReport {
+	close(p.jobs)
+	p.wg.Wait()
+	return p.Report()
+}
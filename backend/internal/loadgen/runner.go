@@ -0,0 +1,137 @@
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type RunConfig struct {
+	// Rate is the target number of samples per second; zero means as fast
+	// as the sink accepts them.
+	Rate float64
+	// Count and Duration bound the run; whichever is reached first wins.
+	// Zero leaves the bound open.
+	Count          int
+	Duration       time.Duration
+	ReportInterval time.Duration
+}
+
+type RunStats struct {
+	Sent    map[Kind]int
+	Errors  int
+	Elapsed time.Duration
+}
+
+func (s RunStats) Total() int {
+	total := 0
+	for _, n := range s.Sent {
+		total += n
+	}
+	return total
+}
+
+type Runner struct {
+	gen    *Generator
+	sink   Sink
+	prober *Prober
+	cfg    RunConfig
+	log    *slog.Logger
+}
+
+// NewRunner wires a generator to a sink. prober may be nil to skip latency
+// measurements.
+func NewRunner(gen *Generator, sink Sink, prober *Prober, cfg RunConfig, log *slog.Logger) *Runner {
+	if cfg.ReportInterval <= 0 {
+		cfg.ReportInterval = 5 * time.Second
+	}
+	return &Runner{
+		gen:    gen,
+		sink:   sink,
+		prober: prober,
+		cfg:    cfg,
+		log:    log,
+	}
+}
+
+func (r *Runner) Run(ctx context.Context) (RunStats, error) {
+	const op = "loadgen.Runner.Run"
+	log := r.log.With(slog.String("op", op))
+
+	if r.cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.Duration)
+		defer cancel()
+	}
+
+	stats := RunStats{Sent: make(map[Kind]int)}
+	start := time.Now()
+	lastReport := start
+
+	for i := 0; r.cfg.Count == 0 || i < r.cfg.Count; i++ {
+		if r.cfg.Rate > 0 {
+			due := start.Add(time.Duration(float64(i) / r.cfg.Rate * float64(time.Second)))
+			if wait := time.Until(due); wait > 0 {
+				select {
+				case <-ctx.Done():
+				case <-time.After(wait):
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		sample := r.gen.Next()
+		sentAt := time.Now()
+		if err := r.sink.Send(ctx, sample); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			stats.Errors++
+			if stats.Errors == 1 || stats.Errors%1000 == 0 {
+				log.Warn("Failed to send sample", "error", err, "errors", stats.Errors)
+			}
+			continue
+		}
+		stats.Sent[sample.Kind]++
+
+		if r.prober != nil && sample.Kind == KindValid {
+			r.prober.Track(sample.Order.OrderUID, sentAt)
+		}
+
+		if now := time.Now(); now.Sub(lastReport) >= r.cfg.ReportInterval {
+			lastReport = now
+			r.report(log, stats, now.Sub(start))
+		}
+	}
+
+	stats.Elapsed = time.Since(start)
+	r.report(log, stats, stats.Elapsed)
+
+	if err := ctx.Err(); err != nil && err != context.DeadlineExceeded {
+		return stats, fmt.Errorf("%s: %w", op, err)
+	}
+	return stats, nil
+}
+
+func (r *Runner) report(log *slog.Logger, stats RunStats, elapsed time.Duration) {
+	args := []any{
+		"sent", stats.Total(),
+		"valid", stats.Sent[KindValid],
+		"invalid", stats.Sent[KindInvalid],
+		"duplicate", stats.Sent[KindDuplicate],
+		"errors", stats.Errors,
+		"rate", fmt.Sprintf("%.1f/s", float64(stats.Total())/elapsed.Seconds()),
+	}
+	if r.prober != nil {
+		lat := r.prober.Report()
+		args = append(args,
+			"read_back", lat.Count,
+			"p50", lat.P50,
+			"p99", lat.P99,
+			"timeouts", lat.Timeouts)
+	}
+	log.Info("Load progress", args...)
+}
@@ -0,0 +1,107 @@
+package loadgen
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type Sink interface {
+	Send(ctx context.Context, s Sample) error
+	Close() error
+}
+
+// KafkaSink produces samples asynchronously, keyed by order UID. Delivery
+// failures are counted rather than returned from Send.
+type KafkaSink struct {
+	writer *kafka.Writer
+	failed atomic.Int64
+}
+
+func NewKafkaSink(brokers []string, topic string, transport kafka.RoundTripper) *KafkaSink {
+	s := &KafkaSink{}
+	s.writer = &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Topic:                  topic,
+		Transport:              transport,
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireOne,
+		BatchTimeout:           5 * time.Millisecond,
+		Async:                  true,
+		AllowAutoTopicCreation: true,
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				s.failed.Add(int64(len(messages)))
+			}
+		},
+	}
+	return s
+}
+
+func (s *KafkaSink) Send(ctx context.Context, sample Sample) error {
+	var key []byte
+	if sample.Order != nil {
+		key = []byte(sample.Order.OrderUID)
+	}
+	return s.writer.WriteMessages(ctx, kafka.Message{
+		Key:     key,
+		Value:   sample.Payload,
+		Headers: []kafka.Header{{Key: "content-type", Value: []byte("application/json")}},
+	})
+}
+
+// Failed returns how many messages the brokers did not acknowledge.
+func (s *KafkaSink) Failed() int64 {
+	return s.failed.Load()
+}
+
+func (s *KafkaSink) Close() error {
+	return s.writer.Close()
+}
+
+// FileSink writes samples as NDJSON, gzip-compressed when the path ends in
+// ".gz". The output can be fed back through cmd/importer.
+type FileSink struct {
+	file *os.File
+	gz   *gzip.Writer
+	buf  *bufio.Writer
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FileSink{file: f}
+	var w io.Writer = f
+	if strings.HasSuffix(path, ".gz") {
+		s.gz = gzip.NewWriter(f)
+		w = s.gz
+	}
+	s.buf = bufio.NewWriterSize(w, 1<<20)
+	return s, nil
+}
+
+func (s *FileSink) Send(_ context.Context, sample Sample) error {
+	if _, err := s.buf.Write(sample.Payload); err != nil {
+		return err
+	}
+	return s.buf.WriteByte('\n')
+}
+
+func (s *FileSink) Close() error {
+	err := s.buf.Flush()
+	if s.gz != nil {
+		err = errors.Join(err, s.gz.Close())
+	}
+	return errors.Join(err, s.file.Close())
+}
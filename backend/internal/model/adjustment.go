@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// ItemRef identifies an order item by chrt_id or rid.
+type ItemRef struct {
+	ChrtID int64  `json:"chrt_id,omitempty"`
+	Rid    string `json:"rid,omitempty"`
+}
+
+func (r ItemRef) Matches(item Item) bool {
+	if r.Rid != "" {
+		return r.Rid == item.Rid
+	}
+	return r.ChrtID != 0 && r.ChrtID == item.ChrtID
+}
+
+type Cancellation struct {
+	Reason string    `json:"reason"`
+	Actor  string    `json:"actor"`
+	Source string    `json:"source"`
+	At     time.Time `json:"at"`
+	Refund int       `json:"refund"`
+}
+
+type ItemReturn struct {
+	Items  []ItemRef `json:"items"`
+	Reason string    `json:"reason"`
+	Actor  string    `json:"actor"`
+	Source string    `json:"source"`
+	At     time.Time `json:"at"`
+	Refund int       `json:"refund"`
+}
@@ -0,0 +1,17 @@
+package model
+
+// Clone returns a deep copy of the order that shares no memory with it.
+func (o *Order) Clone() *Order {
+	c := *o
+	if o.Items != nil {
+		c.Items = make([]Item, len(o.Items))
+		for i, item := range o.Items {
+			if item.ReturnedAt != nil {
+				at := *item.ReturnedAt
+				item.ReturnedAt = &at
+			}
+			c.Items[i] = item
+		}
+	}
+	return &c
+}
@@ -3,20 +3,22 @@ package model
 import "time"
 
 type Order struct {
-	OrderUID          string    `json:"order_uid"  db:"order_uid"`
-	TrackNumber       string    `json:"track_number" db:"track_number"`
-	Entry             string    `json:"entry" db:"entry"`
-	Delivery          Delivery  `json:"delivery"`
-	Payment           Payment   `json:"payment"`
-	Items             []Item    `json:"items"`
-	Locale            string    `json:"locale" db:"locale"`
-	InternalSignature string    `json:"internal_signature" db:"internal_signature"`
-	CustomerID        string    `json:"customer_id" db:"customer_id"`
-	DeliveryService   string    `json:"delivery_service" db:"delivery_service"`
-	Shardkey          string    `json:"shardkey" db:"shardkey"`
-	SmID              int       `json:"sm_id" db:"sm_id"`
-	DateCreated       time.Time `json:"date_created" db:"date_created"`
-	OofShard          string    `json:"oof_shard" db:"oof_shard"`
+	OrderUID          string      `json:"order_uid"  db:"order_uid"`
+	TrackNumber       string      `json:"track_number" db:"track_number"`
+	Entry             string      `json:"entry" db:"entry"`
+	Delivery          Delivery    `json:"delivery"`
+	Payment           Payment     `json:"payment"`
+	Items             []Item      `json:"items"`
+	Locale            string      `json:"locale" db:"locale"`
+	InternalSignature string      `json:"internal_signature" db:"internal_signature"`
+	CustomerID        string      `json:"customer_id" db:"customer_id"`
+	DeliveryService   string      `json:"delivery_service" db:"delivery_service"`
+	Shardkey          string      `json:"shardkey" db:"shardkey"`
+	SmID              int         `json:"sm_id" db:"sm_id"`
+	DateCreated       time.Time   `json:"date_created" db:"date_created"`
+	OofShard          string      `json:"oof_shard" db:"oof_shard"`
+	Status            OrderStatus `json:"status" db:"status"`
+	StatusUpdatedAt   time.Time   `json:"status_updated_at" db:"status_updated_at"`
 }
 
 type Delivery struct {
@@ -55,4 +57,6 @@ type Item struct {
 	NmID        int64  `json:"nm_id"       db:"nm_id"`
 	Brand       string `json:"brand"       db:"brand"`
 	Status      int    `json:"status"      db:"status"`
+
+	ReturnedAt *time.Time `json:"returned_at,omitempty" db:"returned_at"`
 }
@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+type OrderStatus string
+
+const (
+	StatusCreated    OrderStatus = "created"
+	StatusPaid       OrderStatus = "paid"
+	StatusAssembling OrderStatus = "assembling"
+	StatusShipped    OrderStatus = "shipped"
+	StatusDelivered  OrderStatus = "delivered"
+	StatusCancelled  OrderStatus = "cancelled"
+	StatusReturned   OrderStatus = "returned"
+)
+
+// transitions lists the statuses reachable from each status. Cancelled and
+// returned are terminal.
+var transitions = map[OrderStatus][]OrderStatus{
+	StatusCreated:    {StatusPaid, StatusCancelled},
+	StatusPaid:       {StatusAssembling, StatusCancelled},
+	StatusAssembling: {StatusShipped, StatusCancelled},
+	StatusShipped:    {StatusDelivered, StatusReturned},
+	StatusDelivered:  {StatusReturned},
+	StatusCancelled:  {},
+	StatusReturned:   {},
+}
+
+func (s OrderStatus) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	for _, next := range transitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (s OrderStatus) Terminal() bool {
+	return s.Valid() && len(transitions[s]) == 0
+}
+
+// StatusChange is one recorded step of the order lifecycle.
+type StatusChange struct {
+	From      OrderStatus `json:"from"       db:"from_status"`
+	To        OrderStatus `json:"to"         db:"to_status"`
+	Reason    string      `json:"reason"     db:"reason"`
+	Actor     string      `json:"actor"      db:"actor"`
+	Source    string      `json:"source"     db:"source"`
+	ChangedAt time.Time   `json:"changed_at" db:"changed_at"`
+}
@@ -0,0 +1,136 @@
+package outbox
+
+import (
+	"L0-wbtech/pkg/logger/sl"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type Record struct {
+	ID        int64     `db:"id"`
+	EventID   string    `db:"event_id"`
+	OrderUID  string    `db:"order_uid"`
+	EventType string    `db:"event_type"`
+	Payload   []byte    `db:"payload"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type Store interface {
+	// ProcessOutbox passes up to limit unpublished records, oldest first, to
+	// fn and marks them published once fn succeeds. At most one caller
+	// processes the outbox at a time; others get zero records.
+	ProcessOutbox(ctx context.Context, limit int, fn func(ctx context.Context, records []Record) error) (int, error)
+	Purger
+}
+
+// Producer is the subset of *kafka.Writer used by the relay.
+type Producer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+type Config struct {
+	Topic        string
+	BatchSize    int
+	PollInterval time.Duration
+}
+
+// Relay publishes outbox records to Kafka. Messages are keyed by order_uid,
+// so with a hashing balancer all events of an order land on one partition
+// in the order they were committed. A record is marked published only after
+// the broker acknowledged it, which makes delivery at-least-once.
+type Relay struct {
+	store    Store
+	producer Producer
+	cfg      Config
+	log      *slog.Logger
+}
+
+func NewRelay(store Store, producer Producer, cfg Config, log *slog.Logger) *Relay {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	return &Relay{
+		store:    store,
+		producer: producer,
+		cfg:      cfg,
+		log:      log,
+	}
+}
+
+func (r *Relay) Run(ctx context.Context) {
+	const op = "outbox.Relay.Run"
+	log := r.log.With(slog.String("op", op))
+
+	log.Info("Starting outbox relay", "topic", r.cfg.Topic)
+
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Flush(ctx); err != nil && ctx.Err() == nil {
+			log.Error("Failed to relay outbox", sl.Err(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info("Outbox relay stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush publishes batches until the outbox is drained and returns the number
+// of records published.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n, err := r.store.ProcessOutbox(ctx, r.cfg.BatchSize, r.publish)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < r.cfg.BatchSize {
+			return total, nil
+		}
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, records []Record) error {
+	msgs := make([]kafka.Message, len(records))
+	for i, rec := range records {
+		msgs[i] = kafka.Message{
+			Topic: r.cfg.Topic,
+			Key:   []byte(rec.OrderUID),
+			Value: rec.Payload,
+			Headers: []kafka.Header{
+				{Key: "content-type", Value: []byte("application/json")},
+				{Key: "event-id", Value: []byte(rec.EventID)},
+				{Key: "event-type", Value: []byte(rec.EventType)},
+			},
+			Time: rec.CreatedAt,
+		}
+	}
+
+	if err := r.producer.WriteMessages(ctx, msgs...); err != nil {
+		return fmt.Errorf("publish %d events: %w", len(msgs), err)
+	}
+
+	r.log.Debug("Outbox batch published", "count", len(msgs))
+	return nil
+}
+
+func (r *Relay) Close() error {
+	if closer, ok := r.producer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package outbox_test
+
+import (
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/kafka/kafkatest"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/outbox"
+	"L0-wbtech/internal/storage/memory"
+	"context"
+	stdErrors "errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const topic = "order-events"
+
+func newRelay(store outbox.Store, broker *kafkatest.Log, batchSize int) *outbox.Relay {
+	return outbox.NewRelay(store, broker, outbox.Config{
+		Topic:     topic,
+		BatchSize: batchSize,
+	}, slog.New(slog.DiscardHandler))
+}
+
+func createOrder(t *testing.T, store *memory.Storage, uid string) {
+	t.Helper()
+	createOrderAt(t, store, uid, time.Now().UTC())
+}
+
+func createOrderAt(t *testing.T, store *memory.Storage, uid string, at time.Time) {
+	t.Helper()
+
+	e := event.New(event.OrderCreated, uid)
+	e.OccurredAt = at
+	if err := store.CreateOrder(context.Background(), &model.Order{OrderUID: uid}, &e); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func changeStatus(t *testing.T, store *memory.Storage, uid string, to model.OrderStatus) {
+	t.Helper()
+
+	e := event.New(event.OrderStatusChanged, uid)
+	change := &model.StatusChange{To: to, ChangedAt: time.Now().UTC()}
+	if err := store.UpdateOrderStatus(context.Background(), uid, change, &e); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func header(msg kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// produced returns the events on the topic by order UID, in the order the
+// log holds them.
+func produced(broker *kafkatest.Log) map[string][]kafka.Message {
+	byKey := make(map[string][]kafka.Message)
+	for _, msg := range broker.Messages(topic) {
+		byKey[string(msg.Key)] = append(byKey[string(msg.Key)], msg)
+	}
+	return byKey
+}
+
+func TestRelayPublishesAndMarksSent(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	broker := kafkatest.NewLog(3)
+	relay := newRelay(store, broker, 2)
+
+	for _, uid := range []string{"a", "b", "c"} {
+		createOrder(t, store, uid)
+	}
+
+	n, err := relay.Flush(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("published %d records, want 3", n)
+	}
+
+	records := store.Outbox()
+	byKey := produced(broker)
+	if len(broker.Messages(topic)) != len(records) {
+		t.Fatalf("log holds %d messages, want %d", len(broker.Messages(topic)), len(records))
+	}
+	for _, rec := range records {
+		msgs := byKey[rec.OrderUID]
+		if len(msgs) != 1 {
+			t.Fatalf("order %s has %d messages, want 1", rec.OrderUID, len(msgs))
+		}
+		msg := msgs[0]
+		if header(msg, "event-id") != rec.EventID || header(msg, "event-type") != rec.EventType {
+			t.Errorf("order %s: headers %v do not match record %+v", rec.OrderUID, msg.Headers, rec)
+		}
+		if header(msg, "content-type") != "application/json" {
+			t.Errorf("order %s: content-type %q", rec.OrderUID, header(msg, "content-type"))
+		}
+		if string(msg.Value) != string(rec.Payload) {
+			t.Errorf("order %s: payload differs from the record", rec.OrderUID)
+		}
+		if !msg.Time.Equal(rec.CreatedAt) {
+			t.Errorf("order %s: message time %v, want %v", rec.OrderUID, msg.Time, rec.CreatedAt)
+		}
+	}
+
+	// Published records are not sent again.
+	if n, err := relay.Flush(ctx); err != nil || n != 0 {
+		t.Fatalf("second flush published %d records, err %v; want 0, nil", n, err)
+	}
+	if got := len(broker.Messages(topic)); got != len(records) {
+		t.Errorf("log holds %d messages after the outbox was drained, want %d", got, len(records))
+	}
+}
+
+func TestRelayRedeliversAfterPublishFailure(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	broker := kafkatest.NewLog(1)
+	relay := newRelay(store, broker, 10)
+
+	createOrder(t, store, "a")
+	createOrder(t, store, "b")
+
+	broker.FailWrites(1)
+	n, err := relay.Flush(ctx)
+	if !stdErrors.Is(err, kafkatest.ErrUnavailable) {
+		t.Fatalf("flush error = %v, want %v", err, kafkatest.ErrUnavailable)
+	}
+	if n != 0 {
+		t.Fatalf("published %d records while the broker was down", n)
+	}
+	if got := len(broker.Messages(topic)); got != 0 {
+		t.Fatalf("log holds %d messages after a failed write", got)
+	}
+
+	n, err = relay.Flush(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("published %d records after recovery, want 2", n)
+	}
+
+	var ids []string
+	for _, msg := range broker.Messages(topic) {
+		ids = append(ids, header(msg, "event-id"))
+	}
+	var want []string
+	for _, rec := range store.Outbox() {
+		want = append(want, rec.EventID)
+	}
+	if !slices.Equal(ids, want) {
+		t.Errorf("delivered events %v, want %v", ids, want)
+	}
+}
+
+func TestRelayKeepsOrderPerOrderUID(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	broker := kafkatest.NewLog(3)
+	relay := newRelay(store, broker, 2)
+
+	statuses := []model.OrderStatus{model.StatusPaid, model.StatusAssembling, model.StatusShipped}
+	createOrder(t, store, "a")
+	createOrder(t, store, "b")
+	for _, to := range statuses {
+		changeStatus(t, store, "a", to)
+		changeStatus(t, store, "b", to)
+	}
+
+	if _, err := relay.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// A failed batch is resent ahead of anything newer.
+	broker.FailWrites(1)
+	changeStatus(t, store, "a", model.StatusDelivered)
+	changeStatus(t, store, "b", model.StatusDelivered)
+	if _, err := relay.Flush(ctx); err == nil {
+		t.Fatal("flush succeeded while the broker was down")
+	}
+	if _, err := relay.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := make(map[string][]string)
+	for _, rec := range store.Outbox() {
+		want[rec.OrderUID] = append(want[rec.OrderUID], rec.EventID)
+	}
+
+	byKey := produced(broker)
+	if len(byKey) != len(want) {
+		t.Fatalf("log holds events of %d orders, want %d", len(byKey), len(want))
+	}
+	for uid, msgs := range byKey {
+		var ids []string
+		for _, msg := range msgs {
+			// Keyed by order UID, so one partition holds them all.
+			if msg.Partition != msgs[0].Partition {
+				t.Errorf("order %s spread over partitions %d and %d", uid, msgs[0].Partition, msg.Partition)
+			}
+			ids = append(ids, header(msg, "event-id"))
+		}
+		if len(want[uid]) != len(statuses)+2 {
+			t.Fatalf("order %s has %d outbox records, want %d", uid, len(want[uid]), len(statuses)+2)
+		}
+		if !slices.Equal(ids, want[uid]) {
+			t.Errorf("order %s events published as %v, want %v", uid, ids, want[uid])
+		}
+	}
+}
+
+func TestRetentionKeepsWhatConsumersNeed(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	relay := newRelay(store, kafkatest.NewLog(1), 10)
+	old := time.Now().Add(-2 * time.Hour)
+
+	createOrderAt(t, store, "a", old)
+	if _, err := relay.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	createOrderAt(t, store, "b", old)
+	createOrder(t, store, "c")
+
+	purge := func(cfg outbox.RetentionConfig) {
+		t.Helper()
+		cfg.Retention = time.Hour
+		if _, err := outbox.NewRetention(store, cfg, slog.New(slog.DiscardHandler)).Purge(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	remaining := func() []string {
+		var uids []string
+		for _, rec := range store.Outbox() {
+			uids = append(uids, rec.OrderUID)
+		}
+		return uids
+	}
+
+	// Webhooks need every record, published or not.
+	purge(outbox.RetentionConfig{Relay: true, Webhooks: true})
+	if got := remaining(); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Fatalf("outbox after purge = %v, want every record", got)
+	}
+
+	purge(outbox.RetentionConfig{Relay: true})
+	if got := remaining(); !slices.Equal(got, []string{"b", "c"}) {
+		t.Fatalf("outbox after purge = %v, want the unpublished and recent records", got)
+	}
+	if n, err := relay.Flush(ctx); err != nil || n != 2 {
+		t.Fatalf("flush after purge published %d records, err %v; want 2, nil", n, err)
+	}
+
+	// Without consumers, old records go whether published or not.
+	createOrderAt(t, store, "d", old)
+	purge(outbox.RetentionConfig{})
+	if got := remaining(); !slices.Equal(got, []string{"c"}) {
+		t.Fatalf("outbox after purge = %v, want only the recent record", got)
+	}
+}
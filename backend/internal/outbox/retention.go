@@ -0,0 +1,96 @@
+package outbox
+
+import (
+	"L0-wbtech/pkg/logger/sl"
+	"context"
+	"log/slog"
+	"time"
+)
+
+const (
+	defaultRetention     = 24 * time.Hour
+	defaultPurgeInterval = 10 * time.Minute
+)
+
+// Purge selects the outbox records to delete: those written before Before
+// that every running consumer is done with. A consumer that is switched
+// off does not hold records back, or they would pile up forever.
+type Purge struct {
+	Before time.Time
+	// KeepUnpublished holds records back until the relay published them.
+	KeepUnpublished bool
+	// KeepUndispatched holds records back until the webhook dispatcher
+	// queued their deliveries.
+	KeepUndispatched bool
+}
+
+type Purger interface {
+	PurgeOutbox(ctx context.Context, p Purge) (int64, error)
+}
+
+type RetentionConfig struct {
+	Retention time.Duration
+	Interval  time.Duration
+	// Relay and Webhooks tell which consumers read the outbox.
+	Relay    bool
+	Webhooks bool
+}
+
+// Retention deletes old outbox records on its own schedule. Every mutation
+// writes a record whether or not anything reads the outbox, so it runs in
+// every deployment.
+type Retention struct {
+	store Purger
+	cfg   RetentionConfig
+	log   *slog.Logger
+}
+
+func NewRetention(store Purger, cfg RetentionConfig, log *slog.Logger) *Retention {
+	if cfg.Retention <= 0 {
+		cfg.Retention = defaultRetention
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultPurgeInterval
+	}
+	return &Retention{
+		store: store,
+		cfg:   cfg,
+		log:   log,
+	}
+}
+
+func (r *Retention) Run(ctx context.Context) {
+	const op = "outbox.Retention.Run"
+	log := r.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Purge(ctx); err != nil && ctx.Err() == nil {
+			log.Error("Failed to purge outbox", sl.Err(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge deletes the records past retention and returns how many there were.
+func (r *Retention) Purge(ctx context.Context) (int64, error) {
+	deleted, err := r.store.PurgeOutbox(ctx, Purge{
+		Before:           time.Now().Add(-r.cfg.Retention),
+		KeepUnpublished:  r.cfg.Relay,
+		KeepUndispatched: r.cfg.Webhooks,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		r.log.Info("Purged outbox records", "count", deleted)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/pkg/errors"
+	"L0-wbtech/pkg/logger/sl"
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+func (s *orderService) CancelOrder(
+	ctx context.Context,
+	orderUID string,
+	cancellation model.Cancellation,
+) (*model.Order, error) {
+	const op = "service.orderService.CancelOrder"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("order_uid", orderUID),
+	)
+
+	if orderUID == "" {
+		log.Warn("Order UID is empty")
+		return nil, fmt.Errorf("%s: %w", op, errors.ErrInvalidInput)
+	}
+
+	if cancellation.At.IsZero() {
+		cancellation.At = time.Now().UTC()
+	}
+
+	e := event.New(event.OrderCancelled, orderUID)
+	e.Actor, e.Reason = cancellation.Actor, cancellation.Reason
+
+	if err := s.storage.CancelOrder(ctx, orderUID, &cancellation, &e); err != nil {
+		logMutationError(log, "Failed to cancel order", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.orders.Set(e.Order)
+	s.events.Publish(ctx, e)
+
+	log.Info("Order cancelled",
+		"actor", cancellation.Actor,
+		"refund", cancellation.Refund)
+	return e.Order, nil
+}
+
+func (s *orderService) ReturnItems(
+	ctx context.Context,
+	orderUID string,
+	ret model.ItemReturn,
+) (*model.Order, error) {
+	const op = "service.orderService.ReturnItems"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("order_uid", orderUID),
+	)
+
+	if orderUID == "" || len(ret.Items) == 0 {
+		log.Warn("Nothing to return")
+		return nil, fmt.Errorf("%s: %w", op, errors.ErrInvalidInput)
+	}
+	for _, ref := range ret.Items {
+		if ref.ChrtID == 0 && ref.Rid == "" {
+			log.Warn("Item reference without chrt_id or rid")
+			return nil, fmt.Errorf("%s: %w", op, errors.ErrInvalidInput)
+		}
+	}
+
+	if ret.At.IsZero() {
+		ret.At = time.Now().UTC()
+	}
+
+	e := event.New(event.OrderItemsReturned, orderUID)
+	e.Actor, e.Reason = ret.Actor, ret.Reason
+
+	if err := s.storage.ReturnItems(ctx, orderUID, &ret, &e); err != nil {
+		logMutationError(log, "Failed to return items", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.orders.Set(e.Order)
+	s.events.Publish(ctx, e)
+
+	log.Info("Items returned",
+		"actor", ret.Actor,
+		"items", len(ret.Items),
+		"refund", ret.Refund)
+	return e.Order, nil
+}
+
+// logMutationError logs expected domain failures as warnings.
+func logMutationError(log *slog.Logger, msg string, err error) {
+	switch {
+	case stdErrors.Is(err, errors.ErrNotFound),
+		stdErrors.Is(err, errors.ErrInvalidTransition),
+		stdErrors.Is(err, errors.ErrInvalidInput):
+		log.Warn(msg, sl.Err(err))
+	default:
+		log.Error(msg, sl.Err(err))
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"L0-wbtech/internal/cache"
+	"L0-wbtech/internal/event"
 	"L0-wbtech/internal/model"
 	"L0-wbtech/internal/storage"
 	"L0-wbtech/pkg/errors"
@@ -10,22 +11,26 @@ import (
 	stdErrors "errors"
 	"fmt"
 	"log/slog"
+	"time"
 )
 
 type orderService struct {
 	storage storage.Storage
-	cache   cache.Cache
+	orders  cache.Loader
+	events  event.Publisher
 	log     *slog.Logger
 }
 
 func NewOrderService(
 	storage storage.Storage,
-	cache cache.Cache,
+	orders cache.Loader,
+	events event.Publisher,
 	log *slog.Logger,
 ) Service {
 	return &orderService{
 		storage: storage,
-		cache:   cache,
+		orders:  orders,
+		events:  events,
 		log:     log,
 	}
 }
@@ -42,14 +47,20 @@ func (s *orderService) CreateOrder(ctx context.Context, order *model.Order) erro
 		return fmt.Errorf("%s: %w", op, errors.ErrInvalidInput)
 	}
 
-	if err := s.storage.CreateOrder(ctx, order); err != nil {
+	e := event.New(event.OrderCreated, order.OrderUID)
+	if err := s.storage.CreateOrder(ctx, order, &e); err != nil {
+		if stdErrors.Is(err, errors.ErrAlreadyExists) {
+			log.Info("Order already stored, skipping redelivery")
+			return nil
+		}
 		log.Error("Failed to create order",
 			sl.Err(err),
 			"order_uid", order.OrderUID)
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	s.cache.Set(order)
+	s.orders.Set(order)
+	s.events.Publish(ctx, e)
 	log.Info("Order created and cached")
 	return nil
 }
@@ -61,25 +72,146 @@ func (s *orderService) GetOrder(ctx context.Context, orderUID string) (*model.Or
 		slog.String("order_uid", orderUID),
 	)
 
-	if order, ok := s.cache.Get(orderUID); ok {
-		log.Info("Order retrieved from cache")
-		return order, nil
+	order, err := s.orders.Load(ctx, orderUID)
+	if err != nil {
+		if stdErrors.Is(err, errors.ErrNotFound) {
+			log.Warn("Order not found")
+			return nil, fmt.Errorf("%s: %w", op, errors.ErrNotFound)
+		}
+
+		log.Error("Failed to load order", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	order, err := s.storage.GetOrder(ctx, orderUID)
+	log.Info("Order retrieved")
+	return order, nil
+}
+
+func (s *orderService) GetOrderJSON(ctx context.Context, orderUID string) ([]byte, error) {
+	const op = "service.orderService.GetOrderJSON"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("order_uid", orderUID),
+	)
+
+	data, err := s.orders.LoadJSON(ctx, orderUID)
 	if err != nil {
 		if stdErrors.Is(err, errors.ErrNotFound) {
-			log.Warn("Order not found in storage")
+			log.Warn("Order not found")
 			return nil, fmt.Errorf("%s: %w", op, errors.ErrNotFound)
 		}
 
-		log.Error("Failed to get order from storage", sl.Err(err))
+		log.Error("Failed to load order", sl.Err(err))
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	s.cache.Set(order)
-	log.Info("Order retrieved from storage and cached")
-	return order, nil
+	log.Info("Order retrieved")
+	return data, nil
+}
+
+// GetOrders loads the orders through the caches, reading what they miss
+// from storage in one round. Results keep the order of orderUIDs, without
+// duplicates.
+func (s *orderService) GetOrders(ctx context.Context, orderUIDs []string) ([]*model.Order, []string, error) {
+	const op = "service.orderService.GetOrders"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int("requested", len(orderUIDs)),
+	)
+
+	uids := make([]string, 0, len(orderUIDs))
+	seen := make(map[string]struct{}, len(orderUIDs))
+	for _, uid := range orderUIDs {
+		if _, ok := seen[uid]; ok || uid == "" {
+			continue
+		}
+		seen[uid] = struct{}{}
+		uids = append(uids, uid)
+	}
+
+	found, err := s.orders.LoadMany(ctx, uids)
+	if err != nil {
+		log.Error("Failed to load orders", sl.Err(err))
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	orders := make([]*model.Order, 0, len(found))
+	var missing []string
+	for _, uid := range uids {
+		if order, ok := found[uid]; ok {
+			orders = append(orders, order)
+		} else {
+			missing = append(missing, uid)
+		}
+	}
+
+	log.Info("Orders retrieved",
+		"found", len(orders),
+		"missing", len(missing))
+	return orders, missing, nil
+}
+
+// ListOrders pages through orders by order_uid. It always reads storage, as
+// the cache cannot answer range queries.
+func (s *orderService) ListOrders(ctx context.Context, afterUID string, limit int) ([]*model.Order, error) {
+	const op = "service.orderService.ListOrders"
+	log := s.log.With(slog.String("op", op))
+
+	if limit <= 0 {
+		log.Warn("Invalid page size", "limit", limit)
+		return nil, fmt.Errorf("%s: %w", op, errors.ErrInvalidInput)
+	}
+
+	orders, err := s.storage.ListOrders(ctx, afterUID, limit)
+	if err != nil {
+		log.Error("Failed to list orders", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return orders, nil
+}
+
+func (s *orderService) UpdateStatus(
+	ctx context.Context,
+	orderUID string,
+	change model.StatusChange,
+) (*model.Order, error) {
+	const op = "service.orderService.UpdateStatus"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("order_uid", orderUID),
+		slog.String("status", string(change.To)),
+	)
+
+	if orderUID == "" || !change.To.Valid() {
+		log.Warn("Invalid status update")
+		return nil, fmt.Errorf("%s: %w", op, errors.ErrInvalidInput)
+	}
+
+	if change.ChangedAt.IsZero() {
+		change.ChangedAt = time.Now().UTC()
+	}
+
+	e := event.New(event.OrderStatusChanged, orderUID)
+	e.Actor, e.Reason = change.Actor, change.Reason
+
+	if err := s.storage.UpdateOrderStatus(ctx, orderUID, &change, &e); err != nil {
+		switch {
+		case stdErrors.Is(err, errors.ErrNotFound):
+			log.Warn("Order not found in storage")
+		case stdErrors.Is(err, errors.ErrInvalidTransition):
+			log.Warn("Rejected status transition", sl.Err(err))
+		default:
+			log.Error("Failed to update order status", sl.Err(err))
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.orders.Set(e.Order)
+	s.events.Publish(ctx, e)
+
+	log.Info("Order status updated", "from", change.From)
+	return e.Order, nil
 }
 
 func (s *orderService) RestoreCache(ctx context.Context) error {
@@ -93,7 +225,7 @@ func (s *orderService) RestoreCache(ctx context.Context) error {
 	}
 
 	for _, order := range orders {
-		s.cache.Set(order)
+		s.orders.Prime(order)
 	}
 
 	log.Info("Cache restored", "orders_count", len(orders))
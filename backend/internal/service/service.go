@@ -8,6 +8,14 @@ import (
 type Service interface {
 	CreateOrder(ctx context.Context, order *model.Order) error
 	GetOrder(ctx context.Context, orderUID string) (*model.Order, error)
+	// GetOrderJSON returns the order as JSON prepared by the cache. The
+	// bytes are shared and must not be modified.
+	GetOrderJSON(ctx context.Context, orderUID string) ([]byte, error)
+	GetOrders(ctx context.Context, orderUIDs []string) (found []*model.Order, missing []string, err error)
+	ListOrders(ctx context.Context, afterUID string, limit int) ([]*model.Order, error)
+	UpdateStatus(ctx context.Context, orderUID string, change model.StatusChange) (*model.Order, error)
+	CancelOrder(ctx context.Context, orderUID string, cancellation model.Cancellation) (*model.Order, error)
+	ReturnItems(ctx context.Context, orderUID string, ret model.ItemReturn) (*model.Order, error)
 	RestoreCache(ctx context.Context) error
 	Close() error
 }
@@ -0,0 +1,317 @@
+package service_test
+
+import (
+	"L0-wbtech/internal/cache"
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/service"
+	"L0-wbtech/internal/storage/memory"
+	"L0-wbtech/internal/storage/storagetest"
+	"L0-wbtech/pkg/errors"
+	"context"
+	stdErrors "errors"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+const deliveryCost = 100
+
+// publisher keeps every published event.
+type publisher struct {
+	mu     sync.Mutex
+	events []event.Event
+}
+
+func (p *publisher) Publish(_ context.Context, e event.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, e)
+}
+
+func (p *publisher) last() (event.Event, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.events) == 0 {
+		return event.Event{}, 0
+	}
+	return p.events[len(p.events)-1], len(p.events)
+}
+
+type fixture struct {
+	svc    service.Service
+	cache  cache.Cache
+	events *publisher
+	gen    *storagetest.Orders
+}
+
+func newFixture() *fixture {
+	log := slog.New(slog.DiscardHandler)
+	store := memory.New()
+	c := cache.NewCache()
+	events := &publisher{}
+	return &fixture{
+		svc:    service.NewOrderService(store, cache.NewReadThrough(c, store, cache.ReadThroughConfig{}, log), events, log),
+		cache:  c,
+		events: events,
+		gen:    storagetest.NewOrders(),
+	}
+}
+
+// create stores an order with two items worth 300 and 500 and walks it
+// through path.
+func (f *fixture) create(t *testing.T, path ...model.OrderStatus) *model.Order {
+	t.Helper()
+
+	order := f.gen.Next()
+	first, second := order.Items[0], order.Items[0]
+	first.ChrtID, first.Rid, first.TotalPrice = 101, order.OrderUID+"-a", 300
+	second.ChrtID, second.Rid, second.TotalPrice = 102, order.OrderUID+"-b", 500
+	order.Items = []model.Item{first, second}
+	order.Payment.GoodsTotal = 800
+	order.Payment.DeliveryCost = deliveryCost
+	order.Payment.Amount = 800 + deliveryCost
+
+	ctx := context.Background()
+	if err := f.svc.CreateOrder(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	for _, to := range path {
+		if _, err := f.svc.UpdateStatus(ctx, order.OrderUID, model.StatusChange{To: to}); err != nil {
+			t.Fatalf("move to %s: %v", to, err)
+		}
+	}
+	return order
+}
+
+// expect checks the stored and the cached order.
+func (f *fixture) expect(t *testing.T, uid string, status model.OrderStatus, amount, goods int) {
+	t.Helper()
+
+	cached, ok := f.cache.Get(uid)
+	if !ok {
+		t.Fatalf("order %s not cached", uid)
+	}
+	stored, err := f.svc.GetOrder(context.Background(), uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range []struct {
+		name  string
+		order *model.Order
+	}{{"stored", stored}, {"cached", cached}} {
+		if got.order.Status != status || got.order.Payment.Amount != amount || got.order.Payment.GoodsTotal != goods {
+			t.Errorf("%s order is %s with amount %d, goods total %d, want %s, %d and %d",
+				got.name, got.order.Status, got.order.Payment.Amount, got.order.Payment.GoodsTotal, status, amount, goods)
+		}
+	}
+}
+
+// paths reaches every status from created by legal steps.
+var paths = map[model.OrderStatus][]model.OrderStatus{
+	model.StatusCreated:    nil,
+	model.StatusPaid:       {model.StatusPaid},
+	model.StatusAssembling: {model.StatusPaid, model.StatusAssembling},
+	model.StatusShipped:    {model.StatusPaid, model.StatusAssembling, model.StatusShipped},
+	model.StatusDelivered:  {model.StatusPaid, model.StatusAssembling, model.StatusShipped, model.StatusDelivered},
+	model.StatusCancelled:  {model.StatusCancelled},
+	model.StatusReturned:   {model.StatusPaid, model.StatusAssembling, model.StatusShipped, model.StatusReturned},
+}
+
+func TestUpdateStatusTransitions(t *testing.T) {
+	allowed := map[model.OrderStatus][]model.OrderStatus{
+		model.StatusCreated:    {model.StatusPaid, model.StatusCancelled},
+		model.StatusPaid:       {model.StatusAssembling, model.StatusCancelled},
+		model.StatusAssembling: {model.StatusShipped, model.StatusCancelled},
+		model.StatusShipped:    {model.StatusDelivered, model.StatusReturned},
+		model.StatusDelivered:  {model.StatusReturned},
+		model.StatusCancelled:  nil,
+		model.StatusReturned:   nil,
+	}
+
+	for from, path := range paths {
+		for to := range paths {
+			legal := false
+			for _, next := range allowed[from] {
+				legal = legal || next == to
+			}
+
+			t.Run(string(from)+" to "+string(to), func(t *testing.T) {
+				f := newFixture()
+				order := f.create(t, path...)
+				_, published := f.events.last()
+
+				change := model.StatusChange{To: to, Reason: "test", Actor: "tester"}
+				got, err := f.svc.UpdateStatus(context.Background(), order.OrderUID, change)
+				if !legal {
+					if !stdErrors.Is(err, errors.ErrInvalidTransition) {
+						t.Fatalf("UpdateStatus returned %v, want %v", err, errors.ErrInvalidTransition)
+					}
+					if _, n := f.events.last(); n != published {
+						t.Error("rejected transition published an event")
+					}
+					f.expect(t, order.OrderUID, from, order.Payment.Amount, order.Payment.GoodsTotal)
+					return
+				}
+
+				if err != nil {
+					t.Fatalf("UpdateStatus: %v", err)
+				}
+				if got.Status != to {
+					t.Errorf("returned order is %s, want %s", got.Status, to)
+				}
+				f.expect(t, order.OrderUID, to, order.Payment.Amount, order.Payment.GoodsTotal)
+
+				e, _ := f.events.last()
+				if e.Type != event.OrderStatusChanged || e.Status == nil || e.Status.From != from || e.Status.To != to {
+					t.Errorf("published %s with status %+v, want %s from %s to %s", e.Type, e.Status, event.OrderStatusChanged, from, to)
+				}
+				if e.Actor != "tester" || e.Reason != "test" {
+					t.Errorf("event actor %q, reason %q", e.Actor, e.Reason)
+				}
+			})
+		}
+	}
+}
+
+func TestUpdateStatusRejectsBadInput(t *testing.T) {
+	f := newFixture()
+	order := f.create(t)
+
+	for _, tc := range []struct {
+		name string
+		uid  string
+		to   model.OrderStatus
+		want error
+	}{
+		{"empty uid", "", model.StatusPaid, errors.ErrInvalidInput},
+		{"unknown status", order.OrderUID, "lost", errors.ErrInvalidInput},
+		{"empty status", order.OrderUID, "", errors.ErrInvalidInput},
+		{"missing order", "no-such-order", model.StatusPaid, errors.ErrNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := f.svc.UpdateStatus(context.Background(), tc.uid, model.StatusChange{To: tc.to}); !stdErrors.Is(err, tc.want) {
+				t.Errorf("UpdateStatus returned %v, want %v", err, tc.want)
+			}
+		})
+	}
+	f.expect(t, order.OrderUID, model.StatusCreated, order.Payment.Amount, order.Payment.GoodsTotal)
+}
+
+func TestCancelOrderRefundsPayment(t *testing.T) {
+	const amount = 800 + deliveryCost
+
+	for _, tc := range []struct {
+		from    model.OrderStatus
+		wantErr error
+	}{
+		{model.StatusCreated, nil},
+		{model.StatusPaid, nil},
+		{model.StatusAssembling, nil},
+		{model.StatusShipped, errors.ErrInvalidTransition},
+		{model.StatusDelivered, errors.ErrInvalidTransition},
+		{model.StatusReturned, errors.ErrInvalidTransition},
+	} {
+		t.Run(string(tc.from), func(t *testing.T) {
+			f := newFixture()
+			order := f.create(t, paths[tc.from]...)
+			_, published := f.events.last()
+
+			_, err := f.svc.CancelOrder(context.Background(), order.OrderUID, model.Cancellation{Reason: "test"})
+			if tc.wantErr != nil {
+				if !stdErrors.Is(err, tc.wantErr) {
+					t.Fatalf("CancelOrder returned %v, want %v", err, tc.wantErr)
+				}
+				if _, n := f.events.last(); n != published {
+					t.Error("rejected cancellation published an event")
+				}
+				f.expect(t, order.OrderUID, tc.from, amount, 800)
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("CancelOrder: %v", err)
+			}
+			f.expect(t, order.OrderUID, model.StatusCancelled, 0, 0)
+
+			e, _ := f.events.last()
+			if e.Type != event.OrderCancelled || e.Refund != amount {
+				t.Errorf("published %s refunding %d, want %s refunding %d", e.Type, e.Refund, event.OrderCancelled, amount)
+			}
+			if e.Status == nil || e.Status.From != tc.from {
+				t.Errorf("event status %+v, want a move from %s", e.Status, tc.from)
+			}
+
+			// A second cancellation has nothing left to refund.
+			if _, err := f.svc.CancelOrder(context.Background(), order.OrderUID, model.Cancellation{}); !stdErrors.Is(err, errors.ErrInvalidTransition) {
+				t.Errorf("second CancelOrder returned %v, want %v", err, errors.ErrInvalidTransition)
+			}
+			f.expect(t, order.OrderUID, model.StatusCancelled, 0, 0)
+		})
+	}
+}
+
+func TestReturnItemsRefundsItems(t *testing.T) {
+	chrt := func(id int64) model.ItemRef { return model.ItemRef{ChrtID: id} }
+
+	for _, tc := range []struct {
+		name    string
+		from    model.OrderStatus
+		returns [][]model.ItemRef
+		// refunds holds the refund of every return that succeeds; the
+		// first return beyond them must fail with wantErr.
+		refunds    []int
+		wantErr    error
+		wantStatus model.OrderStatus
+		wantGoods  int
+	}{
+		{"one item", model.StatusShipped, [][]model.ItemRef{{chrt(101)}}, []int{300}, nil, model.StatusShipped, 500},
+		{"item by rid", model.StatusDelivered, [][]model.ItemRef{{{Rid: "-b"}}}, []int{500}, nil, model.StatusDelivered, 300},
+		{"both items at once", model.StatusDelivered, [][]model.ItemRef{{chrt(101), chrt(102)}}, []int{800}, nil, model.StatusReturned, 0},
+		{"one item after another", model.StatusShipped, [][]model.ItemRef{{chrt(102)}, {chrt(101)}}, []int{500, 300}, nil, model.StatusReturned, 0},
+		{"same item twice", model.StatusShipped, [][]model.ItemRef{{chrt(101)}, {chrt(101)}}, []int{300}, errors.ErrInvalidTransition, model.StatusShipped, 500},
+		{"same item twice in one return", model.StatusShipped, [][]model.ItemRef{{chrt(101), chrt(101)}}, nil, errors.ErrNotFound, model.StatusShipped, 800},
+		{"unknown item", model.StatusShipped, [][]model.ItemRef{{chrt(101), chrt(999)}}, nil, errors.ErrNotFound, model.StatusShipped, 800},
+		{"reference without ids", model.StatusShipped, [][]model.ItemRef{{{}}}, nil, errors.ErrInvalidInput, model.StatusShipped, 800},
+		{"nothing to return", model.StatusShipped, [][]model.ItemRef{{}}, nil, errors.ErrInvalidInput, model.StatusShipped, 800},
+		{"order not shipped", model.StatusAssembling, [][]model.ItemRef{{chrt(101)}}, nil, errors.ErrInvalidTransition, model.StatusAssembling, 800},
+		{"cancelled order", model.StatusCancelled, [][]model.ItemRef{{chrt(101)}}, nil, errors.ErrInvalidTransition, model.StatusCancelled, 800},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture()
+			order := f.create(t, paths[tc.from]...)
+			ctx := context.Background()
+
+			for i, refs := range tc.returns {
+				for j := range refs {
+					if refs[j].Rid != "" {
+						refs[j].Rid = order.OrderUID + refs[j].Rid
+					}
+				}
+				_, published := f.events.last()
+
+				_, err := f.svc.ReturnItems(ctx, order.OrderUID, model.ItemReturn{Items: refs, Reason: "test"})
+				if i >= len(tc.refunds) {
+					if !stdErrors.Is(err, tc.wantErr) {
+						t.Fatalf("return %d returned %v, want %v", i+1, err, tc.wantErr)
+					}
+					if _, n := f.events.last(); n != published {
+						t.Errorf("rejected return %d published an event", i+1)
+					}
+					break
+				}
+				if err != nil {
+					t.Fatalf("return %d: %v", i+1, err)
+				}
+
+				e, _ := f.events.last()
+				if e.Type != event.OrderItemsReturned || e.Refund != tc.refunds[i] {
+					t.Errorf("return %d published %s refunding %d, want %s refunding %d",
+						i+1, e.Type, e.Refund, event.OrderItemsReturned, tc.refunds[i])
+				}
+			}
+
+			f.expect(t, order.OrderUID, tc.wantStatus, tc.wantGoods+deliveryCost, tc.wantGoods)
+		})
+	}
+}
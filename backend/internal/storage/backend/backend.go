@@ -0,0 +1,53 @@
+// Package backend opens the storage selected in the config.
+package backend
+
+import (
+	"L0-wbtech/internal/config"
+	"L0-wbtech/internal/outbox"
+	"L0-wbtech/internal/storage"
+	"L0-wbtech/internal/storage/postgres"
+	"L0-wbtech/internal/storage/sqlite"
+	"L0-wbtech/internal/webhook"
+	"fmt"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Storage is everything the binaries need from a backend.
+type Storage interface {
+	storage.Storage
+	storage.OrderStreamer
+	storage.BatchWriter
+	storage.ChangeTracker
+	outbox.Store
+	webhook.Store
+}
+
+var (
+	_ Storage = (*postgres.PostgresStorage)(nil)
+	_ Storage = (*sqlite.SQLiteStorage)(nil)
+)
+
+func Open(cfg config.StorageConfig, pg config.Postgres) (Storage, error) {
+	const op = "storage.backend.Open"
+
+	switch cfg.Driver {
+	case DriverPostgres, "":
+		s, err := postgres.NewPostgresDB(pg)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case DriverSQLite:
+		s, err := sqlite.NewSQLiteDB(cfg.SQLite)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("%s: unknown storage driver %q", op, cfg.Driver)
+	}
+}
@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"L0-wbtech/internal/model"
+	"context"
+	"fmt"
+	"time"
+)
+
+func (s *Storage) HighWaterMark(ctx context.Context) (time.Time, error) {
+	const op = "storage.memory.HighWaterMark"
+
+	if err := ctx.Err(); err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var mark time.Time
+	for _, at := range s.updated {
+		if at.After(mark) {
+			mark = at
+		}
+	}
+	return mark, nil
+}
+
+func (s *Storage) OrdersChangedSince(ctx context.Context, since time.Time) (map[string]*model.Order, error) {
+	const op = "storage.memory.OrdersChangedSince"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	orders := make(map[string]*model.Order)
+	for uid, at := range s.updated {
+		if at.After(since) {
+			orders[uid] = s.orders[uid].Clone()
+		}
+	}
+	return orders, nil
+}
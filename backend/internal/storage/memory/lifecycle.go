@@ -0,0 +1,254 @@
+package memory
+
+import (
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/pkg/errors"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// tx collects the changes of one mutation and applies them only on commit.
+type tx struct {
+	s           *Storage
+	order       *model.Order
+	history     []model.StatusChange
+	adjustments []Adjustment
+}
+
+// begin loads a working copy of the order. Callers hold s.mu.
+func (s *Storage) begin(orderUID string) (*tx, error) {
+	order, ok := s.orders[orderUID]
+	if !ok {
+		return nil, errors.ErrNotFound
+	}
+	return &tx{s: s, order: order.Clone()}, nil
+}
+
+// commit stores the working copy and, when e is not nil, completes it and
+// writes it to the outbox.
+func (t *tx) commit(e *event.Event) error {
+	if e != nil {
+		e.Order = t.order.Clone()
+		if err := t.s.insertOutbox(e); err != nil {
+			return err
+		}
+	}
+
+	uid := t.order.OrderUID
+	t.s.orders[uid] = t.order
+	t.s.updated[uid] = time.Now()
+	t.s.history[uid] = append(t.s.history[uid], t.history...)
+	t.s.adjustments[uid] = append(t.s.adjustments[uid], t.adjustments...)
+	return nil
+}
+
+func (t *tx) transition(change *model.StatusChange) error {
+	current := t.order.Status
+	if !current.CanTransitionTo(change.To) {
+		return fmt.Errorf("%s -> %s: %w", current, change.To, errors.ErrInvalidTransition)
+	}
+	change.From = current
+
+	t.order.Status = change.To
+	t.order.StatusUpdatedAt = change.ChangedAt
+	t.history = append(t.history, *change)
+	return nil
+}
+
+func (s *Storage) UpdateOrderStatus(
+	ctx context.Context,
+	orderUID string,
+	change *model.StatusChange,
+	e *event.Event,
+) error {
+	const op = "storage.memory.UpdateOrderStatus"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.begin(orderUID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := t.transition(change); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if e != nil {
+		e.Status = change
+	}
+	if err := t.commit(e); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) CancelOrder(
+	ctx context.Context,
+	orderUID string,
+	cancellation *model.Cancellation,
+	e *event.Event,
+) error {
+	const op = "storage.memory.CancelOrder"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.begin(orderUID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	change := &model.StatusChange{
+		To:        model.StatusCancelled,
+		Reason:    cancellation.Reason,
+		Actor:     cancellation.Actor,
+		Source:    cancellation.Source,
+		ChangedAt: cancellation.At,
+	}
+	if err := t.transition(change); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	refund := t.order.Payment.Amount
+	t.order.Payment.Amount = 0
+	t.order.Payment.GoodsTotal = 0
+	t.adjustments = append(t.adjustments, Adjustment{
+		Kind:      "cancel",
+		Amount:    refund,
+		Reason:    cancellation.Reason,
+		Actor:     cancellation.Actor,
+		Source:    cancellation.Source,
+		CreatedAt: cancellation.At,
+	})
+
+	if e != nil {
+		e.Status, e.Refund = change, refund
+	}
+	if err := t.commit(e); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	cancellation.Refund = refund
+	return nil
+}
+
+func (s *Storage) ReturnItems(
+	ctx context.Context,
+	orderUID string,
+	ret *model.ItemReturn,
+	e *event.Event,
+) error {
+	const op = "storage.memory.ReturnItems"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.begin(orderUID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if current := t.order.Status; !current.CanTransitionTo(model.StatusReturned) {
+		return fmt.Errorf("%s: cannot return items of %s order: %w", op, current, errors.ErrInvalidTransition)
+	}
+
+	picked, err := pickReturnedItems(t.order.Items, ret.Items)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	refund := 0
+	for _, idx := range picked {
+		item := &t.order.Items[idx]
+		at := ret.At
+		item.ReturnedAt = &at
+
+		t.adjustments = append(t.adjustments, Adjustment{
+			Kind:      "return",
+			ChrtID:    &item.ChrtID,
+			Rid:       &item.Rid,
+			Amount:    item.TotalPrice,
+			Reason:    ret.Reason,
+			Actor:     ret.Actor,
+			Source:    ret.Source,
+			CreatedAt: ret.At,
+		})
+		refund += item.TotalPrice
+	}
+
+	t.order.Payment.GoodsTotal -= refund
+	t.order.Payment.Amount -= refund
+
+	var change *model.StatusChange
+	if !slices.ContainsFunc(t.order.Items, func(item model.Item) bool { return item.ReturnedAt == nil }) {
+		change = &model.StatusChange{
+			To:        model.StatusReturned,
+			Reason:    ret.Reason,
+			Actor:     ret.Actor,
+			Source:    ret.Source,
+			ChangedAt: ret.At,
+		}
+		if err := t.transition(change); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if e != nil {
+		e.Status, e.Items, e.Refund = change, ret.Items, refund
+	}
+	if err := t.commit(e); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	ret.Refund = refund
+	return nil
+}
+
+// pickReturnedItems resolves every reference to a distinct item that has not
+// been returned yet and returns their indexes.
+func pickReturnedItems(items []model.Item, refs []model.ItemRef) ([]int, error) {
+	taken := make(map[int]bool, len(refs))
+	picked := make([]int, 0, len(refs))
+
+	for _, ref := range refs {
+		found, alreadyReturned := -1, false
+		for i, item := range items {
+			if taken[i] || !ref.Matches(item) {
+				continue
+			}
+			if item.ReturnedAt != nil {
+				alreadyReturned = true
+				continue
+			}
+			found = i
+			break
+		}
+
+		switch {
+		case found >= 0:
+			taken[found] = true
+			picked = append(picked, found)
+		case alreadyReturned:
+			return nil, fmt.Errorf("item %+v already returned: %w", ref, errors.ErrInvalidTransition)
+		default:
+			return nil, fmt.Errorf("item %+v: %w", ref, errors.ErrNotFound)
+		}
+	}
+
+	return picked, nil
+}
@@ -0,0 +1,281 @@
+// Package memory implements storage.Storage in process memory with the same
+// observable behaviour as the Postgres storage: the same errors, status
+// history, adjustments and outbox. Every method runs under one lock and works
+// on copies, so a failed mutation leaves nothing behind, like a rolled back
+// transaction.
+package memory
+
+import (
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/outbox"
+	"L0-wbtech/internal/storage"
+	"L0-wbtech/pkg/errors"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+type Storage struct {
+	mu          sync.RWMutex
+	orders      map[string]*model.Order
+	updated     map[string]time.Time
+	history     map[string][]model.StatusChange
+	adjustments map[string][]Adjustment
+
+	outboxMu   sync.Mutex
+	outbox     []outbox.Record
+	outboxSeq  int64
+	published  map[int64]time.Time
+	relayGuard sync.Mutex
+}
+
+// Adjustment mirrors a row of the order_adjustments table.
+type Adjustment struct {
+	Kind      string
+	ChrtID    *int64
+	Rid       *string
+	Amount    int
+	Reason    string
+	Actor     string
+	Source    string
+	CreatedAt time.Time
+}
+
+func New() *Storage {
+	return &Storage{
+		orders:      make(map[string]*model.Order),
+		updated:     make(map[string]time.Time),
+		history:     make(map[string][]model.StatusChange),
+		adjustments: make(map[string][]Adjustment),
+		published:   make(map[int64]time.Time),
+	}
+}
+
+func (s *Storage) CreateOrder(ctx context.Context, order *model.Order, e *event.Event) error {
+	const op = "storage.memory.CreateOrder"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.insertOrder(order) {
+		return errors.ErrAlreadyExists
+	}
+
+	if e != nil {
+		e.Order = order
+		if err := s.insertOutbox(e); err != nil {
+			s.deleteOrder(order.OrderUID)
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// CreateOrders stores a batch without events, skipping existing orders.
+func (s *Storage) CreateOrders(ctx context.Context, orders []*model.Order) (int, error) {
+	const op = "storage.memory.CreateOrders"
+
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, order := range orders {
+		if s.insertOrder(order) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// insertOrder stores a copy of order with its initial status history and
+// reports false if the order already exists. Callers hold s.mu.
+func (s *Storage) insertOrder(order *model.Order) bool {
+	if _, ok := s.orders[order.OrderUID]; ok {
+		return false
+	}
+
+	if order.Status == "" {
+		order.Status = model.StatusCreated
+	}
+	if order.StatusUpdatedAt.IsZero() {
+		order.StatusUpdatedAt = time.Now().UTC()
+	}
+
+	stored := order.Clone()
+	if stored.Items == nil {
+		stored.Items = []model.Item{}
+	}
+	s.orders[order.OrderUID] = stored
+	s.updated[order.OrderUID] = time.Now()
+	s.history[order.OrderUID] = []model.StatusChange{{
+		To:        order.Status,
+		Source:    "ingest",
+		ChangedAt: order.StatusUpdatedAt,
+	}}
+	return true
+}
+
+func (s *Storage) deleteOrder(orderUID string) {
+	delete(s.orders, orderUID)
+	delete(s.updated, orderUID)
+	delete(s.history, orderUID)
+	delete(s.adjustments, orderUID)
+}
+
+func (s *Storage) GetOrder(ctx context.Context, orderUID string) (*model.Order, error) {
+	const op = "storage.memory.GetOrder"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	order, ok := s.orders[orderUID]
+	if !ok {
+		return nil, errors.ErrNotFound
+	}
+	return order.Clone(), nil
+}
+
+func (s *Storage) GetOrders(ctx context.Context, orderUIDs []string) (map[string]*model.Order, error) {
+	const op = "storage.memory.GetOrders"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	orders := make(map[string]*model.Order, len(orderUIDs))
+	for _, uid := range orderUIDs {
+		if order, ok := s.orders[uid]; ok {
+			orders[uid] = order.Clone()
+		}
+	}
+	return orders, nil
+}
+
+func (s *Storage) GetAllOrders(ctx context.Context) (map[string]*model.Order, error) {
+	const op = "storage.memory.GetAllOrders"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	orders := make(map[string]*model.Order, len(s.orders))
+	for uid, order := range s.orders {
+		orders[uid] = order.Clone()
+	}
+	return orders, nil
+}
+
+func (s *Storage) ListOrders(ctx context.Context, afterUID string, limit int) ([]*model.Order, error) {
+	const op = "storage.memory.ListOrders"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	orders := make([]*model.Order, 0, limit)
+	for _, uid := range s.sortedUIDs() {
+		if uid <= afterUID {
+			continue
+		}
+		if len(orders) == limit {
+			break
+		}
+		orders = append(orders, s.orders[uid].Clone())
+	}
+	return orders, nil
+}
+
+// StreamOrders walks a snapshot taken when the call starts, ordered by
+// order_uid.
+func (s *Storage) StreamOrders(ctx context.Context, filter storage.OrderFilter, fn func(*model.Order) error) error {
+	const op = "storage.memory.StreamOrders"
+
+	s.mu.RLock()
+	var snapshot []*model.Order
+	for _, uid := range s.sortedUIDs() {
+		if order := s.orders[uid]; matches(filter, order) {
+			snapshot = append(snapshot, order.Clone())
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, order := range snapshot {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matches(filter storage.OrderFilter, order *model.Order) bool {
+	return (filter.From.IsZero() || !order.DateCreated.Before(filter.From)) &&
+		(filter.To.IsZero() || order.DateCreated.Before(filter.To)) &&
+		(filter.DeliveryService == "" || filter.DeliveryService == order.DeliveryService) &&
+		(filter.CustomerID == "" || filter.CustomerID == order.CustomerID)
+}
+
+// StatusHistory returns the recorded status changes of an order, oldest
+// first.
+func (s *Storage) StatusHistory(orderUID string) []model.StatusChange {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.history[orderUID])
+}
+
+// Adjustments returns the recorded cancellations and returns of an order.
+func (s *Storage) Adjustments(orderUID string) []Adjustment {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.adjustments[orderUID])
+}
+
+func (s *Storage) Close() error {
+	return nil
+}
+
+// sortedUIDs returns every order UID in ascending order. Callers hold s.mu.
+func (s *Storage) sortedUIDs() []string {
+	uids := make([]string, 0, len(s.orders))
+	for uid := range s.orders {
+		uids = append(uids, uid)
+	}
+	slices.Sort(uids)
+	return uids
+}
+
+func marshalEvent(e *event.Event) ([]byte, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("marshal event failed: %w", err)
+	}
+	return payload, nil
+}
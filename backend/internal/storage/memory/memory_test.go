@@ -0,0 +1,10 @@
+package memory_test
+
+import (
+	"L0-wbtech/internal/storage/storagetest"
+	"testing"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.RunBackend(t, storagetest.Memory())
+}
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/outbox"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+func (s *Storage) insertOutbox(e *event.Event) error {
+	payload, err := marshalEvent(e)
+	if err != nil {
+		return err
+	}
+
+	s.outboxMu.Lock()
+	defer s.outboxMu.Unlock()
+
+	s.outboxSeq++
+	s.outbox = append(s.outbox, outbox.Record{
+		ID:        s.outboxSeq,
+		EventID:   e.ID,
+		OrderUID:  e.OrderUID,
+		EventType: string(e.Type),
+		Payload:   payload,
+		CreatedAt: e.OccurredAt,
+	})
+	return nil
+}
+
+func (s *Storage) ProcessOutbox(
+	ctx context.Context,
+	limit int,
+	fn func(ctx context.Context, records []outbox.Record) error,
+) (int, error) {
+	const op = "storage.memory.ProcessOutbox"
+
+	if !s.relayGuard.TryLock() {
+		return 0, nil
+	}
+	defer s.relayGuard.Unlock()
+
+	s.outboxMu.Lock()
+	var records []outbox.Record
+	for _, rec := range s.outbox {
+		if len(records) == limit {
+			break
+		}
+		if _, done := s.published[rec.ID]; !done {
+			records = append(records, rec)
+		}
+	}
+	s.outboxMu.Unlock()
+
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	if err := fn(ctx, records); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now().UTC()
+	s.outboxMu.Lock()
+	for _, rec := range records {
+		s.published[rec.ID] = now
+	}
+	s.outboxMu.Unlock()
+
+	return len(records), nil
+}
+
+// PurgeOutbox treats every record as undispatched: the memory storage
+// does not queue webhooks.
+func (s *Storage) PurgeOutbox(_ context.Context, p outbox.Purge) (int64, error) {
+	s.outboxMu.Lock()
+	defer s.outboxMu.Unlock()
+
+	if p.KeepUndispatched {
+		return 0, nil
+	}
+
+	before := len(s.outbox)
+	s.outbox = slices.DeleteFunc(s.outbox, func(rec outbox.Record) bool {
+		if !rec.CreatedAt.Before(p.Before) {
+			return false
+		}
+		if _, done := s.published[rec.ID]; p.KeepUnpublished && !done {
+			return false
+		}
+		delete(s.published, rec.ID)
+		return true
+	})
+	return int64(before - len(s.outbox)), nil
+}
+
+// Outbox returns every record still in the outbox, published or not.
+func (s *Storage) Outbox() []outbox.Record {
+	s.outboxMu.Lock()
+	defer s.outboxMu.Unlock()
+	return slices.Clone(s.outbox)
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"L0-wbtech/internal/model"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const orderChangesChannel = "order_changes"
+
+// OrderChanges LISTENs for the notifications the order, payment and items
+// triggers send on every write, from this replica or any other. After a
+// reconnect it sends an empty UID, since notifications may have been missed.
+func (s *PostgresStorage) OrderChanges(ctx context.Context) (<-chan string, error) {
+	const op = "storage.postgres.OrderChanges"
+
+	listener := pq.NewListener(s.dsn, time.Second, time.Minute, nil)
+	if err := listener.Listen(orderChangesChannel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	changes := make(chan string, 1024)
+	go func() {
+		defer close(changes)
+		defer listener.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-listener.Notify:
+				uid := ""
+				if n != nil {
+					uid = n.Extra
+				}
+				select {
+				case changes <- uid:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+func (s *PostgresStorage) HighWaterMark(ctx context.Context) (time.Time, error) {
+	const op = "storage.postgres.HighWaterMark"
+
+	var mark sql.NullTime
+	if err := s.db.GetContext(ctx, &mark, `SELECT max(updated_at) FROM orders`); err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return mark.Time, nil
+}
+
+func (s *PostgresStorage) OrdersChangedSince(ctx context.Context, since time.Time) (map[string]*model.Order, error) {
+	const op = "storage.postgres.OrdersChangedSince"
+
+	var uids []string
+	err := s.db.SelectContext(ctx, &uids,
+		`SELECT order_uid FROM orders WHERE updated_at > $1`, since)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	orders, err := getOrders(ctx, s.db, uids)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return orders, nil
+}
@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"L0-wbtech/internal/ingest"
+	"cmp"
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const inboxChannel = "inbox"
+
+func (s *PostgresStorage) ProcessInbox(
+	ctx context.Context,
+	limit int,
+	lease time.Duration,
+	fn func(ctx context.Context, msg ingest.InboxMessage) error,
+) (int, error) {
+	const op = "storage.postgres.ProcessInbox"
+
+	messages, err := s.claimInbox(ctx, limit, lease)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	settled := 0
+	for i, msg := range messages {
+		handleErr := fn(ctx, msg)
+		if handleErr != nil && ctx.Err() != nil {
+			// Interrupted, not failed: hand the rest back untouched.
+			if err := s.releaseInbox(context.WithoutCancel(ctx), messages[i:]); err != nil {
+				return settled, fmt.Errorf("%s: %w", op, err)
+			}
+			return settled, nil
+		}
+
+		if err := s.settleInbox(ctx, msg.ID, handleErr); err != nil {
+			return settled, fmt.Errorf("%s: %w", op, err)
+		}
+		if handleErr != nil && !stdErrors.Is(handleErr, ingest.ErrRejected) {
+			// Later messages wait for this one, so they keep their order.
+			if err := s.releaseInbox(ctx, messages[i+1:]); err != nil {
+				return settled, fmt.Errorf("%s: %w", op, err)
+			}
+			break
+		}
+		settled++
+	}
+
+	return settled, nil
+}
+
+// claimInbox hides up to limit pending messages from other workers for
+// lease. A worker that dies mid-batch gives its messages back when the
+// lease runs out.
+func (s *PostgresStorage) claimInbox(ctx context.Context, limit int, lease time.Duration) ([]ingest.InboxMessage, error) {
+	now := time.Now().UTC()
+
+	var messages []ingest.InboxMessage
+	err := s.db.SelectContext(ctx, &messages, `
+		UPDATE inbox
+		SET claimed_until = $1
+		WHERE id IN (
+			SELECT id
+			FROM inbox
+			WHERE processed_at IS NULL AND failed_at IS NULL
+				AND (claimed_until IS NULL OR claimed_until <= $2)
+			ORDER BY id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, topic, content_type, payload, attempts, created_at
+	`, now.Add(lease), now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim inbox failed: %w", err)
+	}
+
+	slices.SortFunc(messages, func(a, b ingest.InboxMessage) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return messages, nil
+}
+
+// settleInbox records the outcome of handling a claimed message.
+func (s *PostgresStorage) settleInbox(ctx context.Context, id int64, handleErr error) error {
+	var err error
+	if handleErr == nil {
+		_, err = s.db.ExecContext(ctx,
+			`UPDATE inbox SET processed_at = $2, claimed_until = NULL WHERE id = $1`, id, time.Now().UTC())
+	} else {
+		_, err = s.db.ExecContext(ctx, `
+			UPDATE inbox
+			SET attempts = attempts + 1,
+				last_error = $2,
+				failed_at = CASE WHEN $3 THEN $4::timestamptz ELSE NULL END,
+				claimed_until = NULL
+			WHERE id = $1
+		`, id, handleErr.Error(), stdErrors.Is(handleErr, ingest.ErrRejected), time.Now().UTC())
+	}
+	if err != nil {
+		return fmt.Errorf("update inbox failed: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) releaseInbox(ctx context.Context, messages []ingest.InboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+	if _, err := s.db.ExecContext(ctx,
+		`UPDATE inbox SET claimed_until = NULL WHERE id = ANY($1)`, pq.Int64Array(ids)); err != nil {
+		return fmt.Errorf("release inbox failed: %w", err)
+	}
+	return nil
+}
+
+// ListenInbox opens a dedicated connection that LISTENs for the
+// notifications sent by the inbox insert trigger.
+func (s *PostgresStorage) ListenInbox(ctx context.Context) (<-chan struct{}, error) {
+	const op = "storage.postgres.ListenInbox"
+
+	listener := pq.NewListener(s.dsn, time.Second, time.Minute, nil)
+	if err := listener.Listen(inboxChannel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	wake := make(chan struct{}, 1)
+	go func() {
+		defer listener.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			// A nil notification follows a reconnect, after which anything
+			// may have been missed, so it wakes the source as well.
+			case <-listener.Notify:
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return wake, nil
+}
@@ -0,0 +1,233 @@
+package postgres
+
+import (
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage/sqlstore"
+	"L0-wbtech/pkg/errors"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func (s *PostgresStorage) UpdateOrderStatus(
+	ctx context.Context,
+	orderUID string,
+	change *model.StatusChange,
+	e *event.Event,
+) error {
+	const op = "storage.postgres.UpdateOrderStatus"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := dialect.TransitionStatus(ctx, tx, orderUID, change); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := touchOrder(ctx, tx, orderUID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if e != nil {
+		e.Status = change
+		if err := dialect.RecordEvent(ctx, tx, orderUID, e); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: transaction commit failed: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *PostgresStorage) CancelOrder(
+	ctx context.Context,
+	orderUID string,
+	cancellation *model.Cancellation,
+	e *event.Event,
+) error {
+	const op = "storage.postgres.CancelOrder"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	change := &model.StatusChange{
+		To:        model.StatusCancelled,
+		Reason:    cancellation.Reason,
+		Actor:     cancellation.Actor,
+		Source:    cancellation.Source,
+		ChangedAt: cancellation.At,
+	}
+	if err := dialect.TransitionStatus(ctx, tx, orderUID, change); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var refund int
+	err = tx.GetContext(ctx, &refund,
+		`SELECT amount FROM payment WHERE order_uid = $1`+dialect.Lock, orderUID)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("%s: lock payment failed: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE payment SET amount = 0, goods_total = 0 WHERE order_uid = $1`, orderUID)
+	if err != nil {
+		return fmt.Errorf("%s: update payment failed: %w", op, err)
+	}
+
+	if err := dialect.InsertAdjustment(ctx, tx, orderUID, sqlstore.Adjustment{
+		Kind:      "cancel",
+		Amount:    refund,
+		Reason:    cancellation.Reason,
+		Actor:     cancellation.Actor,
+		Source:    cancellation.Source,
+		CreatedAt: cancellation.At,
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := touchOrder(ctx, tx, orderUID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if e != nil {
+		e.Status, e.Refund = change, refund
+		if err := dialect.RecordEvent(ctx, tx, orderUID, e); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: transaction commit failed: %w", op, err)
+	}
+
+	cancellation.Refund = refund
+	return nil
+}
+
+func (s *PostgresStorage) ReturnItems(
+	ctx context.Context,
+	orderUID string,
+	ret *model.ItemReturn,
+	e *event.Event,
+) error {
+	const op = "storage.postgres.ReturnItems"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	current, err := dialect.LockStatus(ctx, tx, orderUID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !current.CanTransitionTo(model.StatusReturned) {
+		return fmt.Errorf("%s: cannot return items of %s order: %w", op, current, errors.ErrInvalidTransition)
+	}
+
+	items, err := dialect.LockItems(ctx, tx, orderUID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	picked, err := sqlstore.PickReturnedItems(items, ret.Items)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	refund := 0
+	for _, idx := range picked {
+		item := items[idx]
+		_, err := tx.ExecContext(ctx,
+			`UPDATE items SET returned_at = $2 WHERE id = $1`, item.ID, dialect.Time(ret.At))
+		if err != nil {
+			return fmt.Errorf("%s: update item failed: %w", op, err)
+		}
+
+		if err := dialect.InsertAdjustment(ctx, tx, orderUID, sqlstore.Adjustment{
+			Kind:      "return",
+			ChrtID:    &item.ChrtID,
+			Rid:       &item.Rid,
+			Amount:    item.TotalPrice,
+			Reason:    ret.Reason,
+			Actor:     ret.Actor,
+			Source:    ret.Source,
+			CreatedAt: ret.At,
+		}); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		refund += item.TotalPrice
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE payment
+		SET goods_total = goods_total - $2, amount = amount - $2
+		WHERE order_uid = $1
+	`, orderUID, refund)
+	if err != nil {
+		return fmt.Errorf("%s: update payment failed: %w", op, err)
+	}
+
+	returned := len(picked)
+	for _, item := range items {
+		if item.ReturnedAt != nil {
+			returned++
+		}
+	}
+	var change *model.StatusChange
+	if returned == len(items) {
+		change = &model.StatusChange{
+			To:        model.StatusReturned,
+			Reason:    ret.Reason,
+			Actor:     ret.Actor,
+			Source:    ret.Source,
+			ChangedAt: ret.At,
+		}
+		if err := dialect.TransitionStatus(ctx, tx, orderUID, change); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := touchOrder(ctx, tx, orderUID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if e != nil {
+		e.Status, e.Items, e.Refund = change, ret.Items, refund
+		if err := dialect.RecordEvent(ctx, tx, orderUID, e); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: transaction commit failed: %w", op, err)
+	}
+
+	ret.Refund = refund
+	return nil
+}
+
+// touchOrder records that the order changed, for storage.ChangeTracker. The
+// wall clock rather than now() keeps long transactions from stamping their
+// writes with their start time.
+func touchOrder(ctx context.Context, tx *sqlx.Tx, orderUID string) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE orders SET updated_at = clock_timestamp() WHERE order_uid = $1`, orderUID)
+	if err != nil {
+		return fmt.Errorf("touch order failed: %w", err)
+	}
+	return nil
+}
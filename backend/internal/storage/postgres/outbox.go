@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"L0-wbtech/internal/outbox"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// outboxLockKey serialises relays across replicas so that events of one
+// order are published in commit order.
+const outboxLockKey = 0x6f7574626f78
+
+func (s *PostgresStorage) ProcessOutbox(
+	ctx context.Context,
+	limit int,
+	fn func(ctx context.Context, records []outbox.Record) error,
+) (int, error) {
+	const op = "storage.postgres.ProcessOutbox"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.GetContext(ctx, &locked, `SELECT pg_try_advisory_xact_lock($1)`, outboxLockKey); err != nil {
+		return 0, fmt.Errorf("%s: acquire relay lock failed: %w", op, err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	var records []outbox.Record
+	err = tx.SelectContext(ctx, &records, `
+		SELECT id, event_id, order_uid, event_type, payload, created_at
+		FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: select outbox failed: %w", op, err)
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	if err := fn(ctx, records); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids := make([]int64, len(records))
+	for i, r := range records {
+		ids[i] = r.ID
+	}
+	query, args, err := sqlx.In(`UPDATE outbox SET published_at = ? WHERE id IN (?)`, time.Now().UTC(), ids)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+		return 0, fmt.Errorf("%s: mark published failed: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: transaction commit failed: %w", op, err)
+	}
+
+	return len(records), nil
+}
+
+func (s *PostgresStorage) PurgeOutbox(ctx context.Context, p outbox.Purge) (int64, error) {
+	const op = "storage.postgres.PurgeOutbox"
+
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM outbox
+		WHERE created_at < $1
+			AND (NOT $2 OR published_at IS NOT NULL)
+			AND (NOT $3 OR dispatched_at IS NOT NULL)
+	`, p.Before, p.KeepUnpublished, p.KeepUndispatched)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res.RowsAffected()
+}
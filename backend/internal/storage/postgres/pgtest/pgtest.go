@@ -0,0 +1,146 @@
+// Package pgtest runs a throwaway Postgres cluster from the local
+// installation, for exercising the Postgres storage without Docker.
+package pgtest
+
+import (
+	"L0-wbtech/internal/config"
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// ErrUnavailable is returned by Start when no Postgres binaries are found.
+var ErrUnavailable = stdErrors.New("postgres binaries not found")
+
+// Server is a running cluster listening on a Unix socket in its data
+// directory.
+type Server struct {
+	dir    string
+	pgCtl  string
+	port   string
+	dbName string
+}
+
+// Start initialises a cluster in a temporary directory, starts it and
+// applies the migrations in migrationsPath. Binaries are looked up in
+// PG_BIN first and then in PATH.
+func Start(ctx context.Context, migrationsPath string) (*Server, error) {
+	const op = "pgtest.Start"
+
+	initdb, err := lookBinary("initdb")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	pgCtl, err := lookBinary("pg_ctl")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	dir, err := os.MkdirTemp("", "pgtest-")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s := &Server{dir: dir, pgCtl: pgCtl, port: port, dbName: "postgres"}
+	data := filepath.Join(dir, "data")
+
+	if err := run(ctx, initdb, "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync"); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("%s: initdb: %w", op, err)
+	}
+
+	opts := fmt.Sprintf("-p %s -k %s -c listen_addresses='' -c fsync=off", port, dir)
+	if err := run(ctx, pgCtl, "-D", data, "-l", filepath.Join(dir, "postgres.log"), "-o", opts, "-w", "start"); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("%s: pg_ctl start: %w", op, err)
+	}
+
+	if err := s.migrate(migrationsPath); err != nil {
+		s.Stop()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
+
+// Config returns the connection settings for postgres.NewPostgresDB.
+func (s *Server) Config() config.Postgres {
+	return config.Postgres{
+		Host:     s.dir,
+		Port:     s.port,
+		Username: "postgres",
+		DBName:   s.dbName,
+		SSLMode:  "disable",
+	}
+}
+
+func (s *Server) migrate(path string) error {
+	return Migrate(fmt.Sprintf("postgres://postgres@/%s?host=%s&port=%s&sslmode=disable", s.dbName, s.dir, s.port), path)
+}
+
+// Migrate applies the migrations in path to the database at dsn, which
+// must be a postgres:// URL.
+func Migrate(dsn, path string) error {
+	m, err := migrate.New("file://"+path, dsn)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	return nil
+}
+
+// Stop shuts the cluster down and removes its files.
+func (s *Server) Stop() error {
+	err := run(context.Background(), s.pgCtl, "-D", filepath.Join(s.dir, "data"), "-m", "immediate", "-w", "stop")
+	return stdErrors.Join(err, os.RemoveAll(s.dir))
+}
+
+func lookBinary(name string) (string, error) {
+	if dir := os.Getenv("PG_BIN"); dir != "" {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrUnavailable, name)
+	}
+	return path, nil
+}
+
+func freePort() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port), nil
+}
+
+func run(ctx context.Context, name string, args ...string) error {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, out)
+	}
+	return nil
+}
@@ -2,19 +2,33 @@ package postgres
 
 import (
 	"L0-wbtech/internal/config"
+	"L0-wbtech/internal/event"
 	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage"
+	"L0-wbtech/internal/storage/sqlstore"
 	"L0-wbtech/pkg/errors"
 	"context"
-	"database/sql"
 	stdErrors "errors"
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+// dialect matches order lists as arrays and locks the rows a transaction
+// changes, so concurrent changes to one order are serialised.
+var dialect = sqlstore.Dialect{
+	In: func(column string, values []string) (string, []any) {
+		return column + " = ANY($1)", []any{pq.StringArray(values)}
+	},
+	Time: func(t time.Time) time.Time { return t },
+	Lock: " FOR UPDATE",
+}
+
 type PostgresStorage struct {
-	db *sqlx.DB
+	db  *sqlx.DB
+	dsn string
 }
 
 func NewPostgresDB(cfg config.Postgres) (*PostgresStorage, error) {
@@ -23,6 +37,18 @@ func NewPostgresDB(cfg config.Postgres) (*PostgresStorage, error) {
 	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.DBName, cfg.SSLMode)
 
+	s, err := Open(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return s, nil
+}
+
+// Open connects to the database at dsn, given as a URL or as key=value
+// pairs.
+func Open(dsn string) (*PostgresStorage, error) {
+	const op = "storage.postgres.Open"
+
 	db, err := sqlx.Open("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
@@ -32,10 +58,10 @@ func NewPostgresDB(cfg config.Postgres) (*PostgresStorage, error) {
 		return nil, fmt.Errorf("%s: db.Ping error: %w", op, err)
 	}
 
-	return &PostgresStorage{db: db}, nil
+	return &PostgresStorage{db: db, dsn: dsn}, nil
 }
 
-func (s *PostgresStorage) CreateOrder(ctx context.Context, order *model.Order) error {
+func (s *PostgresStorage) CreateOrder(ctx context.Context, order *model.Order, e *event.Event) error {
 	const op = "storage.postgres.CreateOrder"
 
 	tx, err := s.db.BeginTxx(ctx, nil)
@@ -44,14 +70,76 @@ func (s *PostgresStorage) CreateOrder(ctx context.Context, order *model.Order) e
 	}
 	defer tx.Rollback()
 
+	// A redelivered order is already stored together with its children.
+	if inserted, err := insertOrder(ctx, tx, order); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	} else if !inserted {
+		return errors.ErrAlreadyExists
+	}
+
+	if e != nil {
+		e.Order = order
+		if err := dialect.InsertOutbox(ctx, tx, e); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: transaction commit failed: %w", op, err)
+	}
+
+	return nil
+}
+
+// CreateOrders stores a batch of orders in one transaction without
+// recording events, skipping orders that already exist. It returns how many
+// orders were inserted; any failure rolls back the whole batch.
+func (s *PostgresStorage) CreateOrders(ctx context.Context, orders []*model.Order) (int, error) {
+	const op = "storage.postgres.CreateOrders"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	count := 0
+	for _, order := range orders {
+		inserted, err := insertOrder(ctx, tx, order)
+		if err != nil {
+			return 0, fmt.Errorf("%s: order %s: %w", op, order.OrderUID, err)
+		}
+		if inserted {
+			count++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: transaction commit failed: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// insertOrder writes order with its children and initial status history. It
+// reports false without writing anything when the order already exists.
+func insertOrder(ctx context.Context, tx *sqlx.Tx, order *model.Order) (bool, error) {
+	if order.Status == "" {
+		order.Status = model.StatusCreated
+	}
+	if order.StatusUpdatedAt.IsZero() {
+		order.StatusUpdatedAt = time.Now().UTC()
+	}
+
 	orderQuery := `
 		INSERT INTO orders (
 			order_uid, track_number, entry, locale, internal_signature, 
-			customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard,
+			status, status_updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		ON CONFLICT (order_uid) DO NOTHING
 	`
-	_, err = tx.ExecContext(ctx, orderQuery,
+	res, err := tx.ExecContext(ctx, orderQuery,
 		order.OrderUID,
 		order.TrackNumber,
 		order.Entry,
@@ -62,9 +150,25 @@ func (s *PostgresStorage) CreateOrder(ctx context.Context, order *model.Order) e
 		order.Shardkey,
 		order.SmID,
 		order.DateCreated,
-		order.OofShard)
+		order.OofShard,
+		order.Status,
+		order.StatusUpdatedAt)
 	if err != nil {
-		return fmt.Errorf("%s: insert order failed: %w", op, err)
+		return false, fmt.Errorf("insert order failed: %w", err)
+	}
+
+	if inserted, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else if inserted == 0 {
+		return false, nil
+	}
+
+	if err := dialect.InsertStatusChange(ctx, tx, order.OrderUID, &model.StatusChange{
+		To:        order.Status,
+		Source:    "ingest",
+		ChangedAt: order.StatusUpdatedAt,
+	}); err != nil {
+		return false, err
 	}
 
 	deliveryQuery := `
@@ -82,7 +186,7 @@ func (s *PostgresStorage) CreateOrder(ctx context.Context, order *model.Order) e
 		order.Delivery.Region,
 		order.Delivery.Email)
 	if err != nil {
-		return fmt.Errorf("%s: insert delivery failed: %w", op, err)
+		return false, fmt.Errorf("insert delivery failed: %w", err)
 	}
 
 	paymentQuery := `
@@ -104,7 +208,7 @@ func (s *PostgresStorage) CreateOrder(ctx context.Context, order *model.Order) e
 		order.Payment.GoodsTotal,
 		order.Payment.CustomFee)
 	if err != nil {
-		return fmt.Errorf("%s: insert payment failed: %w", op, err)
+		return false, fmt.Errorf("insert payment failed: %w", err)
 	}
 
 	itemQuery := `
@@ -128,105 +232,93 @@ func (s *PostgresStorage) CreateOrder(ctx context.Context, order *model.Order) e
 			item.Brand,
 			item.Status)
 		if err != nil {
-			return fmt.Errorf("%s: insert item failed: %w", op, err)
+			return false, fmt.Errorf("insert item failed: %w", err)
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("%s: transaction commit failed: %w", op, err)
-	}
-
-	return nil
+	return true, nil
 }
 
 func (s *PostgresStorage) GetOrder(ctx context.Context, orderUID string) (*model.Order, error) {
+	return getOrder(ctx, s.db, orderUID)
+}
+
+// getOrder loads a full order through q, which is either the pool or a
+// transaction.
+func getOrder(ctx context.Context, q sqlx.QueryerContext, orderUID string) (*model.Order, error) {
 	const op = "storage.postgres.GetOrder"
 
-	orderQuery := `
-		SELECT
-			order_uid, track_number, entry, locale,
-			internal_signature, customer_id, delivery_service,
-			shardkey, sm_id, date_created, oof_shard
-		FROM orders
-		WHERE order_uid = $1
-	`
-	var order model.Order
-	if err := s.db.GetContext(ctx, &order, orderQuery, orderUID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.ErrNotFound
+	order, err := dialect.GetOrder(ctx, q, orderUID)
+	if err != nil {
+		if stdErrors.Is(err, errors.ErrNotFound) {
+			return nil, err
 		}
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	return order, nil
+}
 
-	deliveryQuery := `
-		SELECT name, phone, zip, city, address, region, email
-		FROM delivery
-		WHERE order_uid = $1
-	`
-	if err := s.db.GetContext(ctx, &order.Delivery, deliveryQuery, orderUID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.ErrNotFound
-		}
-		return nil, fmt.Errorf("%s: get delivery failed: %w", op, err)
-	}
-
-	paymentQuery := `
-		SELECT
-			id, transaction, request_id, currency, provider,
-			amount, payment_dt, bank, delivery_cost, goods_total, custom_fee
-		FROM payment
-		WHERE order_uid = $1
-	`
-	if err := s.db.GetContext(ctx, &order.Payment, paymentQuery, orderUID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.ErrNotFound
-		}
-		return nil, fmt.Errorf("%s: get payment failed: %w", op, err)
-	}
+func (s *PostgresStorage) GetAllOrders(ctx context.Context) (map[string]*model.Order, error) {
+	const op = "storage.postgres.GetAllOrders"
 
-	itemsQuery := `
-		SELECT
-			chrt_id, track_number, price, rid,
-			name, sale, size, total_price, nm_id, brand, status
-		FROM items
-		WHERE order_uid = $1
-	`
-	if err := s.db.SelectContext(ctx, &order.Items, itemsQuery, orderUID); err != nil {
-		if err == sql.ErrNoRows {
-			order.Items = []model.Item{}
-		} else {
-			return nil, fmt.Errorf("%s: get items failed: %w", op, err)
-		}
+	orders := make(map[string]*model.Order)
+	err := s.StreamOrders(ctx, storage.OrderFilter{}, func(order *model.Order) error {
+		orders[order.OrderUID] = order
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &order, nil
+	return orders, nil
 }
 
-func (s *PostgresStorage) GetAllOrders(ctx context.Context) (map[string]*model.Order, error) {
-	const op = "storage.postgres.GetAllOrders"
+func (s *PostgresStorage) ListOrders(ctx context.Context, afterUID string, limit int) ([]*model.Order, error) {
+	const op = "storage.postgres.ListOrders"
 
-	uidsQuery := `SELECT order_uid FROM orders`
+	uidsQuery := `
+		SELECT order_uid
+		FROM orders
+		WHERE order_uid > $1
+		ORDER BY order_uid
+		LIMIT $2
+	`
 	var uids []string
-	err := s.db.SelectContext(ctx, &uids, uidsQuery)
-	if err != nil {
+	if err := s.db.SelectContext(ctx, &uids, uidsQuery, afterUID, limit); err != nil {
 		return nil, fmt.Errorf("%s: get order uids failed: %w", op, err)
 	}
 
-	orders := make(map[string]*model.Order)
+	found, err := getOrders(ctx, s.db, uids)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	orders := make([]*model.Order, 0, len(uids))
 	for _, uid := range uids {
-		order, err := s.GetOrder(ctx, uid)
-		if err != nil {
-			if stdErrors.Is(err, errors.ErrNotFound) {
-				continue
-			}
-			return nil, fmt.Errorf("%s: get order %s failed: %w", op, uid, err)
+		if order, ok := found[uid]; ok {
+			orders = append(orders, order)
 		}
-		orders[uid] = order
 	}
 
 	return orders, nil
 }
 
+func (s *PostgresStorage) GetOrders(ctx context.Context, orderUIDs []string) (map[string]*model.Order, error) {
+	return getOrders(ctx, s.db, orderUIDs)
+}
+
+// getOrders loads many orders with one query per table. UIDs that do not
+// exist are absent from the result.
+func getOrders(ctx context.Context, q sqlx.QueryerContext, orderUIDs []string) (map[string]*model.Order, error) {
+	const op = "storage.postgres.GetOrders"
+
+	orders, err := dialect.GetOrders(ctx, q, orderUIDs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return orders, nil
+}
+
 func (s *PostgresStorage) Close() error {
 	return s.db.Close()
 }
@@ -0,0 +1,19 @@
+package postgres_test
+
+import (
+	"L0-wbtech/internal/storage/storagetest"
+	"os"
+	"testing"
+)
+
+const migrations = "../../../migrations"
+
+// TestStorage uses the database at POSTGRES_DSN when it is set and a
+// temporary local cluster otherwise.
+func TestStorage(t *testing.T) {
+	backend := storagetest.LocalPostgres(migrations)
+	if dsn := os.Getenv("POSTGRES_DSN"); dsn != "" {
+		backend = storagetest.Postgres(dsn, migrations)
+	}
+	storagetest.RunBackend(t, backend)
+}
@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// StreamOrders pages through matching orders by order_uid inside a single
+// read-only snapshot, so memory stays bounded by the batch size and the
+// result is consistent even while orders keep arriving.
+func (s *PostgresStorage) StreamOrders(ctx context.Context, filter storage.OrderFilter, fn func(*model.Order) error) error {
+	const op = "storage.postgres.StreamOrders"
+
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := dialect.StreamOrders(ctx, tx, filter, fn); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
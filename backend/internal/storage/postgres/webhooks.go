@@ -0,0 +1,244 @@
+package postgres
+
+import (
+	"L0-wbtech/internal/storage/sqlstore"
+	"L0-wbtech/internal/webhook"
+	"L0-wbtech/pkg/errors"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type webhookRow struct {
+	webhook.Subscription
+	Events pq.StringArray `db:"events"`
+}
+
+func (r webhookRow) toSubscription() webhook.Subscription {
+	sub := r.Subscription
+	sub.Events = []string(r.Events)
+	return sub
+}
+
+const webhookColumns = `
+	id, url, secret, events, delivery_service,
+	active, failure_count, disabled_at, created_at
+`
+
+func (s *PostgresStorage) CreateWebhook(ctx context.Context, sub *webhook.Subscription) error {
+	const op = "storage.postgres.CreateWebhook"
+
+	query := `
+		INSERT INTO webhook_subscriptions (url, secret, events, delivery_service, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err := s.db.QueryRowxContext(ctx, query,
+		sub.URL,
+		sub.Secret,
+		pq.StringArray(sub.Events),
+		sub.DeliveryService,
+		sub.Active).Scan(&sub.ID, &sub.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) GetWebhook(ctx context.Context, id int64) (*webhook.Subscription, error) {
+	const op = "storage.postgres.GetWebhook"
+
+	var row webhookRow
+	query := `SELECT ` + webhookColumns + ` FROM webhook_subscriptions WHERE id = $1`
+	if err := s.db.GetContext(ctx, &row, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sub := row.toSubscription()
+	return &sub, nil
+}
+
+func (s *PostgresStorage) ListWebhooks(ctx context.Context, activeOnly bool) ([]webhook.Subscription, error) {
+	const op = "storage.postgres.ListWebhooks"
+
+	query := `SELECT ` + webhookColumns + ` FROM webhook_subscriptions`
+	if activeOnly {
+		query += ` WHERE active`
+	}
+	query += ` ORDER BY id`
+
+	var rows []webhookRow
+	if err := s.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	subs := make([]webhook.Subscription, len(rows))
+	for i, row := range rows {
+		subs[i] = row.toSubscription()
+	}
+	return subs, nil
+}
+
+func (s *PostgresStorage) DeleteWebhook(ctx context.Context, id int64) error {
+	const op = "storage.postgres.DeleteWebhook"
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return sqlstore.ExpectAffected(res)
+}
+
+func (s *PostgresStorage) SetWebhookActive(ctx context.Context, id int64, active bool) error {
+	const op = "storage.postgres.SetWebhookActive"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE webhook_subscriptions
+		SET active = $2,
+			failure_count = 0,
+			disabled_at = CASE WHEN $2 THEN NULL ELSE now() END
+		WHERE id = $1
+	`
+	res, err := tx.ExecContext(ctx, query, id, active)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := sqlstore.ExpectAffected(res); err != nil {
+		return err
+	}
+
+	if !active {
+		if err := dropWebhookJobs(ctx, tx, id); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: transaction commit failed: %w", op, err)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) RecordWebhookResult(ctx context.Context, id int64, success bool, disableAfter int) (bool, error) {
+	const op = "storage.postgres.RecordWebhookResult"
+
+	if success {
+		_, err := s.db.ExecContext(ctx,
+			`UPDATE webhook_subscriptions SET failure_count = 0 WHERE id = $1 AND failure_count <> 0`, id)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+		return false, nil
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE webhook_subscriptions
+		SET failure_count = failure_count + 1,
+			active = CASE WHEN $2 > 0 AND failure_count + 1 >= $2 THEN FALSE ELSE active END,
+			disabled_at = CASE WHEN $2 > 0 AND failure_count + 1 >= $2 THEN $3 ELSE disabled_at END
+		WHERE id = $1
+		RETURNING active
+	`
+	var active bool
+	if err := tx.GetContext(ctx, &active, query, id, disableAfter, time.Now().UTC()); err != nil {
+		if err == sql.ErrNoRows {
+			return false, errors.ErrNotFound
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !active {
+		if err := dropWebhookJobs(ctx, tx, id); err != nil {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("%s: transaction commit failed: %w", op, err)
+	}
+	return !active, nil
+}
+
+// dropWebhookJobs removes the queued jobs of a disabled subscription, so
+// they neither pile up nor go out in a burst once it is enabled again.
+func dropWebhookJobs(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM webhook_jobs WHERE subscription_id = $1`, id); err != nil {
+		return fmt.Errorf("drop webhook jobs failed: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) CreateWebhookDelivery(ctx context.Context, d *webhook.Delivery) error {
+	const op = "storage.postgres.CreateWebhookDelivery"
+
+	query := `
+		INSERT INTO webhook_deliveries (
+			subscription_id, event_id, event_type, attempt,
+			status_code, error, success, duration_ms, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+	err := s.db.QueryRowxContext(ctx, query,
+		d.SubscriptionID,
+		d.EventID,
+		d.EventType,
+		d.Attempt,
+		d.StatusCode,
+		d.Error,
+		d.Success,
+		d.Duration,
+		d.CreatedAt).Scan(&d.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) ListWebhookDeliveries(ctx context.Context, id int64, limit int) ([]webhook.Delivery, error) {
+	const op = "storage.postgres.ListWebhookDeliveries"
+
+	query := `
+		SELECT
+			id, subscription_id, event_id, event_type, attempt,
+			status_code, error, success, duration_ms, created_at
+		FROM webhook_deliveries
+		WHERE subscription_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`
+	var deliveries []webhook.Delivery
+	if err := s.db.SelectContext(ctx, &deliveries, query, id, limit); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return deliveries, nil
+}
+
+func (s *PostgresStorage) PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.postgres.PurgeWebhookDeliveries"
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"L0-wbtech/internal/model"
+	"context"
+	"fmt"
+	"maps"
+	"time"
+)
+
+// changedChunk keeps the IN list of one query well under the SQLite limit
+// on bound parameters.
+const changedChunk = 500
+
+func (s *SQLiteStorage) HighWaterMark(ctx context.Context) (time.Time, error) {
+	const op = "storage.sqlite.HighWaterMark"
+
+	// max() would return text, as aggregates lose the DATETIME column type
+	// the driver parses times by.
+	var mark []time.Time
+	err := s.reader.SelectContext(ctx, &mark,
+		`SELECT updated_at FROM orders ORDER BY updated_at DESC LIMIT 1`)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(mark) == 0 {
+		return time.Time{}, nil
+	}
+	return mark[0], nil
+}
+
+func (s *SQLiteStorage) OrdersChangedSince(ctx context.Context, since time.Time) (map[string]*model.Order, error) {
+	const op = "storage.sqlite.OrdersChangedSince"
+
+	var uids []string
+	err := s.reader.SelectContext(ctx, &uids,
+		`SELECT order_uid FROM orders WHERE updated_at > $1`, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	orders := make(map[string]*model.Order, len(uids))
+	for start := 0; start < len(uids); start += changedChunk {
+		chunk, err := getOrders(ctx, s.reader, uids[start:min(start+changedChunk, len(uids))])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		maps.Copy(orders, chunk)
+	}
+	return orders, nil
+}
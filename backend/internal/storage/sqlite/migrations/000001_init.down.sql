@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS order_adjustments;
+DROP TABLE IF EXISTS order_status_history;
+DROP TABLE IF EXISTS items;
+DROP TABLE IF EXISTS payment;
+DROP TABLE IF EXISTS delivery;
+DROP TABLE IF EXISTS orders;
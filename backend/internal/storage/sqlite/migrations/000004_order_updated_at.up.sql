@@ -0,0 +1,5 @@
+ALTER TABLE orders ADD COLUMN updated_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+
+UPDATE orders SET updated_at = status_updated_at;
+
+CREATE INDEX IF NOT EXISTS idx_orders_updated_at ON orders (updated_at);
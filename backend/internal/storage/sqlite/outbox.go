@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"L0-wbtech/internal/outbox"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func (s *SQLiteStorage) ProcessOutbox(
+	ctx context.Context,
+	limit int,
+	fn func(ctx context.Context, records []outbox.Record) error,
+) (int, error) {
+	const op = "storage.sqlite.ProcessOutbox"
+
+	if !s.relayMu.TryLock() {
+		return 0, nil
+	}
+	defer s.relayMu.Unlock()
+
+	var records []outbox.Record
+	err := s.reader.SelectContext(ctx, &records, `
+		SELECT id, event_id, order_uid, event_type, payload, created_at
+		FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: select outbox failed: %w", op, err)
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	if err := fn(ctx, records); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids := make([]int64, len(records))
+	for i, r := range records {
+		ids[i] = r.ID
+	}
+	query, args, err := sqlx.In(`UPDATE outbox SET published_at = ? WHERE id IN (?)`, time.Now().UTC(), ids)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		return 0, fmt.Errorf("%s: mark published failed: %w", op, err)
+	}
+
+	return len(records), nil
+}
+
+func (s *SQLiteStorage) PurgeOutbox(ctx context.Context, p outbox.Purge) (int64, error) {
+	const op = "storage.sqlite.PurgeOutbox"
+
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM outbox
+		WHERE created_at < $1
+			AND (NOT $2 OR published_at IS NOT NULL)
+			AND (NOT $3 OR dispatched_at IS NOT NULL)
+	`, p.Before.UTC(), p.KeepUnpublished, p.KeepUndispatched)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,399 @@
+package sqlite
+
+import (
+	"L0-wbtech/internal/config"
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage"
+	"L0-wbtech/internal/storage/sqlstore"
+	"L0-wbtech/pkg/errors"
+	"context"
+	"database/sql"
+	"embed"
+	stdErrors "errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// dialect binds times in UTC, as SQLite compares them as text, and locks
+// nothing: transactions run on the single writer connection, so nothing
+// changes what they read before they end.
+var dialect = sqlstore.Dialect{
+	In: func(column string, values []string) (string, []any) {
+		args := make([]any, len(values))
+		for i, v := range values {
+			args[i] = v
+		}
+		return column + " IN (" + sqlstore.Placeholders(1, len(values)) + ")", args
+	},
+	Time: func(t time.Time) time.Time { return t.UTC() },
+}
+
+// SQLiteStorage keeps orders in a single database file. All writes go
+// through one connection, so transactions are serialised the way row locks
+// serialise them in Postgres; reads use a separate pool and see the last
+// committed state.
+type SQLiteStorage struct {
+	db     *sqlx.DB
+	reader *sqlx.DB
+	// relayMu and dispatchMu stand in for the Postgres advisory locks: the
+	// database file belongs to one process, so one relay and one webhook
+	// dispatcher at a time are enough.
+	relayMu    sync.Mutex
+	dispatchMu sync.Mutex
+}
+
+func NewSQLiteDB(cfg config.SQLite) (*SQLiteStorage, error) {
+	const op = "storage.sqlite.NewSQLiteDB"
+
+	if dir := filepath.Dir(cfg.Path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	dsn := dataSource(cfg)
+	if err := migrateUp(dsn); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	db, err := sqlx.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	db.SetMaxOpenConns(1)
+
+	reader, err := sqlx.Open("sqlite", dsn)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		reader.Close()
+		return nil, fmt.Errorf("%s: db.Ping error: %w", op, err)
+	}
+
+	return &SQLiteStorage{db: db, reader: reader}, nil
+}
+
+func dataSource(cfg config.SQLite) string {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "synchronous(NORMAL)")
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", cfg.BusyTimeout.Milliseconds()))
+	params.Set("_time_format", "sqlite")
+	return "file:" + cfg.Path + "?" + params.Encode()
+}
+
+// migrateUp applies the embedded migrations, so a fresh file is ready to use
+// without the separate migrator. It uses its own connection, which the
+// migration driver closes.
+func migrateUp(dsn string) error {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return err
+	}
+
+	driver, err := migratesqlite.WithInstance(db, &migratesqlite.Config{})
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("migration initialization failed: %w", err)
+	}
+
+	source, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		driver.Close()
+		return fmt.Errorf("migrations source: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "sqlite", driver)
+	if err != nil {
+		driver.Close()
+		return fmt.Errorf("migration initialization failed: %w", err)
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) CreateOrder(ctx context.Context, order *model.Order, e *event.Event) error {
+	const op = "storage.sqlite.CreateOrder"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	// A redelivered order is already stored together with its children.
+	if inserted, err := insertOrder(ctx, tx, order); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	} else if !inserted {
+		return errors.ErrAlreadyExists
+	}
+
+	if e != nil {
+		e.Order = order
+		if err := dialect.InsertOutbox(ctx, tx, e); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: transaction commit failed: %w", op, err)
+	}
+
+	return nil
+}
+
+// CreateOrders stores a batch of orders in one transaction without
+// recording events, skipping orders that already exist. It returns how many
+// orders were inserted; any failure rolls back the whole batch.
+func (s *SQLiteStorage) CreateOrders(ctx context.Context, orders []*model.Order) (int, error) {
+	const op = "storage.sqlite.CreateOrders"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	count := 0
+	for _, order := range orders {
+		inserted, err := insertOrder(ctx, tx, order)
+		if err != nil {
+			return 0, fmt.Errorf("%s: order %s: %w", op, order.OrderUID, err)
+		}
+		if inserted {
+			count++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: transaction commit failed: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// insertOrder writes order with its children and initial status history. It
+// reports false without writing anything when the order already exists.
+func insertOrder(ctx context.Context, tx *sqlx.Tx, order *model.Order) (bool, error) {
+	if order.Status == "" {
+		order.Status = model.StatusCreated
+	}
+	if order.StatusUpdatedAt.IsZero() {
+		order.StatusUpdatedAt = time.Now().UTC()
+	}
+
+	orderQuery := `
+		INSERT INTO orders (
+			order_uid, track_number, entry, locale, internal_signature,
+			customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard,
+			status, status_updated_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (order_uid) DO NOTHING
+	`
+	res, err := tx.ExecContext(ctx, orderQuery,
+		order.OrderUID,
+		order.TrackNumber,
+		order.Entry,
+		order.Locale,
+		order.InternalSignature,
+		order.CustomerID,
+		order.DeliveryService,
+		order.Shardkey,
+		order.SmID,
+		order.DateCreated.UTC(),
+		order.OofShard,
+		order.Status,
+		order.StatusUpdatedAt.UTC(),
+		time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("insert order failed: %w", err)
+	}
+
+	if inserted, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else if inserted == 0 {
+		return false, nil
+	}
+
+	if err := dialect.InsertStatusChange(ctx, tx, order.OrderUID, &model.StatusChange{
+		To:        order.Status,
+		Source:    "ingest",
+		ChangedAt: order.StatusUpdatedAt,
+	}); err != nil {
+		return false, err
+	}
+
+	deliveryQuery := `
+		INSERT INTO delivery (
+			order_uid, name, phone, zip, city, address, region, email
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err = tx.ExecContext(ctx, deliveryQuery,
+		order.OrderUID,
+		order.Delivery.Name,
+		order.Delivery.Phone,
+		order.Delivery.Zip,
+		order.Delivery.City,
+		order.Delivery.Address,
+		order.Delivery.Region,
+		order.Delivery.Email)
+	if err != nil {
+		return false, fmt.Errorf("insert delivery failed: %w", err)
+	}
+
+	paymentQuery := `
+		INSERT INTO payment (
+			order_uid, "transaction", request_id, currency, provider, amount,
+			payment_dt, bank, delivery_cost, goods_total, custom_fee
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	_, err = tx.ExecContext(ctx, paymentQuery,
+		order.OrderUID,
+		order.Payment.Transaction,
+		order.Payment.RequestID,
+		order.Payment.Currency,
+		order.Payment.Provider,
+		order.Payment.Amount,
+		order.Payment.PaymentDt,
+		order.Payment.Bank,
+		order.Payment.DeliveryCost,
+		order.Payment.GoodsTotal,
+		order.Payment.CustomFee)
+	if err != nil {
+		return false, fmt.Errorf("insert payment failed: %w", err)
+	}
+
+	itemQuery := `
+		INSERT INTO items (
+			order_uid, chrt_id, track_number, price, rid, name,
+			sale, size, total_price, nm_id, brand, status
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+	for _, item := range order.Items {
+		_, err = tx.ExecContext(ctx, itemQuery,
+			order.OrderUID,
+			item.ChrtID,
+			item.TrackNumber,
+			item.Price,
+			item.Rid,
+			item.Name,
+			item.Sale,
+			item.Size,
+			item.TotalPrice,
+			item.NmID,
+			item.Brand,
+			item.Status)
+		if err != nil {
+			return false, fmt.Errorf("insert item failed: %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+func (s *SQLiteStorage) GetOrder(ctx context.Context, orderUID string) (*model.Order, error) {
+	return getOrder(ctx, s.reader, orderUID)
+}
+
+// getOrder loads a full order through q, which is either the read pool or a
+// transaction.
+func getOrder(ctx context.Context, q sqlx.QueryerContext, orderUID string) (*model.Order, error) {
+	const op = "storage.sqlite.GetOrder"
+
+	order, err := dialect.GetOrder(ctx, q, orderUID)
+	if err != nil {
+		if stdErrors.Is(err, errors.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return order, nil
+}
+
+func (s *SQLiteStorage) GetAllOrders(ctx context.Context) (map[string]*model.Order, error) {
+	const op = "storage.sqlite.GetAllOrders"
+
+	orders := make(map[string]*model.Order)
+	err := s.StreamOrders(ctx, storage.OrderFilter{}, func(order *model.Order) error {
+		orders[order.OrderUID] = order
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return orders, nil
+}
+
+func (s *SQLiteStorage) ListOrders(ctx context.Context, afterUID string, limit int) ([]*model.Order, error) {
+	const op = "storage.sqlite.ListOrders"
+
+	uidsQuery := `
+		SELECT order_uid
+		FROM orders
+		WHERE order_uid > $1
+		ORDER BY order_uid
+		LIMIT $2
+	`
+	var uids []string
+	if err := s.reader.SelectContext(ctx, &uids, uidsQuery, afterUID, limit); err != nil {
+		return nil, fmt.Errorf("%s: get order uids failed: %w", op, err)
+	}
+
+	found, err := getOrders(ctx, s.reader, uids)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	orders := make([]*model.Order, 0, len(uids))
+	for _, uid := range uids {
+		if order, ok := found[uid]; ok {
+			orders = append(orders, order)
+		}
+	}
+
+	return orders, nil
+}
+
+func (s *SQLiteStorage) GetOrders(ctx context.Context, orderUIDs []string) (map[string]*model.Order, error) {
+	return getOrders(ctx, s.reader, orderUIDs)
+}
+
+// getOrders loads many orders with one query per table. UIDs that do not
+// exist are absent from the result.
+func getOrders(ctx context.Context, q sqlx.QueryerContext, orderUIDs []string) (map[string]*model.Order, error) {
+	const op = "storage.sqlite.GetOrders"
+
+	orders, err := dialect.GetOrders(ctx, q, orderUIDs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return orders, nil
+}
+
+func (s *SQLiteStorage) Close() error {
+	return stdErrors.Join(s.reader.Close(), s.db.Close())
+}
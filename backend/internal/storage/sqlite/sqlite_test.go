@@ -0,0 +1,211 @@
+package sqlite_test
+
+import (
+	"L0-wbtech/internal/config"
+	"L0-wbtech/internal/storage/sqlite"
+	"L0-wbtech/internal/storage/storagetest"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.RunBackend(t, storagetest.SQLite())
+}
+
+func open(t *testing.T, path string, busyTimeout time.Duration) *sqlite.SQLiteStorage {
+	t.Helper()
+
+	s, err := sqlite.NewSQLiteDB(config.SQLite{Path: path, BusyTimeout: busyTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// rawDB opens the file without the storage, the way another process would.
+func rawDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func latestMigration(t *testing.T) uint {
+	t.Helper()
+
+	files, err := filepath.Glob("migrations/*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uint(len(files))
+}
+
+func schemaVersion(t *testing.T, db *sql.DB) (uint, bool) {
+	t.Helper()
+
+	var version uint
+	var dirty bool
+	if err := db.QueryRow(`SELECT version, dirty FROM schema_migrations`).Scan(&version, &dirty); err != nil {
+		t.Fatal(err)
+	}
+	return version, dirty
+}
+
+func TestReopenKeepsData(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "orders.db")
+
+	s := open(t, path, time.Second)
+	order := storagetest.NewOrders().Next()
+	if err := s.CreateOrder(ctx, order, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Opening an up to date file runs the migrations again, which must be
+	// a no-op.
+	s = open(t, path, time.Second)
+	if _, err := s.GetOrder(ctx, order.OrderUID); err != nil {
+		t.Fatalf("order lost on reopen: %v", err)
+	}
+	if version, dirty := schemaVersion(t, rawDB(t, path)); version != latestMigration(t) || dirty {
+		t.Errorf("schema at version %d (dirty %t), want %d", version, dirty, latestMigration(t))
+	}
+}
+
+func TestOpenMigratesOlderFile(t *testing.T) {
+	const oldVersion = 3
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "orders.db")
+
+	// A file left behind by a release that knew only the first migrations.
+	db := rawDB(t, path)
+	driver, err := migratesqlite.WithInstance(db, &migratesqlite.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := iofs.New(os.DirFS("migrations"), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.NewWithInstance("iofs", source, "sqlite", driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Migrate(oldVersion); err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := schemaVersion(t, db); version != oldVersion {
+		t.Fatalf("prepared schema at version %d, want %d", version, oldVersion)
+	}
+
+	s := open(t, path, time.Second)
+	if version, dirty := schemaVersion(t, db); version != latestMigration(t) || dirty {
+		t.Errorf("schema at version %d (dirty %t) after open, want %d", version, dirty, latestMigration(t))
+	}
+
+	// The tables of the later migrations are in place.
+	order := storagetest.NewOrders().Next()
+	if err := s.CreateOrder(ctx, order, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.OrdersChangedSince(ctx, time.Time{}); err != nil {
+		t.Errorf("change tracking after upgrade: %v", err)
+	}
+}
+
+func TestJournalModeIsWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.db")
+	open(t, path, time.Second)
+
+	// The mode is stored in the file, so any connection reports it.
+	var mode string
+	if err := rawDB(t, path).QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.EqualFold(mode, "wal") {
+		t.Errorf("journal mode %q, want wal", mode)
+	}
+}
+
+func TestWriteLockedByAnotherConnection(t *testing.T) {
+	const hold = 150 * time.Millisecond
+
+	for _, tc := range []struct {
+		name        string
+		busyTimeout time.Duration
+		wantErr     bool
+	}{
+		{"waits out a short lock", 5 * time.Second, false},
+		{"gives up after the busy timeout", 20 * time.Millisecond, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			path := filepath.Join(t.TempDir(), "orders.db")
+			s := open(t, path, tc.busyTimeout)
+
+			gen := storagetest.NewOrders()
+			stored := gen.Next()
+			if err := s.CreateOrder(ctx, stored, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			conn, err := rawDB(t, path).Conn(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+				t.Fatal(err)
+			}
+			released := make(chan struct{})
+			time.AfterFunc(hold, func() {
+				defer close(released)
+				if _, err := conn.ExecContext(ctx, `ROLLBACK`); err != nil {
+					t.Error(err)
+				}
+			})
+
+			// WAL lets readers through while another connection writes.
+			start := time.Now()
+			if _, err := s.GetOrder(ctx, stored.OrderUID); err != nil {
+				t.Errorf("read during a foreign write: %v", err)
+			}
+			if waited := time.Since(start); waited >= hold {
+				t.Errorf("read waited %v for the writer", waited)
+			}
+
+			err = s.CreateOrder(ctx, gen.Next(), nil)
+			<-released
+			if tc.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "SQLITE_BUSY") {
+					t.Errorf("write under a held lock returned %v, want SQLITE_BUSY", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("write after the lock was released: %v", err)
+			}
+			if waited := time.Since(start); waited < hold {
+				t.Errorf("write went through after %v, before the lock was released", waited)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package sqlite
+
+import (
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage"
+	"context"
+	"fmt"
+)
+
+// StreamOrders pages through matching orders by order_uid inside a single
+// read transaction, which in WAL mode sees one snapshot of the database, so
+// memory stays bounded by the batch size and the result is consistent even
+// while orders keep arriving.
+func (s *SQLiteStorage) StreamOrders(ctx context.Context, filter storage.OrderFilter, fn func(*model.Order) error) error {
+	const op = "storage.sqlite.StreamOrders"
+
+	tx, err := s.reader.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := dialect.StreamOrders(ctx, tx, filter, fn); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
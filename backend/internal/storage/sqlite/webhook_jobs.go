@@ -0,0 +1,157 @@
+package sqlite
+
+import (
+	"L0-wbtech/internal/outbox"
+	"L0-wbtech/internal/storage/sqlstore"
+	"L0-wbtech/internal/webhook"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const webhookJobColumns = `
+	id, subscription_id, event_id, event_type, payload,
+	attempt, next_attempt_at, created_at
+`
+
+func (s *SQLiteStorage) DispatchOutbox(
+	ctx context.Context,
+	limit int,
+	plan func(ctx context.Context, records []outbox.Record) ([]webhook.Job, error),
+) (int, error) {
+	const op = "storage.sqlite.DispatchOutbox"
+
+	if !s.dispatchMu.TryLock() {
+		return 0, nil
+	}
+	defer s.dispatchMu.Unlock()
+
+	var records []outbox.Record
+	err := s.reader.SelectContext(ctx, &records, `
+		SELECT id, event_id, order_uid, event_type, payload, created_at
+		FROM outbox
+		WHERE dispatched_at IS NULL
+		ORDER BY id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: select outbox failed: %w", op, err)
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	jobs, err := plan(ctx, records)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	jobQuery := `
+		INSERT INTO webhook_jobs (
+			subscription_id, event_id, event_type, payload,
+			attempt, next_attempt_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+	`
+	for _, j := range jobs {
+		_, err := tx.ExecContext(ctx, jobQuery,
+			j.SubscriptionID,
+			j.EventID,
+			j.EventType,
+			j.Payload,
+			j.Attempt,
+			j.NextAttemptAt.UTC(),
+			j.CreatedAt.UTC())
+		if err != nil {
+			return 0, fmt.Errorf("%s: insert webhook job failed: %w", op, err)
+		}
+	}
+
+	ids := make([]int64, len(records))
+	for i, r := range records {
+		ids[i] = r.ID
+	}
+	query, args, err := sqlx.In(`UPDATE outbox SET dispatched_at = ? WHERE id IN (?)`, time.Now().UTC(), ids)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return 0, fmt.Errorf("%s: mark dispatched failed: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: transaction commit failed: %w", op, err)
+	}
+
+	return len(records), nil
+}
+
+func (s *SQLiteStorage) ClaimWebhookJobs(
+	ctx context.Context,
+	limit int,
+	lease time.Duration,
+	skip []int64,
+) ([]webhook.Job, error) {
+	const op = "storage.sqlite.ClaimWebhookJobs"
+
+	// A NULL list would match no subscription at all.
+	if skip == nil {
+		skip = []int64{}
+	}
+
+	skipped, err := json.Marshal(skip)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now().UTC()
+	query := `
+		UPDATE webhook_jobs
+		SET next_attempt_at = $1
+		WHERE id IN (
+			SELECT j.id
+			FROM webhook_jobs j
+			JOIN webhook_subscriptions s ON s.id = j.subscription_id
+			WHERE s.active
+				AND j.next_attempt_at <= $2
+				AND j.subscription_id NOT IN (SELECT value FROM json_each($4))
+			ORDER BY j.next_attempt_at, j.id
+			LIMIT $3
+		)
+		RETURNING ` + webhookJobColumns
+
+	var jobs []webhook.Job
+	if err := s.db.SelectContext(ctx, &jobs, query, now.Add(lease), now, limit, string(skipped)); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return jobs, nil
+}
+
+func (s *SQLiteStorage) RetryWebhookJob(ctx context.Context, id int64, attempt int, at time.Time) error {
+	const op = "storage.sqlite.RetryWebhookJob"
+
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE webhook_jobs SET attempt = $2, next_attempt_at = $3 WHERE id = $1`, id, attempt, at.UTC())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return sqlstore.ExpectAffected(res)
+}
+
+func (s *SQLiteStorage) DeleteWebhookJob(ctx context.Context, id int64) error {
+	const op = "storage.sqlite.DeleteWebhookJob"
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM webhook_jobs WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
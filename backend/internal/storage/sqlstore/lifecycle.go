@@ -0,0 +1,195 @@
+package sqlstore
+
+import (
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/pkg/errors"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ItemRow is an item with its row id, which returns update by.
+type ItemRow struct {
+	ID int64 `db:"id"`
+	model.Item
+}
+
+type Adjustment struct {
+	Kind      string
+	ChrtID    *int64
+	Rid       *string
+	Amount    int
+	Reason    string
+	Actor     string
+	Source    string
+	CreatedAt time.Time
+}
+
+// LockStatus reads the status of an order inside tx and keeps it from
+// changing until tx ends.
+func (d Dialect) LockStatus(ctx context.Context, tx *sqlx.Tx, orderUID string) (model.OrderStatus, error) {
+	var current model.OrderStatus
+	err := tx.GetContext(ctx, &current,
+		`SELECT status FROM orders WHERE order_uid = $1`+d.Lock, orderUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", errors.ErrNotFound
+		}
+		return "", fmt.Errorf("lock order failed: %w", err)
+	}
+	return current, nil
+}
+
+// LockItems reads the items of an order in insertion order and keeps them
+// from changing until tx ends.
+func (d Dialect) LockItems(ctx context.Context, tx *sqlx.Tx, orderUID string) ([]ItemRow, error) {
+	var items []ItemRow
+	err := tx.SelectContext(ctx, &items, `
+		SELECT
+			id, chrt_id, track_number, price, rid,
+			name, sale, size, total_price, nm_id, brand, status, returned_at
+		FROM items
+		WHERE order_uid = $1
+		ORDER BY id
+	`+d.Lock, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("lock items failed: %w", err)
+	}
+	return items, nil
+}
+
+// TransitionStatus moves an order to change.To and records the step.
+func (d Dialect) TransitionStatus(ctx context.Context, tx *sqlx.Tx, orderUID string, change *model.StatusChange) error {
+	current, err := d.LockStatus(ctx, tx, orderUID)
+	if err != nil {
+		return err
+	}
+
+	if !current.CanTransitionTo(change.To) {
+		return fmt.Errorf("%s -> %s: %w", current, change.To, errors.ErrInvalidTransition)
+	}
+	change.From = current
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE orders SET status = $2, status_updated_at = $3 WHERE order_uid = $1`,
+		orderUID, change.To, d.Time(change.ChangedAt))
+	if err != nil {
+		return fmt.Errorf("update status failed: %w", err)
+	}
+
+	return d.InsertStatusChange(ctx, tx, orderUID, change)
+}
+
+func (d Dialect) InsertStatusChange(ctx context.Context, tx *sqlx.Tx, orderUID string, change *model.StatusChange) error {
+	historyQuery := `
+		INSERT INTO order_status_history (
+			order_uid, from_status, to_status, reason, actor, source, changed_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := tx.ExecContext(ctx, historyQuery,
+		orderUID,
+		change.From,
+		change.To,
+		change.Reason,
+		change.Actor,
+		change.Source,
+		d.Time(change.ChangedAt))
+	if err != nil {
+		return fmt.Errorf("insert status history failed: %w", err)
+	}
+	return nil
+}
+
+func (d Dialect) InsertAdjustment(ctx context.Context, tx *sqlx.Tx, orderUID string, adj Adjustment) error {
+	adjustmentQuery := `
+		INSERT INTO order_adjustments (
+			order_uid, kind, chrt_id, rid, amount, reason, actor, source, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := tx.ExecContext(ctx, adjustmentQuery,
+		orderUID,
+		adj.Kind,
+		adj.ChrtID,
+		adj.Rid,
+		adj.Amount,
+		adj.Reason,
+		adj.Actor,
+		adj.Source,
+		d.Time(adj.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("insert adjustment failed: %w", err)
+	}
+	return nil
+}
+
+// RecordEvent attaches the order as it looks inside tx to e and stores the
+// event in the outbox.
+func (d Dialect) RecordEvent(ctx context.Context, tx *sqlx.Tx, orderUID string, e *event.Event) error {
+	order, err := d.GetOrder(ctx, tx, orderUID)
+	if err != nil {
+		return fmt.Errorf("load order snapshot failed: %w", err)
+	}
+	e.Order = order
+	return d.InsertOutbox(ctx, tx, e)
+}
+
+func (d Dialect) InsertOutbox(ctx context.Context, tx *sqlx.Tx, e *event.Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal event failed: %w", err)
+	}
+
+	outboxQuery := `
+		INSERT INTO outbox (event_id, order_uid, event_type, payload, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err = tx.ExecContext(ctx, outboxQuery,
+		e.ID,
+		e.OrderUID,
+		e.Type,
+		payload,
+		d.Time(e.OccurredAt))
+	if err != nil {
+		return fmt.Errorf("insert outbox failed: %w", err)
+	}
+	return nil
+}
+
+// PickReturnedItems resolves every reference to a distinct item that has not
+// been returned yet and returns their indexes.
+func PickReturnedItems(items []ItemRow, refs []model.ItemRef) ([]int, error) {
+	taken := make(map[int]bool, len(refs))
+	picked := make([]int, 0, len(refs))
+
+	for _, ref := range refs {
+		found, alreadyReturned := -1, false
+		for i, item := range items {
+			if taken[i] || !ref.Matches(item.Item) {
+				continue
+			}
+			if item.ReturnedAt != nil {
+				alreadyReturned = true
+				continue
+			}
+			found = i
+			break
+		}
+
+		switch {
+		case found >= 0:
+			taken[found] = true
+			picked = append(picked, found)
+		case alreadyReturned:
+			return nil, fmt.Errorf("item %+v already returned: %w", ref, errors.ErrInvalidTransition)
+		default:
+			return nil, fmt.Errorf("item %+v: %w", ref, errors.ErrNotFound)
+		}
+	}
+
+	return picked, nil
+}
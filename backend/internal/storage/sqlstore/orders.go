@@ -0,0 +1,194 @@
+package sqlstore
+
+import (
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage"
+	"L0-wbtech/pkg/errors"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const streamBatchSize = 500
+
+// GetOrder loads a full order through q, which is either a pool or a
+// transaction.
+func (d Dialect) GetOrder(ctx context.Context, q sqlx.QueryerContext, orderUID string) (*model.Order, error) {
+	orders, err := d.GetOrders(ctx, q, []string{orderUID})
+	if err != nil {
+		return nil, err
+	}
+	order, ok := orders[orderUID]
+	if !ok {
+		return nil, errors.ErrNotFound
+	}
+	return order, nil
+}
+
+// GetOrders loads many orders with one query per table. UIDs that do not
+// exist are absent from the result, and so are orders without delivery or
+// payment.
+func (d Dialect) GetOrders(ctx context.Context, q sqlx.QueryerContext, orderUIDs []string) (map[string]*model.Order, error) {
+	orders := make(map[string]*model.Order, len(orderUIDs))
+	if len(orderUIDs) == 0 {
+		return orders, nil
+	}
+	match, args := d.In("order_uid", orderUIDs)
+
+	ordersQuery := `
+		SELECT
+			order_uid, track_number, entry, locale,
+			internal_signature, customer_id, delivery_service,
+			shardkey, sm_id, date_created, oof_shard,
+			status, status_updated_at
+		FROM orders
+		WHERE ` + match
+	var rows []*model.Order
+	if err := sqlx.SelectContext(ctx, q, &rows, ordersQuery, args...); err != nil {
+		return nil, fmt.Errorf("get orders failed: %w", err)
+	}
+	for _, order := range rows {
+		order.Items = []model.Item{}
+		orders[order.OrderUID] = order
+	}
+
+	deliveryQuery := `
+		SELECT order_uid, name, phone, zip, city, address, region, email
+		FROM delivery
+		WHERE ` + match
+	var deliveries []struct {
+		OrderUID string `db:"order_uid"`
+		model.Delivery
+	}
+	if err := sqlx.SelectContext(ctx, q, &deliveries, deliveryQuery, args...); err != nil {
+		return nil, fmt.Errorf("get deliveries failed: %w", err)
+	}
+	hasDelivery := make(map[string]bool, len(deliveries))
+	for _, d := range deliveries {
+		if order, ok := orders[d.OrderUID]; ok {
+			order.Delivery = d.Delivery
+			hasDelivery[d.OrderUID] = true
+		}
+	}
+
+	paymentQuery := `
+		SELECT
+			order_uid, id, "transaction", request_id, currency, provider,
+			amount, payment_dt, bank, delivery_cost, goods_total, custom_fee
+		FROM payment
+		WHERE ` + match
+	var payments []struct {
+		OrderUID string `db:"order_uid"`
+		model.Payment
+	}
+	if err := sqlx.SelectContext(ctx, q, &payments, paymentQuery, args...); err != nil {
+		return nil, fmt.Errorf("get payments failed: %w", err)
+	}
+	hasPayment := make(map[string]bool, len(payments))
+	for _, p := range payments {
+		if order, ok := orders[p.OrderUID]; ok {
+			order.Payment = p.Payment
+			hasPayment[p.OrderUID] = true
+		}
+	}
+
+	itemsQuery := `
+		SELECT
+			order_uid, chrt_id, track_number, price, rid,
+			name, sale, size, total_price, nm_id, brand, status, returned_at
+		FROM items
+		WHERE ` + match + `
+		ORDER BY id
+	`
+	var items []struct {
+		OrderUID string `db:"order_uid"`
+		model.Item
+	}
+	if err := sqlx.SelectContext(ctx, q, &items, itemsQuery, args...); err != nil {
+		return nil, fmt.Errorf("get items failed: %w", err)
+	}
+	for _, item := range items {
+		if order, ok := orders[item.OrderUID]; ok {
+			order.Items = append(order.Items, item.Item)
+		}
+	}
+
+	for uid := range orders {
+		if !hasDelivery[uid] || !hasPayment[uid] {
+			delete(orders, uid)
+		}
+	}
+
+	return orders, nil
+}
+
+// StreamOrders pages through the orders matching filter by order_uid
+// inside tx, so memory stays bounded by the batch size. The caller picks a
+// transaction that sees one snapshot, which keeps the result consistent
+// while orders keep arriving.
+func (d Dialect) StreamOrders(ctx context.Context, tx *sqlx.Tx, filter storage.OrderFilter, fn func(*model.Order) error) error {
+	where, args := d.FilterClause(filter)
+	args = append(args, "", streamBatchSize)
+	uidsQuery := fmt.Sprintf(`
+		SELECT order_uid
+		FROM orders
+		WHERE %s AND order_uid > $%d
+		ORDER BY order_uid
+		LIMIT $%d
+	`, where, len(args)-1, len(args))
+
+	for {
+		var uids []string
+		if err := tx.SelectContext(ctx, &uids, uidsQuery, args...); err != nil {
+			return fmt.Errorf("get order uids failed: %w", err)
+		}
+		if len(uids) == 0 {
+			return nil
+		}
+
+		orders, err := d.GetOrders(ctx, tx, uids)
+		if err != nil {
+			return err
+		}
+		for _, uid := range uids {
+			if order, ok := orders[uid]; ok {
+				if err := fn(order); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(uids) < streamBatchSize {
+			return nil
+		}
+		args[len(args)-2] = uids[len(uids)-1]
+	}
+}
+
+// FilterClause turns filter into a WHERE condition with placeholders
+// numbered from $1, and its arguments.
+func (d Dialect) FilterClause(filter storage.OrderFilter) (string, []any) {
+	conds := []string{"TRUE"}
+	var args []any
+
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if !filter.From.IsZero() {
+		add("date_created >= $%d", d.Time(filter.From))
+	}
+	if !filter.To.IsZero() {
+		add("date_created < $%d", d.Time(filter.To))
+	}
+	if filter.DeliveryService != "" {
+		add("delivery_service = $%d", filter.DeliveryService)
+	}
+	if filter.CustomerID != "" {
+		add("customer_id = $%d", filter.CustomerID)
+	}
+
+	return strings.Join(conds, " AND "), args
+}
@@ -0,0 +1,47 @@
+// Package sqlstore holds the parts of the SQL backends that do not depend
+// on the database: reading orders back, building filters and recording
+// lifecycle steps. Both backends number their placeholders from $1.
+package sqlstore
+
+import (
+	"L0-wbtech/pkg/errors"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Dialect is what the backends spell differently.
+type Dialect struct {
+	// In returns a condition matching column against any of values, with
+	// placeholders numbered from $1, and its arguments.
+	In func(column string, values []string) (string, []any)
+	// Time prepares a time for binding. SQLite compares times as text, so
+	// it needs them all in UTC.
+	Time func(time.Time) time.Time
+	// Lock is appended to reads of the rows a transaction is about to
+	// change.
+	Lock string
+}
+
+// Placeholders returns "$from, $from+1, ..." for n arguments.
+func Placeholders(from, n int) string {
+	list := make([]string, n)
+	for i := range list {
+		list[i] = fmt.Sprintf("$%d", from+i)
+	}
+	return strings.Join(list, ", ")
+}
+
+// ExpectAffected reports errors.ErrNotFound when an update or delete matched
+// no row.
+func ExpectAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.ErrNotFound
+	}
+	return nil
+}
@@ -1,13 +1,60 @@
 package storage
 
 import (
+	"L0-wbtech/internal/event"
 	"L0-wbtech/internal/model"
 	"context"
+	"time"
 )
 
+// Storage persists orders. Every mutation takes the domain event describing
+// it; a non-nil event is completed with the resulting order and written to
+// the outbox in the same transaction as the change.
 type Storage interface {
-	CreateOrder(ctx context.Context, order *model.Order) error
+	CreateOrder(ctx context.Context, order *model.Order, e *event.Event) error
 	GetOrder(ctx context.Context, orderUID string) (*model.Order, error)
+	// GetOrders loads many orders at once. UIDs that do not exist are
+	// absent from the result.
+	GetOrders(ctx context.Context, orderUIDs []string) (map[string]*model.Order, error)
 	GetAllOrders(ctx context.Context) (map[string]*model.Order, error)
+	// ListOrders returns up to limit orders with order_uid greater than
+	// afterUID, ordered by order_uid.
+	ListOrders(ctx context.Context, afterUID string, limit int) ([]*model.Order, error)
+	UpdateOrderStatus(ctx context.Context, orderUID string, change *model.StatusChange, e *event.Event) error
+	CancelOrder(ctx context.Context, orderUID string, cancellation *model.Cancellation, e *event.Event) error
+	ReturnItems(ctx context.Context, orderUID string, ret *model.ItemReturn, e *event.Event) error
 	Close() error
 }
+
+// OrderFilter selects orders for bulk reads. Zero fields match everything;
+// From is inclusive and To exclusive, both on date_created.
+type OrderFilter struct {
+	From            time.Time
+	To              time.Time
+	DeliveryService string
+	CustomerID      string
+}
+
+// OrderStreamer walks every order matching a filter without holding the
+// whole result in memory. Returning an error from fn stops the walk.
+type OrderStreamer interface {
+	StreamOrders(ctx context.Context, filter OrderFilter, fn func(*model.Order) error) error
+}
+
+// BatchWriter stores many orders in one go without recording events, for
+// bulk loads such as imports of historical data. Orders that already exist
+// are skipped; the returned count covers only inserted orders.
+type BatchWriter interface {
+	CreateOrders(ctx context.Context, orders []*model.Order) (int, error)
+}
+
+// ChangeTracker reports orders by when storage last wrote them, so that a
+// cache restored from an older copy can catch up. Times come from the
+// storage clock and have no relation to the timestamps inside orders.
+type ChangeTracker interface {
+	// HighWaterMark returns the latest write time, zero if there are no
+	// orders.
+	HighWaterMark(ctx context.Context) (time.Time, error)
+	// OrdersChangedSince returns the orders written after since.
+	OrdersChangedSince(ctx context.Context, since time.Time) (map[string]*model.Order, error)
+}
@@ -0,0 +1,118 @@
+package storagetest
+
+import (
+	"L0-wbtech/internal/config"
+	"L0-wbtech/internal/storage"
+	"L0-wbtech/internal/storage/memory"
+	"L0-wbtech/internal/storage/postgres"
+	"L0-wbtech/internal/storage/postgres/pgtest"
+	"L0-wbtech/internal/storage/sqlite"
+	"context"
+	stdErrors "errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Backend opens a fresh storage for the suite. The returned function
+// releases everything Open acquired.
+type Backend struct {
+	Name string
+	Open func(ctx context.Context) (storage.Storage, func() error, error)
+}
+
+func Memory() Backend {
+	return Backend{
+		Name: "memory",
+		Open: func(context.Context) (storage.Storage, func() error, error) {
+			s := memory.New()
+			return s, s.Close, nil
+		},
+	}
+}
+
+// SQLite runs the suite against a database file in a temporary directory.
+func SQLite() Backend {
+	return Backend{
+		Name: "sqlite",
+		Open: func(context.Context) (storage.Storage, func() error, error) {
+			dir, err := os.MkdirTemp("", "storagetest-")
+			if err != nil {
+				return nil, nil, err
+			}
+
+			s, err := sqlite.NewSQLiteDB(config.SQLite{
+				Path:        filepath.Join(dir, "orders.db"),
+				BusyTimeout: 5 * time.Second,
+			})
+			if err != nil {
+				return nil, nil, stdErrors.Join(err, os.RemoveAll(dir))
+			}
+			return s, func() error { return stdErrors.Join(s.Close(), os.RemoveAll(dir)) }, nil
+		},
+	}
+}
+
+// LocalPostgres runs the suite against a temporary cluster started from the
+// local Postgres installation. Open fails with pgtest.ErrUnavailable when
+// there is none.
+func LocalPostgres(migrationsPath string) Backend {
+	return Backend{
+		Name: "postgres",
+		Open: func(ctx context.Context) (storage.Storage, func() error, error) {
+			server, err := pgtest.Start(ctx, migrationsPath)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			s, err := postgres.NewPostgresDB(server.Config())
+			if err != nil {
+				return nil, nil, stdErrors.Join(err, server.Stop())
+			}
+			return s, func() error { return stdErrors.Join(s.Close(), server.Stop()) }, nil
+		},
+	}
+}
+
+// Postgres runs the suite against the existing database at dsn, a
+// postgres:// URL, after applying the migrations. The checks leave their
+// orders behind and publish whatever is pending in the outbox, so the
+// database should be a scratch one.
+func Postgres(dsn, migrationsPath string) Backend {
+	return Backend{
+		Name: "postgres",
+		Open: func(context.Context) (storage.Storage, func() error, error) {
+			if err := pgtest.Migrate(dsn, migrationsPath); err != nil {
+				return nil, nil, err
+			}
+
+			s, err := postgres.Open(dsn)
+			if err != nil {
+				return nil, nil, err
+			}
+			return s, s.Close, nil
+		},
+	}
+}
+
+// RunBackend opens b, runs the suite against it and closes it when the test
+// ends. A backend without its binaries is skipped.
+func RunBackend(t *testing.T, b Backend) {
+	t.Helper()
+
+	s, closeFn, err := b.Open(t.Context())
+	if stdErrors.Is(err, pgtest.ErrUnavailable) {
+		t.Skipf("%s: %v", b.Name, err)
+	}
+	if err != nil {
+		t.Fatalf("%s: %v", b.Name, err)
+	}
+	t.Cleanup(func() {
+		if err := closeFn(); err != nil {
+			t.Errorf("%s: close: %v", b.Name, err)
+		}
+	})
+
+	Run(t, s)
+}
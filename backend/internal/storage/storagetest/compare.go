@@ -0,0 +1,44 @@
+package storagetest
+
+import (
+	"L0-wbtech/internal/model"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Compare reports the first difference between a stored order and the one
+// that was written. Generated keys, status fields and time zones are
+// ignored; timestamps are compared at the microsecond precision Postgres
+// keeps.
+func Compare(want, got *model.Order) error {
+	if got == nil {
+		return fmt.Errorf("order %s: got nil", want.OrderUID)
+	}
+
+	w, g := *want, *got
+	w.Items, g.Items = nil, nil
+	w.Payment.ID, g.Payment.ID = 0, 0
+	w.Status, g.Status = "", ""
+	w.StatusUpdatedAt, g.StatusUpdatedAt = time.Time{}, time.Time{}
+	if !w.DateCreated.Truncate(time.Microsecond).Equal(g.DateCreated) {
+		return fmt.Errorf("order %s: date_created %v, want %v", want.OrderUID, g.DateCreated, w.DateCreated)
+	}
+	w.DateCreated = g.DateCreated
+
+	if !reflect.DeepEqual(w, g) {
+		return fmt.Errorf("order %s: stored\n\t%+v\nwant\n\t%+v", want.OrderUID, g, w)
+	}
+
+	if len(got.Items) != len(want.Items) {
+		return fmt.Errorf("order %s: %d items, want %d", want.OrderUID, len(got.Items), len(want.Items))
+	}
+	for i := range want.Items {
+		wi, gi := want.Items[i], got.Items[i]
+		wi.ReturnedAt, gi.ReturnedAt = nil, nil
+		if wi != gi {
+			return fmt.Errorf("order %s: item %d is\n\t%+v\nwant\n\t%+v", want.OrderUID, i, gi, wi)
+		}
+	}
+	return nil
+}
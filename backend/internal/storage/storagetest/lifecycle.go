@@ -0,0 +1,173 @@
+package storagetest
+
+import (
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage"
+	"L0-wbtech/pkg/errors"
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"time"
+)
+
+// twoItemOrder returns an order with two items worth 300 and 500 and a
+// payment that adds up.
+func twoItemOrder(gen *Orders) *model.Order {
+	order := gen.Next()
+	first, second := order.Items[0], order.Items[0]
+	first.ChrtID, first.Rid, first.TotalPrice = 101, order.OrderUID+"-a", 300
+	second.ChrtID, second.Rid, second.TotalPrice = 102, order.OrderUID+"-b", 500
+	order.Items = []model.Item{first, second}
+	order.Payment.GoodsTotal = 800
+	order.Payment.Amount = 800 + order.Payment.DeliveryCost
+	return order
+}
+
+func statusChange(to model.OrderStatus, at time.Time) *model.StatusChange {
+	return &model.StatusChange{
+		To:        to,
+		Reason:    "conformance check",
+		Actor:     "storagetest",
+		Source:    "storagetest",
+		ChangedAt: at,
+	}
+}
+
+// expectState reads the order back and checks its status and payment.
+func expectState(ctx context.Context, s storage.Storage, uid string, status model.OrderStatus, changedAt time.Time, amount, goods int) (*model.Order, error) {
+	got, err := s.GetOrder(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
+	if got.Status != status {
+		return nil, fmt.Errorf("status %q, want %q", got.Status, status)
+	}
+	if !got.StatusUpdatedAt.Equal(changedAt) {
+		return nil, fmt.Errorf("status_updated_at %v, want %v", got.StatusUpdatedAt, changedAt)
+	}
+	if got.Payment.Amount != amount || got.Payment.GoodsTotal != goods {
+		return nil, fmt.Errorf("payment amount %d, goods total %d, want %d and %d",
+			got.Payment.Amount, got.Payment.GoodsTotal, amount, goods)
+	}
+	return got, nil
+}
+
+func checkLifecycle(ctx context.Context, s storage.Storage, gen *Orders) error {
+	order := twoItemOrder(gen)
+	uid, amount := order.OrderUID, order.Payment.Amount
+	if err := s.CreateOrder(ctx, order, nil); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	at := time.Now().UTC().Truncate(time.Microsecond)
+	for _, to := range []model.OrderStatus{model.StatusPaid, model.StatusAssembling, model.StatusShipped} {
+		at = at.Add(time.Second)
+		e := event.New(event.OrderStatusChanged, uid)
+		if err := s.UpdateOrderStatus(ctx, uid, statusChange(to, at), &e); err != nil {
+			return fmt.Errorf("update to %s: %w", to, err)
+		}
+		if e.Order == nil || e.Order.Status != to {
+			return fmt.Errorf("update to %s did not complete the event with the new order", to)
+		}
+		if e.Status == nil || e.Status.To != to {
+			return fmt.Errorf("update to %s: event status %+v", to, e.Status)
+		}
+	}
+	shippedAt := at
+	if _, err := expectState(ctx, s, uid, model.StatusShipped, shippedAt, amount, 800); err != nil {
+		return err
+	}
+
+	// Illegal moves leave the order as it was.
+	for _, to := range []model.OrderStatus{model.StatusCreated, model.StatusPaid, model.StatusCancelled} {
+		err := s.UpdateOrderStatus(ctx, uid, statusChange(to, at.Add(time.Second)), nil)
+		if !stdErrors.Is(err, errors.ErrInvalidTransition) {
+			return fmt.Errorf("shipped -> %s returned %v, want %v", to, err, errors.ErrInvalidTransition)
+		}
+	}
+	if err := s.CancelOrder(ctx, uid, &model.Cancellation{At: at.Add(time.Second)}, nil); !stdErrors.Is(err, errors.ErrInvalidTransition) {
+		return fmt.Errorf("cancel of a shipped order returned %v, want %v", err, errors.ErrInvalidTransition)
+	}
+	if _, err := expectState(ctx, s, uid, model.StatusShipped, shippedAt, amount, 800); err != nil {
+		return fmt.Errorf("after rejected changes: %w", err)
+	}
+
+	// A partial return refunds the item and keeps the status.
+	at = at.Add(time.Second)
+	ret := &model.ItemReturn{Items: []model.ItemRef{{ChrtID: 101}}, Reason: "damaged", At: at}
+	e := event.New(event.OrderItemsReturned, uid)
+	if err := s.ReturnItems(ctx, uid, ret, &e); err != nil {
+		return fmt.Errorf("return: %w", err)
+	}
+	if ret.Refund != 300 || e.Refund != 300 {
+		return fmt.Errorf("return refunded %d, event says %d, want 300", ret.Refund, e.Refund)
+	}
+	got, err := expectState(ctx, s, uid, model.StatusShipped, shippedAt, amount-300, 500)
+	if err != nil {
+		return fmt.Errorf("after a partial return: %w", err)
+	}
+	if got.Items[0].ReturnedAt == nil || !got.Items[0].ReturnedAt.Equal(at) || got.Items[1].ReturnedAt != nil {
+		return fmt.Errorf("returned_at %v and %v, want %v and none", got.Items[0].ReturnedAt, got.Items[1].ReturnedAt, at)
+	}
+
+	again := &model.ItemReturn{Items: []model.ItemRef{{Rid: order.Items[0].Rid}}, At: at}
+	if err := s.ReturnItems(ctx, uid, again, nil); !stdErrors.Is(err, errors.ErrInvalidTransition) {
+		return fmt.Errorf("second return of an item returned %v, want %v", err, errors.ErrInvalidTransition)
+	}
+	unknown := &model.ItemReturn{Items: []model.ItemRef{{ChrtID: 999}}, At: at}
+	if err := s.ReturnItems(ctx, uid, unknown, nil); !stdErrors.Is(err, errors.ErrNotFound) {
+		return fmt.Errorf("return of an unknown item returned %v, want %v", err, errors.ErrNotFound)
+	}
+
+	// Returning the last item returns the order, which is then final.
+	at = at.Add(time.Second)
+	ret = &model.ItemReturn{Items: []model.ItemRef{{Rid: order.Items[1].Rid}}, Reason: "unwanted", At: at}
+	if err := s.ReturnItems(ctx, uid, ret, nil); err != nil {
+		return fmt.Errorf("return: %w", err)
+	}
+	if ret.Refund != 500 {
+		return fmt.Errorf("return refunded %d, want 500", ret.Refund)
+	}
+	if _, err := expectState(ctx, s, uid, model.StatusReturned, at, amount-800, 0); err != nil {
+		return fmt.Errorf("after the full return: %w", err)
+	}
+	if err := s.UpdateOrderStatus(ctx, uid, statusChange(model.StatusDelivered, at.Add(time.Second)), nil); !stdErrors.Is(err, errors.ErrInvalidTransition) {
+		return fmt.Errorf("returned -> delivered returned %v, want %v", err, errors.ErrInvalidTransition)
+	}
+	return nil
+}
+
+func checkCancellation(ctx context.Context, s storage.Storage, gen *Orders) error {
+	order := twoItemOrder(gen)
+	uid, amount := order.OrderUID, order.Payment.Amount
+	if err := s.CreateOrder(ctx, order, nil); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	at := time.Now().UTC().Truncate(time.Microsecond)
+	cancellation := &model.Cancellation{Reason: "changed my mind", Actor: "storagetest", At: at}
+	e := event.New(event.OrderCancelled, uid)
+	if err := s.CancelOrder(ctx, uid, cancellation, &e); err != nil {
+		return fmt.Errorf("cancel: %w", err)
+	}
+	if cancellation.Refund != amount || e.Refund != amount {
+		return fmt.Errorf("cancel refunded %d, event says %d, want the full %d", cancellation.Refund, e.Refund, amount)
+	}
+	if e.Status == nil || e.Status.From != model.StatusCreated || e.Status.To != model.StatusCancelled {
+		return fmt.Errorf("cancel event status %+v, want created -> cancelled", e.Status)
+	}
+	if _, err := expectState(ctx, s, uid, model.StatusCancelled, at, 0, 0); err != nil {
+		return err
+	}
+
+	if err := s.CancelOrder(ctx, uid, &model.Cancellation{At: at.Add(time.Second)}, nil); !stdErrors.Is(err, errors.ErrInvalidTransition) {
+		return fmt.Errorf("second cancel returned %v, want %v", err, errors.ErrInvalidTransition)
+	}
+	ret := &model.ItemReturn{Items: []model.ItemRef{{ChrtID: 101}}, At: at.Add(time.Second)}
+	if err := s.ReturnItems(ctx, uid, ret, nil); !stdErrors.Is(err, errors.ErrInvalidTransition) {
+		return fmt.Errorf("return from a cancelled order returned %v, want %v", err, errors.ErrInvalidTransition)
+	}
+	_, err := expectState(ctx, s, uid, model.StatusCancelled, at, 0, 0)
+	return err
+}
@@ -0,0 +1,199 @@
+package storagetest
+
+import (
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/outbox"
+	"L0-wbtech/internal/storage"
+	"L0-wbtech/internal/webhook"
+	"L0-wbtech/pkg/errors"
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"time"
+)
+
+// dispatcher is the part of webhook.Store that marks outbox records
+// dispatched.
+type dispatcher interface {
+	DispatchOutbox(ctx context.Context, limit int, plan func(ctx context.Context, records []outbox.Record) ([]webhook.Job, error)) (int, error)
+}
+
+// The outbox checks write events dated long ago, so purges bounded by that
+// date leave newer data alone. They do publish and dispatch everything
+// pending in the outbox.
+var outboxEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func checkOutboxRetention(ctx context.Context, s storage.Storage, gen *Orders) error {
+	store, ok := s.(outbox.Store)
+	if !ok {
+		return nil
+	}
+	d, dispatches := s.(dispatcher)
+
+	before := outboxEpoch.Add(time.Minute)
+	purge := func(p outbox.Purge, want int64) error {
+		p.Before = before
+		n, err := store.PurgeOutbox(ctx, p)
+		if err != nil {
+			return fmt.Errorf("purge: %w", err)
+		}
+		if n != want {
+			return fmt.Errorf("purge %+v deleted %d records, want %d", p, n, want)
+		}
+		return nil
+	}
+	// Leftovers of an earlier run that failed halfway.
+	if _, err := store.PurgeOutbox(ctx, outbox.Purge{Before: before}); err != nil {
+		return fmt.Errorf("purge: %w", err)
+	}
+
+	if err := createAt(ctx, s, gen, outboxEpoch); err != nil {
+		return err
+	}
+	if n, err := store.PurgeOutbox(ctx, outbox.Purge{Before: outboxEpoch}); err != nil || n != 0 {
+		return fmt.Errorf("purge within retention deleted %d records, err %v", n, err)
+	}
+	if err := purge(outbox.Purge{KeepUnpublished: true}, 0); err != nil {
+		return err
+	}
+
+	// Dispatched but never published: gone once the relay is off.
+	if dispatches {
+		if err := purge(outbox.Purge{KeepUndispatched: true}, 0); err != nil {
+			return err
+		}
+		if err := drain(func() (int, error) {
+			return d.DispatchOutbox(ctx, 100, func(context.Context, []outbox.Record) ([]webhook.Job, error) {
+				return nil, nil
+			})
+		}); err != nil {
+			return fmt.Errorf("dispatch: %w", err)
+		}
+		if err := purge(outbox.Purge{KeepUnpublished: true, KeepUndispatched: true}, 0); err != nil {
+			return err
+		}
+		if err := purge(outbox.Purge{KeepUndispatched: true}, 1); err != nil {
+			return err
+		}
+	} else if err := purge(outbox.Purge{}, 1); err != nil {
+		return err
+	}
+
+	// Published: gone once the webhooks are off.
+	if err := createAt(ctx, s, gen, outboxEpoch); err != nil {
+		return err
+	}
+	if err := drain(func() (int, error) {
+		return store.ProcessOutbox(ctx, 100, func(context.Context, []outbox.Record) error { return nil })
+	}); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+	return purge(outbox.Purge{KeepUnpublished: true}, 1)
+}
+
+// checkOutboxEvents verifies that every committed mutation leaves its event
+// in the outbox, in commit order, and that a rejected one leaves nothing.
+func checkOutboxEvents(ctx context.Context, s storage.Storage, gen *Orders) error {
+	store, ok := s.(outbox.Store)
+	if !ok {
+		return nil
+	}
+
+	order := gen.Next()
+	uid := order.OrderUID
+	at := time.Now().UTC().Truncate(time.Microsecond)
+
+	created := event.New(event.OrderCreated, uid)
+	if err := s.CreateOrder(ctx, order, &created); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	paid := event.New(event.OrderStatusChanged, uid)
+	if err := s.UpdateOrderStatus(ctx, uid, statusChange(model.StatusPaid, at), &paid); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	rejected := event.New(event.OrderStatusChanged, uid)
+	if err := s.UpdateOrderStatus(ctx, uid, statusChange(model.StatusDelivered, at), &rejected); !stdErrors.Is(err, errors.ErrInvalidTransition) {
+		return fmt.Errorf("paid -> delivered returned %v, want %v", err, errors.ErrInvalidTransition)
+	}
+	cancelled := event.New(event.OrderCancelled, uid)
+	if err := s.CancelOrder(ctx, uid, &model.Cancellation{At: at.Add(time.Second)}, &cancelled); err != nil {
+		return fmt.Errorf("cancel: %w", err)
+	}
+	want := []event.Event{created, paid, cancelled}
+
+	// A failed publish leaves the records for the next attempt.
+	failed := stdErrors.New("publish failed")
+	if _, err := store.ProcessOutbox(ctx, 1000, func(context.Context, []outbox.Record) error {
+		return failed
+	}); !stdErrors.Is(err, failed) {
+		return fmt.Errorf("failed publish returned %v, want %v", err, failed)
+	}
+
+	var records []outbox.Record
+	if err := drain(func() (int, error) {
+		return store.ProcessOutbox(ctx, 100, func(_ context.Context, batch []outbox.Record) error {
+			for _, rec := range batch {
+				if rec.OrderUID == uid {
+					records = append(records, rec)
+				}
+			}
+			return nil
+		})
+	}); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	if len(records) != len(want) {
+		return fmt.Errorf("outbox holds %d records of the order, want %d", len(records), len(want))
+	}
+	for i, rec := range records {
+		if rec.EventID != want[i].ID || rec.EventType != string(want[i].Type) {
+			return fmt.Errorf("record %d is %s %s, want %s %s", i, rec.EventType, rec.EventID, want[i].Type, want[i].ID)
+		}
+		var got event.Event
+		if err := json.Unmarshal(rec.Payload, &got); err != nil {
+			return fmt.Errorf("record %d: %w", i, err)
+		}
+		if got.ID != want[i].ID || got.Order == nil || got.Order.Status != want[i].Order.Status {
+			return fmt.Errorf("record %d payload does not carry the order after %s", i, want[i].Type)
+		}
+	}
+
+	n, err := store.ProcessOutbox(ctx, 100, func(_ context.Context, batch []outbox.Record) error {
+		for _, rec := range batch {
+			if rec.OrderUID == uid {
+				return fmt.Errorf("record %d published twice", rec.ID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if n != 0 {
+		return fmt.Errorf("%d records pending after the outbox was drained", n)
+	}
+	return nil
+}
+
+func createAt(ctx context.Context, s storage.Storage, gen *Orders, at time.Time) error {
+	order := gen.Next()
+	e := event.New(event.OrderCreated, order.OrderUID)
+	e.OccurredAt = at
+	if err := s.CreateOrder(ctx, order, &e); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	return nil
+}
+
+// drain calls fn until it handles nothing.
+func drain(fn func() (int, error)) error {
+	for {
+		n, err := fn()
+		if err != nil || n == 0 {
+			return err
+		}
+	}
+}
@@ -0,0 +1,272 @@
+// Package storagetest is a conformance suite for storage.Storage
+// implementations. Every backend must pass Run with the same results, so
+// the service and consumers can rely on one set of semantics.
+package storagetest
+
+import (
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/loadgen"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/storage"
+	"L0-wbtech/pkg/errors"
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Check is one conformance property. Checks create orders with their own
+// UIDs, so they can share a database with other data.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context, s storage.Storage, gen *Orders) error
+}
+
+func Checks() []Check {
+	return []Check{
+		{Name: "create and get", Run: checkCreateGet},
+		{Name: "get all", Run: checkGetAll},
+		{Name: "get many", Run: checkGetOrders},
+		{Name: "redelivery", Run: checkRedelivery},
+		{Name: "not found", Run: checkNotFound},
+		{Name: "item order", Run: checkItemOrder},
+		{Name: "unicode", Run: checkUnicode},
+		{Name: "large order", Run: checkLarge},
+		{Name: "cancelled context", Run: checkCancelledContext},
+		{Name: "lifecycle", Run: checkLifecycle},
+		{Name: "cancellation", Run: checkCancellation},
+		{Name: "outbox events", Run: checkOutboxEvents},
+		{Name: "outbox retention", Run: checkOutboxRetention},
+	}
+}
+
+// Run executes every check against s as a subtest of t.
+func Run(t *testing.T, s storage.Storage) {
+	gen := NewOrders()
+
+	for _, c := range Checks() {
+		t.Run(c.Name, func(t *testing.T) {
+			if err := c.Run(t.Context(), s, gen); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// Orders hands out valid orders with UIDs unique to the process.
+type Orders struct {
+	gen   *loadgen.Generator
+	run   string
+	count int
+}
+
+func NewOrders() *Orders {
+	return &Orders{
+		gen: loadgen.NewGenerator(loadgen.GeneratorConfig{}),
+		run: fmt.Sprintf("st%x", time.Now().UnixNano()),
+	}
+}
+
+func (o *Orders) Next() *model.Order {
+	o.count++
+	order := o.gen.Order()
+	order.OrderUID = fmt.Sprintf("%s-%d", o.run, o.count)
+	return order
+}
+
+func checkCreateGet(ctx context.Context, s storage.Storage, gen *Orders) error {
+	want := gen.Next()
+	e := event.New(event.OrderCreated, want.OrderUID)
+	if err := s.CreateOrder(ctx, want, &e); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if e.Order == nil || e.Order.OrderUID != want.OrderUID {
+		return fmt.Errorf("create did not complete the event with the order")
+	}
+
+	got, err := s.GetOrder(ctx, want.OrderUID)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	if err := Compare(want, got); err != nil {
+		return err
+	}
+	if got.Status != model.StatusCreated {
+		return fmt.Errorf("status %q, want %q", got.Status, model.StatusCreated)
+	}
+	return nil
+}
+
+func checkGetAll(ctx context.Context, s storage.Storage, gen *Orders) error {
+	created := make([]*model.Order, 3)
+	for i := range created {
+		created[i] = gen.Next()
+		if err := s.CreateOrder(ctx, created[i], nil); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+	}
+
+	all, err := s.GetAllOrders(ctx)
+	if err != nil {
+		return fmt.Errorf("get all: %w", err)
+	}
+	for _, want := range created {
+		got, ok := all[want.OrderUID]
+		if !ok {
+			return fmt.Errorf("order %s missing", want.OrderUID)
+		}
+		if err := Compare(want, got); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkGetOrders(ctx context.Context, s storage.Storage, gen *Orders) error {
+	want := gen.Next()
+	if err := s.CreateOrder(ctx, want, nil); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	missing := gen.Next().OrderUID
+
+	got, err := s.GetOrders(ctx, []string{want.OrderUID, missing})
+	if err != nil {
+		return fmt.Errorf("get many: %w", err)
+	}
+	if len(got) != 1 {
+		return fmt.Errorf("got %d orders, want 1", len(got))
+	}
+	if _, ok := got[missing]; ok {
+		return fmt.Errorf("missing order %s returned", missing)
+	}
+	return Compare(want, got[want.OrderUID])
+}
+
+func checkRedelivery(ctx context.Context, s storage.Storage, gen *Orders) error {
+	want := gen.Next()
+	if err := s.CreateOrder(ctx, want, nil); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	again := *want
+	again.TrackNumber = "CHANGED"
+	again.Items = want.Items[:1]
+	e := event.New(event.OrderCreated, want.OrderUID)
+	if err := s.CreateOrder(ctx, &again, &e); !stdErrors.Is(err, errors.ErrAlreadyExists) {
+		return fmt.Errorf("redelivery returned %v, want %v", err, errors.ErrAlreadyExists)
+	}
+
+	got, err := s.GetOrder(ctx, want.OrderUID)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	return Compare(want, got)
+}
+
+func checkNotFound(ctx context.Context, s storage.Storage, gen *Orders) error {
+	uid := gen.Next().OrderUID
+
+	if _, err := s.GetOrder(ctx, uid); !stdErrors.Is(err, errors.ErrNotFound) {
+		return fmt.Errorf("get returned %v, want %v", err, errors.ErrNotFound)
+	}
+
+	change := &model.StatusChange{To: model.StatusPaid, ChangedAt: time.Now().UTC()}
+	if err := s.UpdateOrderStatus(ctx, uid, change, nil); !stdErrors.Is(err, errors.ErrNotFound) {
+		return fmt.Errorf("status update returned %v, want %v", err, errors.ErrNotFound)
+	}
+
+	cancellation := &model.Cancellation{At: time.Now().UTC()}
+	if err := s.CancelOrder(ctx, uid, cancellation, nil); !stdErrors.Is(err, errors.ErrNotFound) {
+		return fmt.Errorf("cancel returned %v, want %v", err, errors.ErrNotFound)
+	}
+	return nil
+}
+
+func checkItemOrder(ctx context.Context, s storage.Storage, gen *Orders) error {
+	want := gen.Next()
+	want.Items = make([]model.Item, 0, 10)
+	for i := range 10 {
+		item := gen.Next().Items[0]
+		// Descending IDs catch backends that sort instead of keeping
+		// insertion order.
+		item.ChrtID = int64(1000 - i)
+		item.Rid = fmt.Sprintf("rid-%02d", 10-i)
+		want.Items = append(want.Items, item)
+	}
+
+	if err := s.CreateOrder(ctx, want, nil); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	got, err := s.GetOrder(ctx, want.OrderUID)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	return Compare(want, got)
+}
+
+func checkUnicode(ctx context.Context, s storage.Storage, gen *Orders) error {
+	want := gen.Next()
+	want.OrderUID += "-заказ-注文-🚚"
+	want.Delivery.Name = "Ёжиков Пётр 李小龍"
+	want.Delivery.Address = "ул. Тверская, д. 7 — кв. «12» 🏠"
+	want.Delivery.City = "Москва"
+	want.Items[0].Name = "Тушь для ресниц ✨ 睫毛膏"
+	want.Items[0].Brand = "Vivienne Sabo ©"
+	want.InternalSignature = "\t'quoted' \"double\" back\\slash"
+
+	if err := s.CreateOrder(ctx, want, nil); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	got, err := s.GetOrder(ctx, want.OrderUID)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	return Compare(want, got)
+}
+
+func checkLarge(ctx context.Context, s storage.Storage, gen *Orders) error {
+	want := gen.Next()
+	want.Delivery.Address = strings.Repeat("Большая улица ", 4096)
+	template := want.Items[0]
+	want.Items = make([]model.Item, 1000)
+	for i := range want.Items {
+		want.Items[i] = template
+		want.Items[i].ChrtID = int64(i + 1)
+		want.Items[i].Rid = fmt.Sprintf("%s-%04d", template.Rid, i)
+	}
+
+	if err := s.CreateOrder(ctx, want, nil); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	got, err := s.GetOrder(ctx, want.OrderUID)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	return Compare(want, got)
+}
+
+func checkCancelledContext(ctx context.Context, s storage.Storage, gen *Orders) error {
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	order := gen.Next()
+	if err := s.CreateOrder(cancelled, order, nil); !stdErrors.Is(err, context.Canceled) {
+		return fmt.Errorf("create returned %v, want %v", err, context.Canceled)
+	}
+	if _, err := s.GetOrder(ctx, order.OrderUID); !stdErrors.Is(err, errors.ErrNotFound) {
+		return fmt.Errorf("order stored despite cancelled context: %v", err)
+	}
+
+	if err := s.CreateOrder(ctx, order, nil); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if _, err := s.GetOrder(cancelled, order.OrderUID); !stdErrors.Is(err, context.Canceled) {
+		return fmt.Errorf("get returned %v, want %v", err, context.Canceled)
+	}
+	if _, err := s.GetAllOrders(cancelled); !stdErrors.Is(err, context.Canceled) {
+		return fmt.Errorf("get all returned %v, want %v", err, context.Canceled)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package stream
+
+import (
+	"L0-wbtech/internal/event"
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Subscriber interface {
+	Subscribe(buffer int) *event.Subscription
+}
+
+// Message is an event with its position in the feed. IDs have the form
+// "<epoch>-<seq>", where the epoch changes on every process start.
+type Message struct {
+	ID    string
+	Event event.Event
+}
+
+type Filter struct {
+	DeliveryService string
+	CustomerID      string
+	Entry           string
+}
+
+func (f Filter) Match(e event.Event) bool {
+	if e.Order == nil {
+		return f == Filter{}
+	}
+	return (f.DeliveryService == "" || f.DeliveryService == e.Order.DeliveryService) &&
+		(f.CustomerID == "" || f.CustomerID == e.Order.CustomerID) &&
+		(f.Entry == "" || f.Entry == e.Order.Entry)
+}
+
+type Config struct {
+	ClientBuffer int
+	History      int
+}
+
+// Hub fans order events out to live feed clients and keeps a bounded
+// history for resuming. A client that does not keep up with its buffer is
+// disconnected; it catches up by reconnecting with its last event ID.
+type Hub struct {
+	mu      sync.Mutex
+	epoch   string
+	seq     uint64
+	history []Message
+	next    int
+	clients map[*Client]struct{}
+	closed  bool
+	cfg     Config
+	log     *slog.Logger
+}
+
+type Client struct {
+	C      <-chan Message
+	ch     chan Message
+	filter Filter
+	lagged bool
+}
+
+// Lagged reports whether the hub dropped the client for falling behind.
+func (c *Client) Lagged() bool {
+	return c.lagged
+}
+
+func NewHub(cfg Config, log *slog.Logger) *Hub {
+	if cfg.ClientBuffer <= 0 {
+		cfg.ClientBuffer = 64
+	}
+	if cfg.History <= 0 {
+		cfg.History = 1024
+	}
+	return &Hub{
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		history: make([]Message, 0, cfg.History),
+		clients: make(map[*Client]struct{}),
+		cfg:     cfg,
+		log:     log,
+	}
+}
+
+func (h *Hub) Run(ctx context.Context, events Subscriber) {
+	const op = "stream.Hub.Run"
+	log := h.log.With(slog.String("op", op))
+
+	sub := events.Subscribe(1024)
+	defer sub.Close()
+
+	log.Info("Starting order stream hub")
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.Close()
+			log.Info("Order stream hub stopped")
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			h.broadcast(e)
+		}
+	}
+}
+
+func (h *Hub) broadcast(e event.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	msg := Message{ID: fmt.Sprintf("%s-%d", h.epoch, h.seq), Event: e}
+
+	if len(h.history) < h.cfg.History {
+		h.history = append(h.history, msg)
+	} else {
+		h.history[h.next] = msg
+		h.next = (h.next + 1) % h.cfg.History
+	}
+
+	for c := range h.clients {
+		if !c.filter.Match(e) {
+			continue
+		}
+		select {
+		case c.ch <- msg:
+		default:
+			c.lagged = true
+			h.drop(c)
+			h.log.Warn("Dropping slow stream client", "last_event_id", msg.ID)
+		}
+	}
+}
+
+// Subscribe registers a client and returns the buffered messages after
+// lastEventID that match filter. An ID from a previous process epoch
+// replays the whole history.
+func (h *Hub) Subscribe(filter Filter, lastEventID string) (*Client, []Message, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, nil, fmt.Errorf("stream.Hub.Subscribe: hub is closed")
+	}
+
+	ch := make(chan Message, h.cfg.ClientBuffer)
+	c := &Client{C: ch, ch: ch, filter: filter}
+	h.clients[c] = struct{}{}
+
+	var backlog []Message
+	if lastEventID != "" {
+		after := h.resumePoint(lastEventID)
+		for _, msg := range h.ordered() {
+			if seqOf(msg.ID) > after && filter.Match(msg.Event) {
+				backlog = append(backlog, msg)
+			}
+		}
+	}
+
+	return c, backlog, nil
+}
+
+func (h *Hub) Unsubscribe(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drop(c)
+}
+
+// Close disconnects every client, letting open streams finish.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for c := range h.clients {
+		h.drop(c)
+	}
+}
+
+func (h *Hub) drop(c *Client) {
+	if _, ok := h.clients[c]; ok {
+		delete(h.clients, c)
+		close(c.ch)
+	}
+}
+
+func (h *Hub) resumePoint(lastEventID string) uint64 {
+	epoch, _, found := strings.Cut(lastEventID, "-")
+	if !found || epoch != h.epoch {
+		return 0
+	}
+	return seqOf(lastEventID)
+}
+
+func (h *Hub) ordered() []Message {
+	if len(h.history) < h.cfg.History {
+		return h.history
+	}
+	out := make([]Message, 0, len(h.history))
+	out = append(out, h.history[h.next:]...)
+	return append(out, h.history[:h.next]...)
+}
+
+func seqOf(id string) uint64 {
+	_, raw, _ := strings.Cut(id, "-")
+	seq, _ := strconv.ParseUint(raw, 10, 64)
+	return seq
+}
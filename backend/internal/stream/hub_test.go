@@ -0,0 +1,233 @@
+package stream_test
+
+import (
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/model"
+	"L0-wbtech/internal/stream"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+// feed runs a hub on its own bus until the test ends.
+type feed struct {
+	hub *stream.Hub
+	bus *event.Bus
+	// probe sees every message, so publish knows when the hub has one.
+	probe *stream.Client
+}
+
+func newFeed(t *testing.T, cfg stream.Config) *feed {
+	t.Helper()
+
+	log := slog.New(slog.DiscardHandler)
+	f := &feed{hub: stream.NewHub(cfg, log), bus: event.NewBus(log)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.hub.Run(ctx, f.bus)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	probe, _, err := f.hub.Subscribe(stream.Filter{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.probe = probe
+	// The hub subscribes to the bus in Run; wait until it gets events.
+	deadline := time.Now().Add(time.Second)
+	for {
+		f.bus.Publish(ctx, event.New(event.OrderCreated, "warmup"))
+		select {
+		case <-probe.C:
+			f.hub.Unsubscribe(probe)
+			f.probe, _, _ = f.hub.Subscribe(stream.Filter{}, "")
+			return f
+		case <-time.After(10 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("hub did not subscribe to the bus")
+		}
+	}
+}
+
+// publish sends an event for an order of the given delivery service and
+// returns its feed message once the hub has broadcast it.
+func (f *feed) publish(t *testing.T, uid, deliveryService string) stream.Message {
+	t.Helper()
+
+	e := event.New(event.OrderCreated, uid)
+	e.Order = &model.Order{OrderUID: uid, DeliveryService: deliveryService}
+	f.bus.Publish(context.Background(), e)
+
+	select {
+	case msg := <-f.probe.C:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatalf("event for %s not broadcast", uid)
+		return stream.Message{}
+	}
+}
+
+func receive(t *testing.T, c *stream.Client) stream.Message {
+	t.Helper()
+
+	select {
+	case msg, ok := <-c.C:
+		if !ok {
+			t.Fatal("client closed")
+		}
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message")
+		return stream.Message{}
+	}
+}
+
+func uids(messages []stream.Message) []string {
+	out := make([]string, len(messages))
+	for i, msg := range messages {
+		out[i] = msg.Event.OrderUID
+	}
+	return out
+}
+
+func TestHubDeliversMatchingEvents(t *testing.T) {
+	f := newFeed(t, stream.Config{})
+
+	all, _, err := f.hub.Subscribe(stream.Filter{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cdek, _, err := f.hub.Subscribe(stream.Filter{DeliveryService: "cdek"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := f.publish(t, "order-1", "meest")
+	second := f.publish(t, "order-2", "cdek")
+
+	if got := receive(t, all); got.ID != first.ID {
+		t.Errorf("first message %s, want %s", got.ID, first.ID)
+	}
+	if got := receive(t, all); got.ID != second.ID {
+		t.Errorf("second message %s, want %s", got.ID, second.ID)
+	}
+	if got := receive(t, cdek); got.Event.OrderUID != "order-2" {
+		t.Errorf("filtered client got %s, want order-2", got.Event.OrderUID)
+	}
+	select {
+	case msg := <-cdek.C:
+		t.Errorf("filtered client got an extra message for %s", msg.Event.OrderUID)
+	default:
+	}
+}
+
+func TestHubResume(t *testing.T) {
+	const history = 4
+
+	f := newFeed(t, stream.Config{History: history})
+
+	var sent []stream.Message
+	for i := range 6 {
+		service := "meest"
+		if i%2 == 1 {
+			service = "cdek"
+		}
+		sent = append(sent, f.publish(t, fmt.Sprintf("order-%d", i), service))
+	}
+
+	for _, tc := range []struct {
+		name        string
+		filter      stream.Filter
+		lastEventID string
+		want        []string
+	}{
+		{"new client", stream.Filter{}, "", nil},
+		{"after a recent event", stream.Filter{}, sent[3].ID, []string{"order-4", "order-5"}},
+		{"after the latest event", stream.Filter{}, sent[5].ID, nil},
+		// Only the last events are kept.
+		{"after an evicted event", stream.Filter{}, sent[0].ID, []string{"order-2", "order-3", "order-4", "order-5"}},
+		{"filtered", stream.Filter{DeliveryService: "cdek"}, sent[2].ID, []string{"order-3", "order-5"}},
+		{"from another process", stream.Filter{}, "previous-3", []string{"order-2", "order-3", "order-4", "order-5"}},
+		{"malformed ID", stream.Filter{}, "garbage", []string{"order-2", "order-3", "order-4", "order-5"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, backlog, err := f.hub.Subscribe(tc.filter, tc.lastEventID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.hub.Unsubscribe(c)
+
+			if got := uids(backlog); !slices.Equal(got, tc.want) {
+				t.Errorf("backlog %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHubDropsSlowClient(t *testing.T) {
+	const buffer = 2
+
+	f := newFeed(t, stream.Config{ClientBuffer: buffer})
+
+	slow, _, err := f.hub.Subscribe(stream.Filter{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last stream.Message
+	for i := range buffer + 1 {
+		last = f.publish(t, fmt.Sprintf("order-%d", i), "meest")
+	}
+
+	var got []stream.Message
+	for msg := range slow.C {
+		got = append(got, msg)
+	}
+	if len(got) != buffer {
+		t.Errorf("slow client got %d messages before being dropped, want %d", len(got), buffer)
+	}
+	if !slow.Lagged() {
+		t.Error("dropped client does not report lagging")
+	}
+
+	// It catches up by resuming from the last message it saw.
+	c, backlog, err := f.hub.Subscribe(stream.Filter{}, got[len(got)-1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.hub.Unsubscribe(c)
+	if len(backlog) != 1 || backlog[0].ID != last.ID {
+		t.Errorf("resumed backlog %v, want [%s]", backlog, last.ID)
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	f := newFeed(t, stream.Config{})
+
+	c, _, err := f.hub.Subscribe(stream.Filter{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.hub.Close()
+
+	if _, ok := <-c.C; ok {
+		t.Error("client still open after Close")
+	}
+	if c.Lagged() {
+		t.Error("client closed by shutdown reports lagging")
+	}
+	if _, _, err := f.hub.Subscribe(stream.Filter{}, ""); err == nil {
+		t.Error("Subscribe succeeded after Close")
+	}
+	// Unsubscribing a client the hub already dropped is harmless.
+	f.hub.Unsubscribe(c)
+}
@@ -0,0 +1,424 @@
+package webhook
+
+import (
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/outbox"
+	"L0-wbtech/pkg/errors"
+	"L0-wbtech/pkg/logger/sl"
+	"bytes"
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Subscriber interface {
+	Subscribe(buffer int) *event.Subscription
+}
+
+type Config struct {
+	Workers        int
+	Timeout        time.Duration
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	DisableAfter   int
+	PollInterval   time.Duration
+	BatchSize      int
+	// DeliveryRetention is how long delivery records are kept, checked
+	// every PurgeInterval.
+	DeliveryRetention time.Duration
+	PurgeInterval     time.Duration
+}
+
+// Dispatcher delivers domain events to matching subscriptions. Events are
+// read from the outbox, which storage writes in the same transaction as the
+// change, and turned into one queued job per matching subscription; the
+// in-process event bus only wakes the dispatcher up early. Workers make one
+// attempt per job and put failed jobs back with exponential backoff, so a
+// slow endpoint holds at most one worker for one timeout at a time and
+// queued retries survive a restart. A job that exhausts its attempts counts as one failure
+// of the subscription, which is disabled after DisableAfter consecutive
+// failures, dropping its queued jobs. Every attempt is logged in the store
+// and the log is trimmed to DeliveryRetention.
+//
+// Outbox records purged before the dispatcher read them are not delivered,
+// so the outbox retention bounds how long the dispatcher may be down.
+type Dispatcher struct {
+	store  Store
+	events Subscriber
+	client *http.Client
+	cfg    Config
+	log    *slog.Logger
+
+	// busy holds the subscriptions a worker is sending to, so the workers
+	// spread over endpoints instead of queueing up behind a slow one.
+	mu   sync.Mutex
+	busy map[int64]struct{}
+}
+
+func NewDispatcher(store Store, events Subscriber, client *http.Client, cfg Config, log *slog.Logger) *Dispatcher {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Minute
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.DeliveryRetention <= 0 {
+		cfg.DeliveryRetention = 7 * 24 * time.Hour
+	}
+	if cfg.PurgeInterval <= 0 {
+		cfg.PurgeInterval = 10 * time.Minute
+	}
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+	return &Dispatcher{
+		store:  store,
+		events: events,
+		client: client,
+		cfg:    cfg,
+		log:    log,
+		busy:   make(map[int64]struct{}),
+	}
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	const op = "webhook.Dispatcher.Run"
+	log := d.log.With(slog.String("op", op))
+
+	wake := make(chan struct{}, d.cfg.Workers)
+	var wg sync.WaitGroup
+	for range d.cfg.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.work(ctx, wake)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		d.purgeDeliveries(ctx)
+	}()
+	defer wg.Wait()
+
+	var notify <-chan event.Event
+	if d.events != nil {
+		sub := d.events.Subscribe(1024)
+		defer sub.Close()
+		notify = sub.C
+	}
+
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	log.Info("Starting webhook dispatcher", "workers", d.cfg.Workers)
+
+	for {
+		d.enqueue(ctx, wake)
+
+		select {
+		case <-ctx.Done():
+			log.Info("Webhook dispatcher stopped")
+			return
+		case <-notify:
+			// One pass picks up every event committed so far.
+			for len(notify) > 0 {
+				<-notify
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueue queues jobs for the outbox records written since the last pass
+// and wakes the workers when there were any.
+func (d *Dispatcher) enqueue(ctx context.Context, wake chan<- struct{}) {
+	for {
+		n, err := d.store.DispatchOutbox(ctx, d.cfg.BatchSize, d.plan)
+		if err != nil {
+			if ctx.Err() == nil {
+				d.log.Error("Failed to queue webhook deliveries", sl.Err(err))
+			}
+			return
+		}
+		if n > 0 {
+			for range d.cfg.Workers {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			}
+		}
+		if n < d.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) plan(ctx context.Context, records []outbox.Record) ([]Job, error) {
+	subs, err := d.store.ListWebhooks(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+
+	now := time.Now().UTC()
+	var jobs []Job
+	for _, rec := range records {
+		var e event.Event
+		if err := json.Unmarshal(rec.Payload, &e); err != nil {
+			d.log.Error("Skipping malformed outbox record", sl.Err(err), "event_id", rec.EventID)
+			continue
+		}
+
+		for _, s := range subs {
+			if !s.Matches(e) {
+				continue
+			}
+			jobs = append(jobs, Job{
+				SubscriptionID: s.ID,
+				EventID:        rec.EventID,
+				EventType:      rec.EventType,
+				Payload:        rec.Payload,
+				NextAttemptAt:  now,
+				CreatedAt:      now,
+			})
+		}
+	}
+	return jobs, nil
+}
+
+// purgeDeliveries trims the delivery log until ctx is done.
+func (d *Dispatcher) purgeDeliveries(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := d.store.PurgeWebhookDeliveries(ctx, time.Now().Add(-d.cfg.DeliveryRetention))
+		if err != nil {
+			if ctx.Err() == nil {
+				d.log.Error("Failed to purge webhook deliveries", sl.Err(err))
+			}
+		} else if deleted > 0 {
+			d.log.Info("Purged webhook deliveries", "count", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// work delivers due jobs one at a time until ctx is done.
+func (d *Dispatcher) work(ctx context.Context, wake <-chan struct{}) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for d.deliverNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverNext claims one due job and attempts it, reporting whether there
+// was one.
+func (d *Dispatcher) deliverNext(ctx context.Context) bool {
+	j, ok := d.claim(ctx)
+	if !ok {
+		return false
+	}
+	defer d.release(j.SubscriptionID)
+
+	d.deliver(ctx, j)
+	return ctx.Err() == nil
+}
+
+// claim takes a due job of a subscription no other worker is sending to.
+func (d *Dispatcher) claim(ctx context.Context) (Job, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	skip := make([]int64, 0, len(d.busy))
+	for id := range d.busy {
+		skip = append(skip, id)
+	}
+
+	// The lease outlives the request, so no other dispatcher takes the job
+	// while it is being sent.
+	jobs, err := d.store.ClaimWebhookJobs(ctx, 1, 2*d.cfg.Timeout, skip)
+	if err != nil {
+		if ctx.Err() == nil {
+			d.log.Error("Failed to claim webhook jobs", sl.Err(err))
+		}
+		return Job{}, false
+	}
+	if len(jobs) == 0 {
+		return Job{}, false
+	}
+
+	d.busy[jobs[0].SubscriptionID] = struct{}{}
+	return jobs[0], true
+}
+
+func (d *Dispatcher) release(subscriptionID int64) {
+	d.mu.Lock()
+	delete(d.busy, subscriptionID)
+	d.mu.Unlock()
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, j Job) {
+	log := d.log.With(
+		slog.Int64("webhook_id", j.SubscriptionID),
+		slog.String("event_id", j.EventID),
+		slog.String("event_type", j.EventType),
+	)
+
+	sub, err := d.store.GetWebhook(ctx, j.SubscriptionID)
+	if err != nil {
+		// A deleted subscription takes its jobs with it.
+		if !stdErrors.Is(err, errors.ErrNotFound) && ctx.Err() == nil {
+			log.Error("Failed to load webhook", sl.Err(err))
+		}
+		return
+	}
+
+	attempt := j.Attempt + 1
+	delivery := d.send(ctx, sub, j, attempt)
+	if ctx.Err() != nil {
+		// Interrupted by shutdown: the lease runs out and the job is sent
+		// again.
+		return
+	}
+
+	// The attempt has been made; record its outcome even if shutdown
+	// starts meanwhile, or the job would wait out its lease.
+	ctx = context.WithoutCancel(ctx)
+	if err := d.store.CreateWebhookDelivery(ctx, &delivery); err != nil {
+		log.Error("Failed to record webhook delivery", sl.Err(err))
+	}
+
+	if delivery.Success {
+		if err := d.store.DeleteWebhookJob(ctx, j.ID); err != nil {
+			log.Error("Failed to remove webhook job", sl.Err(err))
+		}
+		if _, err := d.store.RecordWebhookResult(ctx, sub.ID, true, d.cfg.DisableAfter); err != nil {
+			log.Error("Failed to record webhook result", sl.Err(err))
+		}
+		log.Debug("Webhook delivered", "attempt", attempt)
+		return
+	}
+
+	if attempt < d.cfg.MaxAttempts {
+		backoff := d.backoff(attempt)
+		// The job is gone if the subscription was disabled meanwhile.
+		err := d.store.RetryWebhookJob(ctx, j.ID, attempt, time.Now().Add(backoff))
+		if err != nil && !stdErrors.Is(err, errors.ErrNotFound) {
+			log.Error("Failed to reschedule webhook job", sl.Err(err))
+		}
+		log.Warn("Webhook delivery failed",
+			"attempt", attempt,
+			"status_code", delivery.StatusCode,
+			"error", delivery.Error,
+			"retry_in", backoff)
+		return
+	}
+
+	log.Warn("Webhook delivery failed, giving up",
+		"attempt", attempt,
+		"status_code", delivery.StatusCode,
+		"error", delivery.Error)
+
+	if err := d.store.DeleteWebhookJob(ctx, j.ID); err != nil {
+		log.Error("Failed to remove webhook job", sl.Err(err))
+	}
+	disabled, err := d.store.RecordWebhookResult(ctx, sub.ID, false, d.cfg.DisableAfter)
+	if err != nil {
+		log.Error("Failed to record webhook result", sl.Err(err))
+		return
+	}
+	if disabled {
+		log.Warn("Webhook disabled after repeated failures")
+	}
+}
+
+// backoff is the wait after the given failed attempt.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	backoff := d.cfg.InitialBackoff
+	for range attempt - 1 {
+		backoff *= 2
+		if backoff >= d.cfg.MaxBackoff {
+			return d.cfg.MaxBackoff
+		}
+	}
+	return min(backoff, d.cfg.MaxBackoff)
+}
+
+func (d *Dispatcher) send(ctx context.Context, sub *Subscription, j Job, attempt int) Delivery {
+	delivery := Delivery{
+		SubscriptionID: sub.ID,
+		EventID:        j.EventID,
+		EventType:      j.EventType,
+		Attempt:        attempt,
+		CreatedAt:      time.Now().UTC(),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(j.Payload))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, j.EventType)
+	req.Header.Set(HeaderID, j.EventID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, now, j.Payload))
+
+	resp, err := d.client.Do(req)
+	delivery.Duration = time.Since(now).Milliseconds()
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	delivery.StatusCode = resp.StatusCode
+	delivery.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+	if !delivery.Success {
+		delivery.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	return delivery
+}
@@ -0,0 +1,392 @@
+package webhook_test
+
+import (
+	"L0-wbtech/internal/config"
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/outbox"
+	"L0-wbtech/internal/storage/sqlite"
+	"L0-wbtech/internal/storage/storagetest"
+	"L0-wbtech/internal/webhook"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const secret = "test-secret"
+
+// receiver is a partner endpoint that answers with the next status of
+// statuses, repeating the last one.
+type receiver struct {
+	t        *testing.T
+	mu       sync.Mutex
+	statuses []int
+	requests []request
+}
+
+type request struct {
+	at     time.Time
+	header http.Header
+	body   []byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) (*receiver, *httptest.Server) {
+	r := &receiver{t: t, statuses: statuses}
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return r, srv
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		r.t.Error(err)
+	}
+
+	r.mu.Lock()
+	r.requests = append(r.requests, request{at: time.Now(), header: req.Header.Clone(), body: body})
+	status := r.statuses[min(len(r.requests), len(r.statuses))-1]
+	r.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func (r *receiver) received() []request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]request(nil), r.requests...)
+}
+
+func openStore(t *testing.T) *sqlite.SQLiteStorage {
+	t.Helper()
+
+	s, err := sqlite.NewSQLiteDB(config.SQLite{
+		Path:        filepath.Join(t.TempDir(), "orders.db"),
+		BusyTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func subscribe(t *testing.T, store webhook.Store, url string) *webhook.Subscription {
+	t.Helper()
+
+	sub := &webhook.Subscription{URL: url, Secret: secret, Active: true}
+	if err := store.CreateWebhook(context.Background(), sub); err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+// createOrder commits an order together with its order.created event, the
+// way the order service does.
+func createOrder(t *testing.T, store *sqlite.SQLiteStorage) event.Event {
+	t.Helper()
+
+	order := storagetest.NewOrders().Next()
+	e := event.New(event.OrderCreated, order.OrderUID)
+	if err := store.CreateOrder(context.Background(), order, &e); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func startDispatcher(t *testing.T, store webhook.Store, cfg webhook.Config) context.CancelFunc {
+	t.Helper()
+
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = 10 * time.Millisecond
+	}
+	d := webhook.NewDispatcher(store, nil, nil, cfg, slog.New(slog.DiscardHandler))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx)
+	}()
+
+	stop := func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func deliveries(t *testing.T, store webhook.Store, id int64) []webhook.Delivery {
+	t.Helper()
+
+	list, err := store.ListWebhookDeliveries(context.Background(), id, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func TestDispatcherSignsDeliveries(t *testing.T) {
+	store := openStore(t)
+	recv, srv := newReceiver(t, http.StatusOK)
+	sub := subscribe(t, store, srv.URL)
+
+	startDispatcher(t, store, webhook.Config{})
+	e := createOrder(t, store)
+
+	waitFor(t, "the delivery", func() bool { return len(recv.received()) == 1 })
+
+	req := recv.received()[0]
+	if got := req.header.Get(webhook.HeaderID); got != e.ID {
+		t.Errorf("%s = %q, want %q", webhook.HeaderID, got, e.ID)
+	}
+	if got := req.header.Get(webhook.HeaderEvent); got != string(event.OrderCreated) {
+		t.Errorf("%s = %q, want %q", webhook.HeaderEvent, got, event.OrderCreated)
+	}
+	ts, sig := req.header.Get(webhook.HeaderTimestamp), req.header.Get(webhook.HeaderSignature)
+	if !webhook.Verify(secret, ts, sig, req.body) {
+		t.Errorf("signature %q does not verify for timestamp %q", sig, ts)
+	}
+	if webhook.Verify("other-secret", ts, sig, req.body) {
+		t.Error("signature verifies with the wrong secret")
+	}
+
+	waitFor(t, "the delivery record", func() bool { return len(deliveries(t, store, sub.ID)) == 1 })
+	if d := deliveries(t, store, sub.ID)[0]; !d.Success || d.Attempt != 1 || d.EventID != e.ID {
+		t.Errorf("delivery record = %+v, want a successful first attempt of %s", d, e.ID)
+	}
+}
+
+func TestDispatcherRetriesWithBackoff(t *testing.T) {
+	const backoff = 50 * time.Millisecond
+
+	store := openStore(t)
+	recv, srv := newReceiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusNoContent)
+	sub := subscribe(t, store, srv.URL)
+
+	startDispatcher(t, store, webhook.Config{
+		MaxAttempts:    5,
+		InitialBackoff: backoff,
+		MaxBackoff:     time.Second,
+	})
+	createOrder(t, store)
+
+	waitFor(t, "three attempts", func() bool { return len(recv.received()) == 3 })
+	waitFor(t, "three delivery records", func() bool { return len(deliveries(t, store, sub.ID)) == 3 })
+
+	reqs := recv.received()
+	for i, want := range []time.Duration{backoff, 2 * backoff} {
+		if gap := reqs[i+1].at.Sub(reqs[i].at); gap < want {
+			t.Errorf("attempt %d came %v after the previous one, want at least %v", i+2, gap, want)
+		}
+		if reqs[i+1].header.Get(webhook.HeaderID) != reqs[0].header.Get(webhook.HeaderID) {
+			t.Errorf("attempt %d carries another event", i+2)
+		}
+	}
+
+	// Listed newest first.
+	list := deliveries(t, store, sub.ID)
+	for i, d := range list {
+		attempt := len(list) - i
+		if d.Attempt != attempt || d.Success != (attempt == 3) {
+			t.Errorf("delivery %d = attempt %d success %t", i, d.Attempt, d.Success)
+		}
+	}
+
+	time.Sleep(4 * backoff)
+	if n := len(recv.received()); n != 3 {
+		t.Errorf("receiver got %d requests after a successful delivery, want 3", n)
+	}
+}
+
+func TestDispatcherGivesUp(t *testing.T) {
+	store := openStore(t)
+	recv, srv := newReceiver(t, http.StatusInternalServerError)
+	sub := subscribe(t, store, srv.URL)
+
+	startDispatcher(t, store, webhook.Config{
+		MaxAttempts:    3,
+		InitialBackoff: 10 * time.Millisecond,
+		DisableAfter:   1,
+	})
+	createOrder(t, store)
+
+	waitFor(t, "the subscription to be disabled", func() bool {
+		got, err := store.GetWebhook(context.Background(), sub.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return !got.Active
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	if n := len(recv.received()); n != 3 {
+		t.Errorf("receiver got %d requests, want 3", n)
+	}
+	list := deliveries(t, store, sub.ID)
+	if len(list) != 3 {
+		t.Fatalf("recorded %d deliveries, want 3", len(list))
+	}
+	for _, d := range list {
+		if d.Success || d.StatusCode != http.StatusInternalServerError {
+			t.Errorf("delivery %+v, want a failed 500", d)
+		}
+	}
+
+	// Reactivating the subscription does not resend the abandoned event.
+	if err := store.SetWebhookActive(context.Background(), sub.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := len(recv.received()); n != 3 {
+		t.Errorf("receiver got %d requests after reactivation, want 3", n)
+	}
+}
+
+func TestDispatcherResumesRetriesAfterRestart(t *testing.T) {
+	store := openStore(t)
+	recv, srv := newReceiver(t, http.StatusServiceUnavailable, http.StatusOK)
+	sub := subscribe(t, store, srv.URL)
+
+	cfg := webhook.Config{
+		MaxAttempts:    3,
+		InitialBackoff: 200 * time.Millisecond,
+	}
+	stop := startDispatcher(t, store, cfg)
+	e := createOrder(t, store)
+
+	waitFor(t, "the first attempt", func() bool { return len(deliveries(t, store, sub.ID)) == 1 })
+	stop()
+
+	startDispatcher(t, store, cfg)
+	waitFor(t, "the retry", func() bool { return len(recv.received()) == 2 })
+
+	waitFor(t, "the retry record", func() bool { return len(deliveries(t, store, sub.ID)) == 2 })
+	if d := deliveries(t, store, sub.ID)[0]; !d.Success || d.Attempt != 2 || d.EventID != e.ID {
+		t.Errorf("delivery record = %+v, want a successful second attempt of %s", d, e.ID)
+	}
+}
+
+func TestDispatcherSlowEndpointDoesNotBlockOthers(t *testing.T) {
+	store := openStore(t)
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(release) })
+	subscribe(t, store, slow.URL)
+
+	recv, fast := newReceiver(t, http.StatusOK)
+	subscribe(t, store, fast.URL)
+
+	startDispatcher(t, store, webhook.Config{Workers: 2, Timeout: 10 * time.Second})
+	for range 3 {
+		createOrder(t, store)
+	}
+
+	waitFor(t, "deliveries to the fast endpoint", func() bool { return len(recv.received()) == 3 })
+}
+
+func TestDispatcherPurgesOldDeliveries(t *testing.T) {
+	store := openStore(t)
+	sub := subscribe(t, store, "http://127.0.0.1:1")
+
+	now := time.Now().UTC()
+	for _, at := range []time.Time{now.Add(-2 * time.Hour), now.Add(-time.Minute)} {
+		d := &webhook.Delivery{SubscriptionID: sub.ID, EventID: at.String(), EventType: "order.created", Attempt: 1, CreatedAt: at}
+		if err := store.CreateWebhookDelivery(context.Background(), d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	startDispatcher(t, store, webhook.Config{DeliveryRetention: time.Hour})
+
+	waitFor(t, "the old delivery to be purged", func() bool { return len(deliveries(t, store, sub.ID)) == 1 })
+	if d := deliveries(t, store, sub.ID)[0]; !d.CreatedAt.After(now.Add(-time.Hour)) {
+		t.Errorf("kept the delivery made at %v, want the recent one", d.CreatedAt)
+	}
+}
+
+func TestDisabledSubscriptionDropsQueuedJobs(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		disable func(ctx context.Context, store webhook.Store, id int64) error
+		want    int
+	}{
+		{"left enabled", func(context.Context, webhook.Store, int64) error { return nil }, 1},
+		{"disabled by hand", func(ctx context.Context, store webhook.Store, id int64) error {
+			return store.SetWebhookActive(ctx, id, false)
+		}, 0},
+		{"disabled after failures", func(ctx context.Context, store webhook.Store, id int64) error {
+			disabled, err := store.RecordWebhookResult(ctx, id, false, 1)
+			if err == nil && !disabled {
+				t.Error("subscription not disabled after its only allowed failure")
+			}
+			return err
+		}, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := openStore(t)
+			sub := subscribe(t, store, "http://127.0.0.1:1")
+			createOrder(t, store)
+
+			// Queue the event without delivering it.
+			_, err := store.DispatchOutbox(ctx, 10, func(_ context.Context, records []outbox.Record) ([]webhook.Job, error) {
+				jobs := make([]webhook.Job, len(records))
+				for i, rec := range records {
+					jobs[i] = webhook.Job{
+						SubscriptionID: sub.ID,
+						EventID:        rec.EventID,
+						EventType:      rec.EventType,
+						Payload:        rec.Payload,
+						NextAttemptAt:  time.Now(),
+						CreatedAt:      time.Now(),
+					}
+				}
+				return jobs, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tc.disable(ctx, store, sub.ID); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.SetWebhookActive(ctx, sub.ID, true); err != nil {
+				t.Fatal(err)
+			}
+
+			jobs, err := store.ClaimWebhookJobs(ctx, 10, time.Minute, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(jobs) != tc.want {
+				t.Errorf("claimed %d jobs after re-enabling, want %d", len(jobs), tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"L0-wbtech/internal/event"
+	"L0-wbtech/pkg/errors"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+)
+
+var knownEvents = []string{
+	string(event.OrderCreated),
+	string(event.OrderStatusChanged),
+	string(event.OrderCancelled),
+	string(event.OrderItemsReturned),
+}
+
+// Service manages subscriptions on behalf of the admin API.
+type Service struct {
+	store Store
+}
+
+func NewService(store Store) *Service {
+	return &Service{store: store}
+}
+
+// Create registers a subscription. When no secret is given one is generated;
+// the returned secret is the only time it is exposed.
+func (s *Service) Create(ctx context.Context, sub *Subscription) (string, error) {
+	const op = "webhook.Service.Create"
+
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%s: invalid url: %w", op, errors.ErrInvalidInput)
+	}
+	for _, e := range sub.Events {
+		if !slices.Contains(knownEvents, e) {
+			return "", fmt.Errorf("%s: unknown event %q: %w", op, e, errors.ErrInvalidInput)
+		}
+	}
+
+	if sub.Secret == "" {
+		var b [32]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		sub.Secret = hex.EncodeToString(b[:])
+	}
+	sub.Active = true
+
+	if err := s.store.CreateWebhook(ctx, sub); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return sub.Secret, nil
+}
+
+func (s *Service) Get(ctx context.Context, id int64) (*Subscription, error) {
+	return s.store.GetWebhook(ctx, id)
+}
+
+func (s *Service) List(ctx context.Context) ([]Subscription, error) {
+	return s.store.ListWebhooks(ctx, false)
+}
+
+func (s *Service) Delete(ctx context.Context, id int64) error {
+	return s.store.DeleteWebhook(ctx, id)
+}
+
+func (s *Service) SetActive(ctx context.Context, id int64, active bool) error {
+	return s.store.SetWebhookActive(ctx, id, active)
+}
+
+func (s *Service) Deliveries(ctx context.Context, id int64, limit int) ([]Delivery, error) {
+	if _, err := s.store.GetWebhook(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.store.ListWebhookDeliveries(ctx, id, limit)
+}
@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"L0-wbtech/internal/event"
+	"L0-wbtech/internal/outbox"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderID        = "X-Webhook-Id"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Subscription is a partner endpoint. Events filters by event type and
+// DeliveryService by the order delivery service; empty filters match all.
+type Subscription struct {
+	ID              int64      `json:"id"               db:"id"`
+	URL             string     `json:"url"              db:"url"`
+	Secret          string     `json:"-"                db:"secret"`
+	Events          []string   `json:"events"           db:"-"`
+	DeliveryService string     `json:"delivery_service" db:"delivery_service"`
+	Active          bool       `json:"active"           db:"active"`
+	FailureCount    int        `json:"failure_count"    db:"failure_count"`
+	DisabledAt      *time.Time `json:"disabled_at"      db:"disabled_at"`
+	CreatedAt       time.Time  `json:"created_at"       db:"created_at"`
+}
+
+func (s *Subscription) Matches(e event.Event) bool {
+	if len(s.Events) > 0 && !slices.Contains(s.Events, string(e.Type)) {
+		return false
+	}
+	if s.DeliveryService != "" && (e.Order == nil || e.Order.DeliveryService != s.DeliveryService) {
+		return false
+	}
+	return true
+}
+
+type Delivery struct {
+	ID             int64     `json:"id"              db:"id"`
+	SubscriptionID int64     `json:"subscription_id" db:"subscription_id"`
+	EventID        string    `json:"event_id"        db:"event_id"`
+	EventType      string    `json:"event_type"      db:"event_type"`
+	Attempt        int       `json:"attempt"         db:"attempt"`
+	StatusCode     int       `json:"status_code"     db:"status_code"`
+	Error          string    `json:"error,omitempty" db:"error"`
+	Success        bool      `json:"success"         db:"success"`
+	Duration       int64     `json:"duration_ms"     db:"duration_ms"`
+	CreatedAt      time.Time `json:"created_at"      db:"created_at"`
+}
+
+// Job is a queued delivery of one event to one subscription. Attempt counts
+// the attempts already made.
+type Job struct {
+	ID             int64     `db:"id"`
+	SubscriptionID int64     `db:"subscription_id"`
+	EventID        string    `db:"event_id"`
+	EventType      string    `db:"event_type"`
+	Payload        []byte    `db:"payload"`
+	Attempt        int       `db:"attempt"`
+	NextAttemptAt  time.Time `db:"next_attempt_at"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+type Store interface {
+	CreateWebhook(ctx context.Context, sub *Subscription) error
+	GetWebhook(ctx context.Context, id int64) (*Subscription, error)
+	ListWebhooks(ctx context.Context, activeOnly bool) ([]Subscription, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	// SetWebhookActive enables or disables a subscription. Disabling it
+	// drops its queued jobs.
+	SetWebhookActive(ctx context.Context, id int64, active bool) error
+	// RecordWebhookResult resets the failure counter on success. On failure
+	// it increments the counter and disables the subscription once the
+	// counter reaches disableAfter, dropping its queued jobs, and reports
+	// whether it did so.
+	RecordWebhookResult(ctx context.Context, id int64, success bool, disableAfter int) (bool, error)
+	CreateWebhookDelivery(ctx context.Context, d *Delivery) error
+	ListWebhookDeliveries(ctx context.Context, id int64, limit int) ([]Delivery, error)
+	// PurgeWebhookDeliveries deletes the delivery records made before
+	// before and returns how many there were.
+	PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error)
+
+	// DispatchOutbox passes up to limit outbox records not yet dispatched to
+	// webhooks, oldest first, to plan and queues the jobs it returns, marking
+	// the records dispatched in the same transaction. At most one caller
+	// dispatches at a time; others get zero records.
+	DispatchOutbox(ctx context.Context, limit int, plan func(ctx context.Context, records []outbox.Record) ([]Job, error)) (int, error)
+	// ClaimWebhookJobs returns up to limit due jobs of active subscriptions
+	// other than skip and hides them from other callers until lease has
+	// passed, so a job whose worker died is picked up again.
+	ClaimWebhookJobs(ctx context.Context, limit int, lease time.Duration, skip []int64) ([]Job, error)
+	RetryWebhookJob(ctx context.Context, id int64, attempt int, at time.Time) error
+	DeleteWebhookJob(ctx context.Context, id int64) error
+}
+
+// Sign returns the signature of a payload sent at ts. Receivers recompute it
+// over "<timestamp>.<body>" with the shared secret and compare it with the
+// X-Webhook-Signature header.
+func Sign(secret string, ts time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func Verify(secret, timestamp, signature string, body []byte) bool {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	expected := Sign(secret, time.Unix(unix, 0), body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
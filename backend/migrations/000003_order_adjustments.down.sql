@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS order_adjustments;
+
+ALTER TABLE items
+    DROP COLUMN IF EXISTS returned_at;
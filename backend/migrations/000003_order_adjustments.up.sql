@@ -0,0 +1,18 @@
+ALTER TABLE items
+    ADD COLUMN IF NOT EXISTS returned_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS order_adjustments (
+    id BIGSERIAL PRIMARY KEY,
+    order_uid TEXT NOT NULL REFERENCES orders(order_uid) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    chrt_id BIGINT,
+    rid TEXT,
+    amount INTEGER NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    actor TEXT NOT NULL DEFAULT '',
+    source TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_adjustments_order_uid
+    ON order_adjustments (order_uid);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS inbox_notify ON inbox;
+DROP FUNCTION IF EXISTS notify_inbox();
+DROP TABLE IF EXISTS inbox;
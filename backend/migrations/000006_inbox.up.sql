@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS inbox (
+    id BIGSERIAL PRIMARY KEY,
+    topic TEXT NOT NULL,
+    content_type TEXT NOT NULL DEFAULT '',
+    payload BYTEA NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    processed_at TIMESTAMPTZ,
+    failed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbox_pending
+    ON inbox (id) WHERE processed_at IS NULL AND failed_at IS NULL;
+
+CREATE OR REPLACE FUNCTION notify_inbox() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('inbox', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS inbox_notify ON inbox;
+CREATE TRIGGER inbox_notify
+    AFTER INSERT ON inbox
+    FOR EACH ROW EXECUTE FUNCTION notify_inbox();
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS items_notify_change ON items;
+DROP TRIGGER IF EXISTS payment_notify_change ON payment;
+DROP TRIGGER IF EXISTS orders_notify_change ON orders;
+DROP FUNCTION IF EXISTS notify_order_change();
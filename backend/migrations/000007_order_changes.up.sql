@@ -0,0 +1,21 @@
+CREATE OR REPLACE FUNCTION notify_order_change() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('order_changes', NEW.order_uid);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS orders_notify_change ON orders;
+CREATE TRIGGER orders_notify_change
+    AFTER INSERT OR UPDATE ON orders
+    FOR EACH ROW EXECUTE FUNCTION notify_order_change();
+
+DROP TRIGGER IF EXISTS payment_notify_change ON payment;
+CREATE TRIGGER payment_notify_change
+    AFTER UPDATE ON payment
+    FOR EACH ROW EXECUTE FUNCTION notify_order_change();
+
+DROP TRIGGER IF EXISTS items_notify_change ON items;
+CREATE TRIGGER items_notify_change
+    AFTER UPDATE ON items
+    FOR EACH ROW EXECUTE FUNCTION notify_order_change();
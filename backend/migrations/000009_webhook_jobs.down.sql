@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS webhook_jobs;
+
+DROP INDEX IF EXISTS idx_outbox_undispatched;
+
+ALTER TABLE outbox
+    DROP COLUMN IF EXISTS dispatched_at;
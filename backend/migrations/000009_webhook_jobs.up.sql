@@ -0,0 +1,24 @@
+ALTER TABLE outbox
+    ADD COLUMN IF NOT EXISTS dispatched_at TIMESTAMPTZ;
+
+-- Events written before webhooks were queued from the outbox went through
+-- the old dispatcher already.
+UPDATE outbox SET dispatched_at = now() WHERE dispatched_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_undispatched
+    ON outbox (id) WHERE dispatched_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS webhook_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    attempt INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_jobs_next_attempt
+    ON webhook_jobs (next_attempt_at);
@@ -0,0 +1,5 @@
+-- Retention deletes by age whether or not the relay published a record.
+DROP INDEX IF EXISTS idx_outbox_published_at;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_created_at
+    ON outbox (created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE inbox
+    DROP COLUMN IF EXISTS claimed_until;
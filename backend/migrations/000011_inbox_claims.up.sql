@@ -0,0 +1,4 @@
+-- Messages are claimed for a while instead of locked for the whole
+-- handling, so no transaction stays open across handler retries.
+ALTER TABLE inbox
+    ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+-- The dispatcher deletes delivery records by age.
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_created_at
+    ON webhook_deliveries (created_at);
+
+-- Jobs of disabled subscriptions are dropped as they are disabled now.
+DELETE FROM webhook_jobs
+WHERE subscription_id IN (SELECT id FROM webhook_subscriptions WHERE NOT active);
@@ -2,6 +2,7 @@ package slogsetup
 
 import (
 	"L0-wbtech/pkg/logger/slogpretty"
+	"io"
 	"log/slog"
 	"os"
 )
@@ -13,36 +14,42 @@ const (
 )
 
 func SetupLogger(env string) *slog.Logger {
+	return SetupLoggerTo(env, os.Stdout)
+}
+
+// SetupLoggerTo is SetupLogger with a custom destination, for tools that
+// write their own output to stdout.
+func SetupLoggerTo(env string, out io.Writer) *slog.Logger {
 	var log *slog.Logger
 
 	switch env {
 	case envLocal:
-		log = setupPrettySlog()
+		log = setupPrettySlog(out)
 	case envDev:
 		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+			slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}),
 		)
 	case envProd:
 		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
+			slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelInfo}),
 		)
 	default:
 		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
+			slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelInfo}),
 		)
 	}
 
 	return log
 }
 
-func setupPrettySlog() *slog.Logger {
+func setupPrettySlog(out io.Writer) *slog.Logger {
 	opts := slogpretty.PrettyHandlerOptions{
 		SlogOpts: &slog.HandlerOptions{
 			Level: slog.LevelDebug,
 		},
 	}
 
-	handler := opts.NewPrettyHandler(os.Stdout)
+	handler := opts.NewPrettyHandler(out)
 
 	return slog.New(handler)
 }
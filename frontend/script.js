@@ -56,6 +56,10 @@ function displayOrder(order) {
             <strong>Delivery Service</strong>
             <span>${order.delivery_service}</span>
         </div>
+        <div class="info-item">
+            <strong>Status</strong>
+            <span>${order.status}</span>
+        </div>
     `;
     
     const delivery = order.delivery;